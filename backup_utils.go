@@ -0,0 +1,114 @@
+package dynamodbx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrBackupDeleted = errors.New("dynamodbx/CreateBackupSync: backup was deleted before it became available")
+)
+
+// CreateBackupSync will create an on demand backup and block until the backup is AVAILABLE.
+// The returned BackupDetails reflect the available backup.
+func CreateBackupSync(client *dynamodb.DynamoDB, input *dynamodb.CreateBackupInput) (*dynamodb.CreateBackupOutput, error) {
+	return CreateBackupSyncWithContext(context.Background(), client, input)
+}
+
+// CreateBackupSyncWithContext will create an on demand backup and block until the backup is AVAILABLE.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func CreateBackupSyncWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.CreateBackupInput, opts ...request.Option) (*dynamodb.CreateBackupOutput, error) {
+	out, err := client.CreateBackupWithContext(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch aws.StringValue(out.BackupDetails.BackupStatus) {
+		case dynamodb.BackupStatusAvailable:
+			return out, nil
+		case dynamodb.BackupStatusDeleted:
+			return nil, ErrBackupDeleted
+		}
+		if err := aws.SleepWithContext(ctx, time.Millisecond*500); err != nil {
+			return nil, err
+		}
+		desc, err := client.DescribeBackupWithContext(ctx, &dynamodb.DescribeBackupInput{BackupArn: out.BackupDetails.BackupArn}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		out.BackupDetails = desc.BackupDescription.BackupDetails
+	}
+}
+
+// RestoreTableFromBackupSync will restore a backup to a new table and block until the new table is
+// ACTIVE. Restores of large tables can take several hours.
+func RestoreTableFromBackupSync(client *dynamodb.DynamoDB, input *dynamodb.RestoreTableFromBackupInput) (*dynamodb.RestoreTableFromBackupOutput, error) {
+	return RestoreTableFromBackupSyncWithContext(context.Background(), client, input)
+}
+
+// RestoreTableFromBackupSyncWithContext will restore a backup to a new table and block until the new
+// table is ACTIVE.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func RestoreTableFromBackupSyncWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.RestoreTableFromBackupInput, opts ...request.Option) (*dynamodb.RestoreTableFromBackupOutput, error) {
+	out, err := client.RestoreTableFromBackupWithContext(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := waitTableActive(ctx, client, aws.StringValue(input.TargetTableName), opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RestoreTableToPointInTimeSync will restore a table with point in time recovery enabled to a new
+// table and block until the new table is ACTIVE.
+func RestoreTableToPointInTimeSync(client *dynamodb.DynamoDB, input *dynamodb.RestoreTableToPointInTimeInput) (*dynamodb.RestoreTableToPointInTimeOutput, error) {
+	return RestoreTableToPointInTimeSyncWithContext(context.Background(), client, input)
+}
+
+// RestoreTableToPointInTimeSyncWithContext will restore a table with point in time recovery enabled
+// to a new table and block until the new table is ACTIVE.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func RestoreTableToPointInTimeSyncWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.RestoreTableToPointInTimeInput, opts ...request.Option) (*dynamodb.RestoreTableToPointInTimeOutput, error) {
+	out, err := client.RestoreTableToPointInTimeWithContext(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := waitTableActive(ctx, client, aws.StringValue(input.TargetTableName), opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListAllBackups pages through ListBackups calling fn for every backup. Iteration stops when fn
+// returns false or there are no more backups. The input is not modified.
+func ListAllBackups(client *dynamodb.DynamoDB, input *dynamodb.ListBackupsInput, fn func(*dynamodb.BackupSummary) bool) error {
+	return ListAllBackupsWithContext(context.Background(), client, input, fn)
+}
+
+// ListAllBackupsWithContext pages through ListBackups calling fn for every backup.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func ListAllBackupsWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.ListBackupsInput, fn func(*dynamodb.BackupSummary) bool, opts ...request.Option) error {
+	page := *input
+	for {
+		out, err := client.ListBackupsWithContext(ctx, &page, opts...)
+		if err != nil {
+			return err
+		}
+		for _, b := range out.BackupSummaries {
+			if !fn(b) {
+				return nil
+			}
+		}
+		if out.LastEvaluatedBackupArn == nil {
+			return nil
+		}
+		page.ExclusiveStartBackupArn = out.LastEvaluatedBackupArn
+	}
+}
@@ -0,0 +1,215 @@
+package dynamodbx_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+)
+
+// backupArn is the arn of the backups in the stubbed responses, long enough to pass validation.
+const backupArn = "arn:aws:dynamodb:us-east-1:123456789012:table/test/backup/01551441600000-abcdefgh"
+
+// listedArn is the arn of the named backup in the stubbed ListBackups responses.
+func listedArn(name string) string {
+	return "arn:aws:dynamodb:us-east-1:123456789012:table/test/backup/" + name
+}
+
+func TestBackupUtilsCreateBackupSync(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		statuses []string
+		err      error
+		ops      []string
+	}{
+		{
+			name:     "available",
+			statuses: []string{dynamodb.BackupStatusCreating, dynamodb.BackupStatusCreating, dynamodb.BackupStatusAvailable},
+			ops:      []string{"CreateBackup", "DescribeBackup", "DescribeBackup"},
+		},
+		{
+			name:     "already available",
+			statuses: []string{dynamodb.BackupStatusAvailable},
+			ops:      []string{"CreateBackup"},
+		},
+		{
+			name:     "deleted",
+			statuses: []string{dynamodb.BackupStatusCreating, dynamodb.BackupStatusDeleted},
+			err:      dynamodbx.ErrBackupDeleted,
+			ops:      []string{"CreateBackup", "DescribeBackup"},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			statuses := tc.statuses
+			details := func() *dynamodb.BackupDetails {
+				status := statuses[0]
+				statuses = statuses[1:]
+				return &dynamodb.BackupDetails{
+					BackupArn:    aws.String(backupArn),
+					BackupName:   aws.String("backup"),
+					BackupStatus: aws.String(status),
+				}
+			}
+			ddb, ops := stubClient(func(op string, input interface{}) (interface{}, error) {
+				switch in := input.(type) {
+				case *dynamodb.CreateBackupInput:
+					return &dynamodb.CreateBackupOutput{BackupDetails: details()}, nil
+				case *dynamodb.DescribeBackupInput:
+					if aws.StringValue(in.BackupArn) != backupArn {
+						t.Errorf("unexpected backup arn %q", aws.StringValue(in.BackupArn))
+					}
+					return &dynamodb.DescribeBackupOutput{
+						BackupDescription: &dynamodb.BackupDescription{BackupDetails: details()},
+					}, nil
+				}
+				t.Fatalf("unexpected operation %s", op)
+				return nil, nil
+			})
+			out, err := dynamodbx.CreateBackupSync(ddb, &dynamodb.CreateBackupInput{
+				BackupName: aws.String("backup"),
+				TableName:  aws.String("test"),
+			})
+			if err != tc.err {
+				t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+			}
+			if err == nil && aws.StringValue(out.BackupDetails.BackupStatus) != dynamodb.BackupStatusAvailable {
+				t.Fatalf("expected the backup to be available, got %v", out.BackupDetails)
+			}
+			if got := ops(); !reflect.DeepEqual(got, tc.ops) {
+				t.Fatal(pretty.Compare(got, tc.ops))
+			}
+		})
+	}
+}
+
+func TestBackupUtilsRestoreSync(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		restore func(*dynamodb.DynamoDB) error
+		op      string
+	}{
+		{
+			name: "from backup",
+			restore: func(ddb *dynamodb.DynamoDB) error {
+				_, err := dynamodbx.RestoreTableFromBackupSync(ddb, &dynamodb.RestoreTableFromBackupInput{
+					BackupArn:       aws.String(backupArn),
+					TargetTableName: aws.String("restored"),
+				})
+				return err
+			},
+			op: "RestoreTableFromBackup",
+		},
+		{
+			name: "to point in time",
+			restore: func(ddb *dynamodb.DynamoDB) error {
+				_, err := dynamodbx.RestoreTableToPointInTimeSync(ddb, &dynamodb.RestoreTableToPointInTimeInput{
+					SourceTableName:         aws.String("test"),
+					TargetTableName:         aws.String("restored"),
+					UseLatestRestorableTime: aws.Bool(true),
+				})
+				return err
+			},
+			op: "RestoreTableToPointInTime",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			statuses := []string{dynamodb.TableStatusCreating, dynamodb.TableStatusCreating, dynamodb.TableStatusActive}
+			ddb, ops := stubClient(func(op string, input interface{}) (interface{}, error) {
+				switch in := input.(type) {
+				case *dynamodb.RestoreTableFromBackupInput:
+					return &dynamodb.RestoreTableFromBackupOutput{}, nil
+				case *dynamodb.RestoreTableToPointInTimeInput:
+					return &dynamodb.RestoreTableToPointInTimeOutput{}, nil
+				case *dynamodb.DescribeTableInput:
+					if aws.StringValue(in.TableName) != "restored" {
+						t.Errorf("expected the restored table to be described, got %q", aws.StringValue(in.TableName))
+					}
+					status := statuses[0]
+					statuses = statuses[1:]
+					return &dynamodb.DescribeTableOutput{
+						Table: &dynamodb.TableDescription{TableName: in.TableName, TableStatus: aws.String(status)},
+					}, nil
+				}
+				t.Fatalf("unexpected operation %s", op)
+				return nil, nil
+			})
+			if err := tc.restore(ddb); err != nil {
+				t.Fatal(err)
+			}
+			expect := []string{tc.op, "DescribeTable", "DescribeTable", "DescribeTable"}
+			if got := ops(); !reflect.DeepEqual(got, expect) {
+				t.Fatal(pretty.Compare(got, expect))
+			}
+		})
+	}
+}
+
+func TestBackupUtilsListAllBackups(t *testing.T) {
+	t.Parallel()
+	pages := map[string]*dynamodb.ListBackupsOutput{
+		"": {
+			BackupSummaries:        []*dynamodb.BackupSummary{{BackupArn: aws.String(listedArn("a"))}, {BackupArn: aws.String(listedArn("b"))}},
+			LastEvaluatedBackupArn: aws.String(listedArn("b")),
+		},
+		listedArn("b"): {
+			BackupSummaries:        []*dynamodb.BackupSummary{{BackupArn: aws.String(listedArn("c"))}},
+			LastEvaluatedBackupArn: aws.String(listedArn("c")),
+		},
+		listedArn("c"): {
+			BackupSummaries: []*dynamodb.BackupSummary{{BackupArn: aws.String(listedArn("d"))}},
+		},
+	}
+	ddb, ops := stubClient(func(op string, input interface{}) (interface{}, error) {
+		in, ok := input.(*dynamodb.ListBackupsInput)
+		if !ok {
+			t.Fatalf("unexpected operation %s", op)
+		}
+		if aws.StringValue(in.TableName) != "test" {
+			t.Errorf("expected the table name on every page, got %q", aws.StringValue(in.TableName))
+		}
+		return pages[aws.StringValue(in.ExclusiveStartBackupArn)], nil
+	})
+	input := &dynamodb.ListBackupsInput{TableName: aws.String("test")}
+
+	var arns []string
+	err := dynamodbx.ListAllBackups(ddb, input, func(b *dynamodb.BackupSummary) bool {
+		arns = append(arns, aws.StringValue(b.BackupArn))
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{listedArn("a"), listedArn("b"), listedArn("c"), listedArn("d")}; !reflect.DeepEqual(arns, expect) {
+		t.Fatal(pretty.Compare(arns, expect))
+	}
+	if input.ExclusiveStartBackupArn != nil {
+		t.Fatal("expected the input not to be modified")
+	}
+
+	// Stopping early does not read any more pages
+	arns = nil
+	err = dynamodbx.ListAllBackups(ddb, input, func(b *dynamodb.BackupSummary) bool {
+		arns = append(arns, aws.StringValue(b.BackupArn))
+		return len(arns) < 3
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{listedArn("a"), listedArn("b"), listedArn("c")}; !reflect.DeepEqual(arns, expect) {
+		t.Fatal(pretty.Compare(arns, expect))
+	}
+	if got := len(ops()); got != 5 {
+		t.Fatalf("expected 5 ListBackups calls, got %d", got)
+	}
+}
@@ -0,0 +1,165 @@
+package dynamodbx
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kynrai/dynamodbx/expr"
+)
+
+var (
+	ErrBatchUpdateNilInput = errors.New("dynamodbx/BatchUpdateItem: updates cannot be nil")
+	ErrUpdateRequestKey    = errors.New("dynamodbx/UpdateItemRequest: table name and key cannot be empty")
+)
+
+// BatchUpdateItemInput describes the updates of a BatchUpdateItem.
+type BatchUpdateItemInput struct {
+	// Updates are the UpdateItem requests to make, in any order. Each can have its own condition,
+	// ReturnValues and ReturnConsumedCapacity.
+	Updates []*dynamodb.UpdateItemInput
+	// Concurrency is the number of updates made at once. Defaults to 4.
+	Concurrency int
+	// WritesPerSecond limits how many items are updated per second. Zero is unlimited.
+	WritesPerSecond int
+}
+
+// BatchUpdateItemResult is the result of an update of a BatchUpdateItem.
+type BatchUpdateItemResult struct {
+	// Attributes are the attributes requested by the ReturnValues of the update.
+	Attributes map[string]*dynamodb.AttributeValue
+	// ConditionFailed is true if the item was not updated because its condition failed.
+	ConditionFailed bool
+}
+
+// BatchUpdateItemOutput reports the results of a BatchUpdateItem. Results holds the result of each
+// update in the same order as the input. ConsumedCapacity is aggregated for each table.
+type BatchUpdateItemOutput struct {
+	Results          []*BatchUpdateItemResult
+	ConsumedCapacity []*dynamodb.ConsumedCapacity
+}
+
+// UpdateItemRequest creates an UpdateItemInput for use with BatchUpdateItem from the key of an item,
+// an update and a condition which may be unset.
+func UpdateItemRequest(table string, key map[string]*dynamodb.AttributeValue, update expr.UpdateBuilder, cond expr.ConditionBuilder) (*dynamodb.UpdateItemInput, error) {
+	if table == "" || len(key) == 0 {
+		return nil, ErrUpdateRequestKey
+	}
+	b := expr.NewBuilder().WithUpdate(update)
+	if cond.IsSet() {
+		b = b.WithCondition(cond)
+	}
+	e, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(table),
+		Key:                       key,
+		UpdateExpression:          e.Update(),
+		ConditionExpression:       e.Condition(),
+		ExpressionAttributeNames:  e.Names(),
+		ExpressionAttributeValues: e.Values(),
+	}, nil
+}
+
+// BatchUpdateItem makes many UpdateItem requests concurrently, as dynamodb has no batch update.
+// Updates whose condition fails are reported in their result rather than as an error.
+//
+// Throttled updates are retried with an exponential backoff once the retries of the client are
+// exhausted, in the same way as the unprocessed items of BatchWriteItem. Any other error stops the
+// updates and is returned along with the output so far, in which the results of the updates which
+// were not made are nil.
+func BatchUpdateItem(client *dynamodb.DynamoDB, input *BatchUpdateItemInput) (*BatchUpdateItemOutput, error) {
+	return BatchUpdateItemWithContext(context.Background(), client, input)
+}
+
+// BatchUpdateItemWithContext is the same as BatchUpdateItem.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func BatchUpdateItemWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *BatchUpdateItemInput, opts ...request.Option) (*BatchUpdateItemOutput, error) {
+	for _, update := range input.Updates {
+		if update == nil {
+			return nil, ErrBatchUpdateNilInput
+		}
+		if err := update.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		out      = &BatchUpdateItemOutput{Results: make([]*BatchUpdateItemResult, len(input.Updates))}
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	limiter := newRateLimiter(float64(input.WritesPerSecond))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := limiter.wait(ctx, 1); err != nil {
+					fail(err)
+					continue
+				}
+				var res *dynamodb.UpdateItemOutput
+				err := retryThrottled(ctx, func() (err error) {
+					res, err = client.UpdateItemWithContext(ctx, input.Updates[i], opts...)
+					return err
+				})
+				if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+					mu.Lock()
+					out.Results[i] = &BatchUpdateItemResult{ConditionFailed: true}
+					mu.Unlock()
+					continue
+				}
+				if err != nil {
+					fail(err)
+					continue
+				}
+				mu.Lock()
+				out.Results[i] = &BatchUpdateItemResult{Attributes: res.Attributes}
+				if res.ConsumedCapacity != nil {
+					out.ConsumedCapacity = append(out.ConsumedCapacity, res.ConsumedCapacity)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+send:
+	for i := range input.Updates {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	out.ConsumedCapacity = sumConsumedCapacity(out.ConsumedCapacity)
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return out, firstErr
+}
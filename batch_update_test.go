@@ -0,0 +1,124 @@
+package dynamodbx_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/dynamodbxtest"
+	"github.com/kynrai/dynamodbx/expr"
+	"github.com/kynrai/dynamodbx/faultdb"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func TestBatchUpdateItem(t *testing.T) {
+	t.Parallel()
+	type Item struct {
+		S string
+		N int
+	}
+	key := func(s string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{"S": {S: aws.String(s)}}
+	}
+	ddb := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec, []Item{{"a", 1}, {"b", 2}, {"c", 3}})
+
+	// Increment items with N below 3, creating those which do not exist
+	var updates []*dynamodb.UpdateItemInput
+	for _, s := range []string{"a", "b", "c", "d"} {
+		req, err := dynamodbx.UpdateItemRequest(tbl.Name, key(s),
+			expr.Set(expr.Name("N"), expr.Plus(expr.Name("N").IfNotExists(expr.Value(0)), expr.Value(1))),
+			expr.Or(expr.Name("N").AttributeNotExists(), expr.Name("N").LessThan(expr.Value(3))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.ReturnValues = aws.String(dynamodb.ReturnValueUpdatedNew)
+		req.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+		updates = append(updates, req)
+	}
+	out, err := dynamodbx.BatchUpdateItem(ddb, &dynamodbx.BatchUpdateItemInput{Updates: updates, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := func(v string) map[string]*dynamodb.AttributeValue {
+		return map[string]*dynamodb.AttributeValue{"N": {N: aws.String(v)}}
+	}
+	expect := &dynamodbx.BatchUpdateItemOutput{
+		Results: []*dynamodbx.BatchUpdateItemResult{
+			{Attributes: n("2")},
+			{Attributes: n("3")},
+			{ConditionFailed: true},
+			{Attributes: n("1")},
+		},
+		ConsumedCapacity: []*dynamodb.ConsumedCapacity{{TableName: aws.String(tbl.Name), CapacityUnits: aws.Float64(3)}},
+	}
+	if !reflect.DeepEqual(out, expect) {
+		t.Fatal(pretty.Compare(out, expect))
+	}
+	tbl.AssertItems([]Item{{"a", 2}, {"b", 3}, {"c", 3}, {"d", 1}})
+}
+
+func TestBatchUpdateItemErrors(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name    string
+		updates []*dynamodb.UpdateItemInput
+		err     error
+	}{
+		{
+			name:    "nil update",
+			updates: []*dynamodb.UpdateItemInput{nil},
+			err:     dynamodbx.ErrBatchUpdateNilInput,
+		},
+		{
+			name:    "no table",
+			updates: []*dynamodb.UpdateItemInput{{TableName: aws.String("missing"), Key: map[string]*dynamodb.AttributeValue{"S": {S: aws.String("a")}}}},
+		},
+		{
+			name:    "invalid update",
+			updates: []*dynamodb.UpdateItemInput{{}},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := dynamodbx.BatchUpdateItem(memdb.NewClient(), &dynamodbx.BatchUpdateItemInput{Updates: tc.updates})
+			if err == nil || tc.err != nil && err != tc.err {
+				t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+			}
+		})
+	}
+	if _, err := dynamodbx.UpdateItemRequest("", nil, expr.Remove(expr.Name("A")), expr.ConditionBuilder{}); err != dynamodbx.ErrUpdateRequestKey {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrUpdateRequestKey)
+	}
+}
+
+func TestBatchUpdateItemThrottled(t *testing.T) {
+	t.Parallel()
+	mem := memdb.NewClient(&aws.Config{MaxRetries: aws.Int(0)})
+	tbl := dynamodbxtest.NewTestTable(t, mem, batchWriteSpec)
+	faults := faultdb.New(faultdb.Config{Seed: 1, ThrottleRate: 0.3, Operations: []string{"UpdateItem"}})
+	ddb := faults.Wrap(mem)
+	var updates []*dynamodb.UpdateItemInput
+	var expect []map[string]*dynamodb.AttributeValue
+	for i := 0; i < 50; i++ {
+		key := map[string]*dynamodb.AttributeValue{"S": {S: aws.String(strconv.Itoa(i))}}
+		req, err := dynamodbx.UpdateItemRequest(tbl.Name, key, expr.Add(expr.Name("N"), expr.Value(1)), expr.ConditionBuilder{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		updates = append(updates, req)
+		expect = append(expect, map[string]*dynamodb.AttributeValue{"S": key["S"], "N": {N: aws.String("1")}})
+	}
+	if _, err := dynamodbx.BatchUpdateItem(ddb, &dynamodbx.BatchUpdateItemInput{Updates: updates}); err != nil {
+		t.Fatal(err)
+	}
+	if faults.Stats().Throttled == 0 {
+		t.Fatal("expected some updates to be throttled")
+	}
+	tbl.AssertItems(expect)
+}
@@ -2,81 +2,33 @@ package dynamodbx
 
 import (
 	"context"
+	"math"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
+const (
+	// batchWriteSize is the maximum number of items dynamodb accepts in a single BatchWriteItem call
+	batchWriteSize = 25
+	// batchWriteRetries is how many consecutive calls may leave items unprocessed before giving up
+	batchWriteRetries = 10
+)
+
 // BatchWriteItem is a wrapper around the aws-sdk-go dynamodb.BatchWriteItem. It will attemptt to
 // automatically breakup batch writes into smaller batches so that inputs larger tthan 25 items
 // can be easily processed. Use it as a drop in replacement for the existting BatchWriteItem command
 // but with the dynamodb client supplied as the first paramter.
+//
+// Unprocessed items are retried along with the next batch, backing off from 50ms and doubling up to
+// 5s between attempts. After 10 consecutive attempts leave items unprocessed, the items not yet
+// written are returned in UnprocessedItems, in the same way as the aws-sdk-go. A call can therefore
+// block for around 20s while dynamodb is throttling writes; use BatchWriteItemWithContext to bound it.
 func BatchWriteItem(client *dynamodb.DynamoDB, input *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
-	fOut := &dynamodb.BatchWriteItemOutput{}
-	for tableName, items := range input.RequestItems {
-		var unprocessedItems map[string][]*dynamodb.WriteRequest
-		chunkSize := 25
-		for i := 0; i < len(items); i += chunkSize {
-			end := i + chunkSize
-			if end > len(items) {
-				end = len(items)
-			}
-			batch := &dynamodb.BatchWriteItemInput{
-				ReturnConsumedCapacity:      input.ReturnConsumedCapacity,
-				ReturnItemCollectionMetrics: input.ReturnItemCollectionMetrics,
-				RequestItems: map[string][]*dynamodb.WriteRequest{
-					tableName: items[i:end],
-				},
-			}
-			if len(unprocessedItems) > 0 {
-				for k, v := range unprocessedItems {
-					batch.RequestItems[k] = append(batch.RequestItems[k], v...)
-				}
-			}
-			// after adding all the unprocessed items we reset the chunkSize back to 25
-			chunkSize = 25
-
-			out, err := client.BatchWriteItem(batch)
-			if err != nil {
-				return nil, err
-			}
-			fOut.ConsumedCapacity = append(fOut.ConsumedCapacity, out.ConsumedCapacity...)
-			for k, v := range out.ItemCollectionMetrics {
-				fOut.ItemCollectionMetrics[k] = append(fOut.ItemCollectionMetrics[k], v...)
-			}
-
-			// If we have no unprocessed items we make sure the next iteration has the full chunkSize
-			if len(out.UnprocessedItems) == 0 {
-				chunkSize = 25
-				continue
-			}
-
-			// We have unprocessed items at this point, we deduct the number from the chunkSize to add
-			// them both next iteration
-			chunkSize -= len(out.UnprocessedItems)
-		}
-	}
-	// Sum up multiple ConsumedCapacity structs
-	sum := make(map[string]*dynamodb.ConsumedCapacity)
-	for _, v := range fOut.ConsumedCapacity {
-		if _, ok := sum[*v.TableName]; !ok {
-			sum[*v.TableName] = &dynamodb.ConsumedCapacity{
-				CapacityUnits: v.CapacityUnits,
-			}
-			continue
-		}
-		*sum[*v.TableName].CapacityUnits += *v.CapacityUnits
-	}
-	sliceCap := make([]*dynamodb.ConsumedCapacity, 0, len(sum))
-	for k, v := range sum {
-		sliceCap = append(sliceCap, &dynamodb.ConsumedCapacity{
-			TableName:     aws.String(k),
-			CapacityUnits: v.CapacityUnits,
-		})
-	}
-	fOut.ConsumedCapacity = sliceCap
-	return fOut, nil
+	return batchWriteItem(context.Background(), client, input)
 }
 
 // BatchWriteItemWithContext is a wrapper around the aws-sdk-go dynamodb.BatchWriteItemWithContext. It will attemptt to
@@ -85,60 +37,130 @@ func BatchWriteItem(client *dynamodb.DynamoDB, input *dynamodb.BatchWriteItemInp
 // but with the dynamodb client supplied as the first paramter.
 // A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
 func BatchWriteItemWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
+	return batchWriteItem(ctx, client, input, opts...)
+}
+
+// tableWriteRequest is a WriteRequest along with the table it is for.
+type tableWriteRequest struct {
+	table string
+	req   *dynamodb.WriteRequest
+}
+
+func batchWriteItem(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.BatchWriteItemInput, opts ...request.Option) (*dynamodb.BatchWriteItemOutput, error) {
 	fOut := &dynamodb.BatchWriteItemOutput{}
+	var pending []tableWriteRequest
 	for tableName, items := range input.RequestItems {
-		var unprocessedItems map[string][]*dynamodb.WriteRequest
-		chunkSize := 25
-		for i := 0; i < len(items); i += chunkSize {
-			end := i + chunkSize
-			if end > len(items) {
-				end = len(items)
-			}
-			batch := &dynamodb.BatchWriteItemInput{
-				ReturnConsumedCapacity:      input.ReturnConsumedCapacity,
-				ReturnItemCollectionMetrics: input.ReturnItemCollectionMetrics,
-				RequestItems: map[string][]*dynamodb.WriteRequest{
-					tableName: items[i:end],
-				},
-			}
-			if len(unprocessedItems) > 0 {
-				for k, v := range unprocessedItems {
-					batch.RequestItems[k] = append(batch.RequestItems[k], v...)
-				}
+		for _, item := range items {
+			pending = append(pending, tableWriteRequest{table: tableName, req: item})
+		}
+	}
+
+	attempts := 0
+	for len(pending) > 0 {
+		end := batchWriteSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := &dynamodb.BatchWriteItemInput{
+			ReturnConsumedCapacity:      input.ReturnConsumedCapacity,
+			ReturnItemCollectionMetrics: input.ReturnItemCollectionMetrics,
+			RequestItems:                make(map[string][]*dynamodb.WriteRequest),
+		}
+		for _, v := range pending[:end] {
+			batch.RequestItems[v.table] = append(batch.RequestItems[v.table], v.req)
+		}
+		pending = pending[end:]
+
+		out, err := client.BatchWriteItemWithContext(ctx, batch, opts...)
+		if err != nil {
+			return nil, err
+		}
+		fOut.ConsumedCapacity = append(fOut.ConsumedCapacity, out.ConsumedCapacity...)
+		for k, v := range out.ItemCollectionMetrics {
+			if fOut.ItemCollectionMetrics == nil {
+				fOut.ItemCollectionMetrics = make(map[string][]*dynamodb.ItemCollectionMetrics)
 			}
-			// after adding all the unprocessed items we reset the chunkSize back to 25
-			chunkSize = 25
+			fOut.ItemCollectionMetrics[k] = append(fOut.ItemCollectionMetrics[k], v...)
+		}
 
-			out, err := client.BatchWriteItemWithContext(ctx, batch, opts...)
-			if err != nil {
-				return nil, err
+		// If we have no unprocessed items the next batch is sent straight away
+		if len(out.UnprocessedItems) == 0 {
+			attempts = 0
+			continue
+		}
+
+		// We have unprocessed items at this point, they are put at the front of the queue so they are
+		// sent with the next batch after backing off
+		var unprocessed []tableWriteRequest
+		for k, v := range out.UnprocessedItems {
+			for _, item := range v {
+				unprocessed = append(unprocessed, tableWriteRequest{table: k, req: item})
 			}
-			fOut.ConsumedCapacity = append(fOut.ConsumedCapacity, out.ConsumedCapacity...)
-			for k, v := range out.ItemCollectionMetrics {
-				fOut.ItemCollectionMetrics[k] = append(fOut.ItemCollectionMetrics[k], v...)
+		}
+		pending = append(unprocessed, pending...)
+		attempts++
+		if attempts > batchWriteRetries {
+			fOut.UnprocessedItems = make(map[string][]*dynamodb.WriteRequest)
+			for _, v := range pending {
+				fOut.UnprocessedItems[v.table] = append(fOut.UnprocessedItems[v.table], v.req)
 			}
+			break
+		}
+		if err := aws.SleepWithContext(ctx, batchWriteBackoff(attempts)); err != nil {
+			return nil, err
+		}
+	}
+	fOut.ConsumedCapacity = sumConsumedCapacity(fOut.ConsumedCapacity)
+	return fOut, nil
+}
 
-			// If we have no unprocessed items we make sure the next iteration has the full chunkSize
-			if len(out.UnprocessedItems) == 0 {
-				chunkSize = 25
-				continue
-			}
+// batchWriteBackoff is the delay before retrying unprocessed items, doubling from 50ms up to 5s.
+func batchWriteBackoff(attempt int) time.Duration {
+	delay := time.Duration(math.Pow(2, float64(attempt-1))) * 50 * time.Millisecond
+	if delay > 5*time.Second {
+		delay = 5 * time.Second
+	}
+	return delay
+}
 
-			// We have unprocessed items at this point, we deduct the number from the chunkSize to add
-			// them both next iteration
-			chunkSize -= len(out.UnprocessedItems)
+// retryThrottled calls write, retrying it with the backoff of batchWriteBackoff while it is
+// throttled once the retries of the client are exhausted, up to batchWriteRetries times.
+func retryThrottled(ctx context.Context, write func() error) error {
+	for attempts := 1; ; attempts++ {
+		err := write()
+		if err == nil || attempts > batchWriteRetries || !throttled(err) {
+			return err
 		}
+		if err := aws.SleepWithContext(ctx, batchWriteBackoff(attempts)); err != nil {
+			return err
+		}
+	}
+}
+
+// throttled reports whether err is a throttling error.
+func throttled(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
 	}
-	// Sum up multiple ConsumedCapacity structs
+	switch aerr.Code() {
+	case dynamodb.ErrCodeProvisionedThroughputExceededException, dynamodb.ErrCodeRequestLimitExceeded, "ThrottlingException":
+		return true
+	}
+	return false
+}
+
+// sumConsumedCapacity sums up multiple ConsumedCapacity structs into one per table.
+func sumConsumedCapacity(caps []*dynamodb.ConsumedCapacity) []*dynamodb.ConsumedCapacity {
 	sum := make(map[string]*dynamodb.ConsumedCapacity)
-	for _, v := range fOut.ConsumedCapacity {
+	for _, v := range caps {
 		if _, ok := sum[*v.TableName]; !ok {
 			sum[*v.TableName] = &dynamodb.ConsumedCapacity{
-				CapacityUnits: v.CapacityUnits,
+				CapacityUnits: aws.Float64(aws.Float64Value(v.CapacityUnits)),
 			}
 			continue
 		}
-		*sum[*v.TableName].CapacityUnits += *v.CapacityUnits
+		*sum[*v.TableName].CapacityUnits += aws.Float64Value(v.CapacityUnits)
 	}
 	sliceCap := make([]*dynamodb.ConsumedCapacity, 0, len(sum))
 	for k, v := range sum {
@@ -147,6 +169,5 @@ func BatchWriteItemWithContext(ctx context.Context, client *dynamodb.DynamoDB, i
 			CapacityUnits: v.CapacityUnits,
 		})
 	}
-	fOut.ConsumedCapacity = sliceCap
-	return fOut, nil
+	return sliceCap
 }
@@ -2,6 +2,8 @@ package dynamodbx_test
 
 import (
 	"context"
+	"flag"
+	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
@@ -14,9 +16,59 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/kylelemons/godebug/pretty"
 	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/dynamodbxtest"
+	"github.com/kynrai/dynamodbx/faultdb"
+	"github.com/kynrai/dynamodbx/memdb"
+	"github.com/kynrai/dynamodbx/replay"
 )
 
-// TODO: Find a way to to test unprocessed items
+var (
+	record   = flag.Bool("record", false, "record the golden files of replayed tests")
+	endpoint = flag.String("endpoint", "", "dynamodb endpoint to record against, e.g. http://localhost:8000 for DynamoDB Local")
+)
+
+// replayClient returns a client replaying the interactions in testdata/replay/name, or recording
+// them with -record against -endpoint. The golden files are recorded against DynamoDB Local rather
+// than memdb, so that replaying them checks the behaviour of dynamodb itself. The returned func
+// closes the transport, failing the test on a mismatch.
+func replayClient(t *testing.T, name string) (*dynamodb.DynamoDB, func()) {
+	t.Helper()
+	dir := "testdata/replay/" + name
+	url := "http://replay.invalid"
+	var tr *replay.Transport
+	if *record {
+		if url = *endpoint; url == "" {
+			t.Fatal("-record needs -endpoint, e.g. -endpoint http://localhost:8000 for DynamoDB Local")
+		}
+		tr = replay.NewRecorder(dir, nil)
+	} else {
+		var err error
+		if tr, err = replay.NewReplayer(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ddb := dynamodb.New(session.Must(session.NewSession()), &aws.Config{
+		Region:      aws.String("eu-west-1"),
+		Endpoint:    aws.String(url),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		HTTPClient:  &http.Client{Transport: tr},
+	})
+	return ddb, func() {
+		t.Helper()
+		if err := tr.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// batchWriteSpec is the table written by the BatchWriteItem tests.
+var batchWriteSpec = &dynamodb.CreateTableInput{
+	TableName:            aws.String("testBatchWrite"),
+	BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+	AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("S"), AttributeType: aws.String("S")}},
+	KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("S"), KeyType: aws.String("HASH")}},
+}
+
 func TestBatchWriteItem(t *testing.T) {
 	t.Parallel()
 	type TestData struct {
@@ -32,87 +84,28 @@ func TestBatchWriteItem(t *testing.T) {
 		return data
 	}
 	for _, tc := range []struct {
-		name   string
-		table  string
-		input  interface{}
-		expect *dynamodb.BatchWriteItemOutput
+		name  string
+		input []*TestData
 	}{
 		{
 			name:  "insert 2 items",
-			table: "test1",
 			input: testDataSet(2),
-			expect: &dynamodb.BatchWriteItemOutput{
-				ConsumedCapacity: []*dynamodb.ConsumedCapacity{
-					{
-						CapacityUnits: aws.Float64(2),
-						TableName:     aws.String("test1"),
-					},
-				},
-			},
 		},
 		{
 			name:  "insert 115 items",
-			table: "testSet",
 			input: testDataSet(115),
-			expect: &dynamodb.BatchWriteItemOutput{
-				ConsumedCapacity: []*dynamodb.ConsumedCapacity{
-					{
-						CapacityUnits: aws.Float64(115),
-						TableName:     aws.String("testSet"),
-					},
-				},
-			},
 		},
 		{
 			name:  "insert 1000 items",
-			table: "testLargeSet",
 			input: testDataSet(1000),
-			expect: &dynamodb.BatchWriteItemOutput{
-				ConsumedCapacity: []*dynamodb.ConsumedCapacity{
-					{
-						CapacityUnits: aws.Float64(1000),
-						TableName:     aws.String("testLargeSet"),
-					},
-				},
-			},
 		},
 	} {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			// Must have a local dynamodb running
-			ddb := dynamodb.New(
-				session.Must(session.NewSession(
-					&aws.Config{
-						Region:      aws.String("eu-west-1"),
-						Endpoint:    aws.String("http://localhost:8000"),
-						Credentials: credentials.NewStaticCredentials("foo", "bar", "foobar"),
-					},
-				)),
-			)
-			_, err := dynamodbx.CreateTableSync(ddb, &dynamodb.CreateTableInput{
-				TableName:   aws.String(tc.table),
-				BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
-				AttributeDefinitions: []*dynamodb.AttributeDefinition{
-					{
-						AttributeName: aws.String("S"),
-						AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
-					},
-				},
-				KeySchema: []*dynamodb.KeySchemaElement{
-					{
-						AttributeName: aws.String("S"),
-						KeyType:       aws.String(dynamodb.KeyTypeHash),
-					},
-				},
-			})
-			defer ddb.DeleteTable(&dynamodb.DeleteTableInput{
-				TableName: aws.String(tc.table),
-			})
-			if err != nil {
-				t.Fatal(err)
-			}
-			req, err := dynamodbx.BatchPutRequest(tc.table, tc.input)
+			ddb := memdb.NewClient()
+			tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec)
+			req, err := dynamodbx.BatchPutRequest(tbl.Name, tc.input)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -124,9 +117,18 @@ func TestBatchWriteItem(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			if tc.expect != nil && !reflect.DeepEqual(out, tc.expect) {
-				t.Fatalf(pretty.Compare(out, tc.expect))
+			expect := &dynamodb.BatchWriteItemOutput{
+				ConsumedCapacity: []*dynamodb.ConsumedCapacity{
+					{
+						CapacityUnits: aws.Float64(float64(len(tc.input))),
+						TableName:     aws.String(tbl.Name),
+					},
+				},
 			}
+			if !reflect.DeepEqual(out, expect) {
+				t.Fatal(pretty.Compare(out, expect))
+			}
+			tbl.AssertItems(tc.input)
 		})
 	}
 }
@@ -208,16 +210,7 @@ func TestBatchWriteItemWithContext(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			// Must have a local dynamodb running
-			ddb := dynamodb.New(
-				session.Must(session.NewSession(
-					&aws.Config{
-						Region:      aws.String("eu-west-1"),
-						Endpoint:    aws.String("http://localhost:8000"),
-						Credentials: credentials.NewStaticCredentials("foo", "bar", "foobar"),
-					},
-				)),
-			)
+			ddb := memdb.NewClient()
 			_, err := dynamodbx.CreateTableSyncWithContext(tc.ctx, ddb, &dynamodb.CreateTableInput{
 				TableName:   aws.String(tc.table),
 				BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
@@ -256,8 +249,85 @@ func TestBatchWriteItemWithContext(t *testing.T) {
 				t.Fatal(err)
 			}
 			if tc.expect != nil && !reflect.DeepEqual(out, tc.expect) {
-				t.Fatalf(pretty.Compare(out, tc.expect))
+				t.Fatal(pretty.Compare(out, tc.expect))
 			}
 		})
 	}
 }
+
+func TestBatchWriteItemUnprocessed(t *testing.T) {
+	t.Parallel()
+	type TestData struct {
+		S string
+	}
+	data := make([]*TestData, 100)
+	for i := range data {
+		data[i] = &TestData{S: strconv.Itoa(i)}
+	}
+	mem := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, mem, batchWriteSpec)
+	faults := faultdb.New(faultdb.Config{Seed: 1, UnprocessedRate: 0.1, ThrottleRate: 0.1})
+	ddb := faults.Wrap(mem)
+	req, err := dynamodbx.BatchPutRequest(tbl.Name, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := dynamodbx.BatchWriteItem(ddb, &dynamodb.BatchWriteItemInput{RequestItems: req})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.UnprocessedItems) != 0 {
+		t.Fatalf("expected all items to be written, got %d tables unprocessed", len(out.UnprocessedItems))
+	}
+	if faults.Stats().UnprocessedItems == 0 {
+		t.Fatal("expected some items to be returned unprocessed")
+	}
+	tbl.AssertItems(data)
+}
+
+func TestBatchWriteItemReplay(t *testing.T) {
+	t.Parallel()
+	type TestData struct {
+		S string
+	}
+	data := make([]*TestData, 30)
+	for i := range data {
+		data[i] = &TestData{S: strconv.Itoa(i)}
+	}
+	ddb, done := replayClient(t, "batch_write")
+	defer done()
+	_, err := dynamodbx.CreateTableSync(ddb, &dynamodb.CreateTableInput{
+		TableName:            aws.String("testReplay"),
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("S"), AttributeType: aws.String("S")}},
+		KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("S"), KeyType: aws.String("HASH")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := dynamodbx.BatchPutRequest("testReplay", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := dynamodbx.BatchWriteItem(ddb, &dynamodb.BatchWriteItemInput{
+		ReturnConsumedCapacity: aws.String("TOTAL"),
+		RequestItems:           req,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &dynamodb.BatchWriteItemOutput{
+		ConsumedCapacity: []*dynamodb.ConsumedCapacity{
+			{
+				CapacityUnits: aws.Float64(30),
+				TableName:     aws.String("testReplay"),
+			},
+		},
+	}
+	if !reflect.DeepEqual(out, expect) {
+		t.Fatal(pretty.Compare(out, expect))
+	}
+	if _, err := dynamodbx.DeleteTableSync(ddb, &dynamodb.DeleteTableInput{TableName: aws.String("testReplay")}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,17 +1,23 @@
 package dynamodbx
 
 import (
+	"context"
 	"errors"
 	"reflect"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/kynrai/dynamodbx/expr"
 )
 
 var (
-	ErrEmptyTableName = errors.New("dynamodbx/BatchPutRequest: table name cannot be empty")
-	ErrInterfaceNil   = errors.New("dynamodbx/BatchPutRequest: interface cannot be nil")
-	ErrInterfaceSlice = errors.New("dynamodbx/BatchPutRequest: the interface is not a slice")
+	ErrEmptyTableName      = errors.New("dynamodbx/BatchPutRequest: table name cannot be empty")
+	ErrInterfaceNil        = errors.New("dynamodbx/BatchPutRequest: interface cannot be nil")
+	ErrInterfaceSlice      = errors.New("dynamodbx/BatchPutRequest: the interface is not a slice")
+	ErrBatchPutVersion     = errors.New("dynamodbx/BatchPutRequest: versioned items cannot be batch written, use BatchPut")
+	ErrBatchPutUnprocessed = errors.New("dynamodbx/BatchPut: some items could not be processed")
 )
 
 // BatchPutRequest creates a dynamodb WriteRequest batch for use with requests which require
@@ -27,7 +33,25 @@ var (
 //
 // The input v must be a slice of golang structs which can be converted to dynamodb attrivuted
 // using the dynamodbattribute.MarshalMap function. v Cannot be nil.
-func BatchPutRequest(table string, v interface{}) (map[string][]*dynamodb.WriteRequest, error) {
+//
+// Structs with timestamp fields, tagged `dynamodbx:"created"` and `dynamodbx:"updated"`, are
+// stamped with the time of the clock set by WithClock: the updated time is always set, and the
+// created time is set if the field is unset. The structs themselves are not modified.
+//
+// Structs with a time to live field, tagged `dynamodbx:"ttl"`, expire each item at its own time: a
+// time.Time field is the time the item expires, and a time.Duration field is how long after the
+// time of the clock it expires. Either is written as the unix epoch time in seconds, and items
+// whose field is unset are written without it.
+//
+// Structs with a version field, tagged `dynamodbx:"version"`, are rejected with ErrBatchPutVersion,
+// as a PutRequest can neither check nor increment their version. Write them with BatchPut, which
+// falls back to conditional puts for them.
+func BatchPutRequest(table string, v interface{}, opts ...ItemOption) (map[string][]*dynamodb.WriteRequest, error) {
+	return batchPutRequest(table, v, clockTime(opts))
+}
+
+// batchPutRequest is BatchPutRequest stamping items with now.
+func batchPutRequest(table string, v interface{}, now time.Time) (map[string][]*dynamodb.WriteRequest, error) {
 	if table == "" {
 		return nil, ErrEmptyTableName
 	}
@@ -37,6 +61,25 @@ func BatchPutRequest(table string, v interface{}) (map[string][]*dynamodb.WriteR
 	if reflect.TypeOf(v).Kind() != reflect.Slice {
 		return nil, ErrInterfaceSlice
 	}
+	var (
+		ts  *timestamps
+		exp *ttl
+	)
+	if t := structType(v); t != nil {
+		ver, err := itemVersion(t)
+		if err != nil {
+			return nil, err
+		}
+		if ver != nil {
+			return nil, ErrBatchPutVersion
+		}
+		if ts, err = itemTimestamps(t); err != nil {
+			return nil, err
+		}
+		if exp, err = itemTTL(t); err != nil {
+			return nil, err
+		}
+	}
 	items := reflect.ValueOf(v)
 	reqs := make([]*dynamodb.WriteRequest, 0, items.Len())
 
@@ -45,6 +88,12 @@ func BatchPutRequest(table string, v interface{}) (map[string][]*dynamodb.WriteR
 		if err != nil {
 			return nil, err
 		}
+		if ts != nil {
+			ts.put(items.Index(i), data, now)
+		}
+		if exp != nil {
+			exp.put(items.Index(i), data, now)
+		}
 		req := &dynamodb.WriteRequest{
 			PutRequest: &dynamodb.PutRequest{Item: data},
 		}
@@ -52,3 +101,94 @@ func BatchPutRequest(table string, v interface{}) (map[string][]*dynamodb.WriteR
 	}
 	return map[string][]*dynamodb.WriteRequest{table: reqs}, nil
 }
+
+// BatchPut writes v, a slice of go structs, to the table as BatchPutRequest and BatchWriteItem do
+// together. ErrBatchPutUnprocessed is returned if some items are still unprocessed after several
+// attempts.
+//
+// BatchWriteItem cannot check versions, so structs with a version field, tagged
+// `dynamodbx:"version"`, are written with ConditionalPutRequest and ConditionalBatchPut instead.
+// The versions of the items written are incremented if they are pointers, and ErrVersionConflict
+// is returned after the other items are written if some items were modified since they were read.
+// Timestamps are stamped with the time of the clock set by WithClock, and are set in the items
+// written if they are pointers.
+func BatchPut(client *dynamodb.DynamoDB, table string, v interface{}, opts ...ItemOption) error {
+	return BatchPutWithContext(context.Background(), client, table, v, opts...)
+}
+
+// BatchPutWithContext is the same as BatchPut.
+// A context can be provided to and will be passed to the underlying aws-sdk-go calls
+func BatchPutWithContext(ctx context.Context, client *dynamodb.DynamoDB, table string, v interface{}, opts ...ItemOption) error {
+	if table == "" {
+		return ErrEmptyTableName
+	}
+	var (
+		ver *version
+		ts  *timestamps
+	)
+	if t := structType(v); t != nil {
+		var err error
+		if ver, err = itemVersion(t); err != nil {
+			return err
+		}
+		if ts, err = itemTimestamps(t); err != nil {
+			return err
+		}
+	}
+	return batchPut(ctx, client, table, v, ver, ts, clockTime(opts))
+}
+
+// batchPut writes the items with BatchWriteItem, or with ConditionalBatchPut if they have a version,
+// and sets the versions and timestamps of the items written.
+func batchPut(ctx context.Context, client *dynamodb.DynamoDB, table string, items interface{}, ver *version, ts *timestamps, now time.Time, opts ...request.Option) error {
+	if ver != nil {
+		return batchPutVersioned(ctx, client, table, items, ver, ts, now, opts...)
+	}
+	req, err := batchPutRequest(table, items, now)
+	if err != nil {
+		return err
+	}
+	res, err := batchWriteItem(ctx, client, &dynamodb.BatchWriteItemInput{RequestItems: req}, opts...)
+	if err != nil {
+		return err
+	}
+	if len(res.UnprocessedItems) > 0 {
+		return ErrBatchPutUnprocessed
+	}
+	if ts != nil {
+		iv := reflect.ValueOf(items)
+		for i := 0; i < iv.Len(); i++ {
+			ts.written(iv.Index(i), now)
+		}
+	}
+	return nil
+}
+
+// batchPutVersioned writes versioned items with conditional puts.
+func batchPutVersioned(ctx context.Context, client *dynamodb.DynamoDB, table string, items interface{}, ver *version, ts *timestamps, now time.Time, opts ...request.Option) error {
+	puts, err := conditionalPutRequest(items, expr.ConditionBuilder{}, now)
+	if err != nil {
+		return err
+	}
+	res, err := ConditionalBatchPutWithContext(ctx, client, &ConditionalBatchPutInput{TableName: table, Items: puts}, opts...)
+	if err != nil {
+		return err
+	}
+	skipped := make(map[*ConditionalPut]bool, len(res.Skipped))
+	for _, put := range res.Skipped {
+		skipped[put] = true
+	}
+	iv := reflect.ValueOf(items)
+	for i, put := range puts {
+		if !skipped[put] {
+			ver.increment(iv.Index(i))
+			if ts != nil {
+				ts.written(iv.Index(i), now)
+			}
+		}
+	}
+	if len(skipped) > 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package dynamodbx
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrCloneTableName   = errors.New("dynamodbx/CloneTable: source and target table names cannot be empty")
+	ErrCloneUnprocessed = errors.New("dynamodbx/CloneTable: some items could not be written")
+)
+
+// cloneSegments is the default number of parallel scan segments used by CloneTable
+const cloneSegments = 4
+
+// CloneTableInput describes the table to clone and how its items are copied.
+type CloneTableInput struct {
+	SourceTableName string
+	TargetTableName string
+	// Filter is called with the key attributes of every source item. Items are only copied when it
+	// returns true. If nil every item is copied.
+	Filter func(key map[string]*dynamodb.AttributeValue) bool
+	// Transform is called with every item which passes Filter and returns the item to write. Items
+	// are skipped when it returns a nil item. If nil items are copied unchanged.
+	Transform func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error)
+	// WritesPerSecond limits how many items are written to the target per second. Zero is unlimited.
+	WritesPerSecond int
+	// Segments is the number of parallel scan segments. Defaults to 4.
+	Segments int
+}
+
+// CloneTableOutput reports the new table and the work done copying its data. ConsumedCapacity holds
+// the read capacity used on the source and the write capacity used on the target.
+type CloneTableOutput struct {
+	TableDescription *dynamodb.TableDescription
+	Copied           int64
+	Skipped          int64
+	ConsumedCapacity []*dynamodb.ConsumedCapacity
+}
+
+// CloneTable creates a new table with the same schema as the source, including keys, indexes,
+// billing mode and streams, waits for it to be active and then copies the source items into it
+// using a parallel scan and BatchWriteItem.
+func CloneTable(client *dynamodb.DynamoDB, input *CloneTableInput) (*CloneTableOutput, error) {
+	return CloneTableWithContext(context.Background(), client, input)
+}
+
+// CloneTableWithContext is the same as CloneTable.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func CloneTableWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *CloneTableInput, opts ...request.Option) (*CloneTableOutput, error) {
+	if input.SourceTableName == "" || input.TargetTableName == "" {
+		return nil, ErrCloneTableName
+	}
+	desc, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(input.SourceTableName)}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	create := CreateTableInputFromDescription(desc.Table)
+	create.TableName = aws.String(input.TargetTableName)
+	created, err := CreateTableSyncWithContext(ctx, client, create, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := input.Segments
+	if segments <= 0 {
+		segments = cloneSegments
+	}
+	limiter := newRateLimiter(float64(input.WritesPerSecond))
+	var (
+		mu  sync.Mutex
+		out = &CloneTableOutput{TableDescription: created.TableDescription}
+	)
+	err = scanSegments(ctx, client, &dynamodb.ScanInput{
+		TableName:              aws.String(input.SourceTableName),
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}, segments, func(_ int, page *dynamodb.ScanOutput) error {
+		var caps []*dynamodb.ConsumedCapacity
+		if page.ConsumedCapacity != nil {
+			caps = append(caps, page.ConsumedCapacity)
+		}
+		puts := make([]*dynamodb.WriteRequest, 0, len(page.Items))
+		for _, item := range page.Items {
+			if input.Filter != nil && !input.Filter(itemKey(desc.Table.KeySchema, item)) {
+				continue
+			}
+			if input.Transform != nil {
+				var err error
+				if item, err = input.Transform(item); err != nil {
+					return err
+				}
+				if item == nil {
+					continue
+				}
+			}
+			puts = append(puts, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+		}
+		unprocessed := 0
+		if len(puts) > 0 {
+			if err := limiter.wait(ctx, float64(len(puts))); err != nil {
+				return err
+			}
+			res, err := batchWriteItem(ctx, client, &dynamodb.BatchWriteItemInput{
+				ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+				RequestItems:           map[string][]*dynamodb.WriteRequest{input.TargetTableName: puts},
+			}, opts...)
+			if err != nil {
+				return err
+			}
+			caps = append(caps, res.ConsumedCapacity...)
+			unprocessed = len(res.UnprocessedItems[input.TargetTableName])
+		}
+		mu.Lock()
+		out.Copied += int64(len(puts) - unprocessed)
+		out.Skipped += int64(len(page.Items) - len(puts))
+		out.ConsumedCapacity = append(out.ConsumedCapacity, caps...)
+		mu.Unlock()
+		if unprocessed > 0 {
+			return ErrCloneUnprocessed
+		}
+		return nil
+	}, opts...)
+	out.ConsumedCapacity = sumConsumedCapacity(out.ConsumedCapacity)
+	return out, err
+}
+
+// itemKey returns the key attributes of item according to keySchema.
+func itemKey(keySchema []*dynamodb.KeySchemaElement, item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	key := make(map[string]*dynamodb.AttributeValue, len(keySchema))
+	for _, k := range keySchema {
+		name := aws.StringValue(k.AttributeName)
+		if v, ok := item[name]; ok {
+			key[name] = v
+		}
+	}
+	return key
+}
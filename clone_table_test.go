@@ -0,0 +1,157 @@
+package dynamodbx_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func TestCloneTable(t *testing.T) {
+	t.Parallel()
+	type TestData struct {
+		S string
+		N int
+	}
+	testDataSet := func(count int) []*TestData {
+		data := make([]*TestData, count)
+		for i := 0; i < count; i++ {
+			data[i] = &TestData{
+				S: strconv.Itoa(i),
+				N: i,
+			}
+		}
+		return data
+	}
+	for _, tc := range []struct {
+		name    string
+		input   *dynamodbx.CloneTableInput
+		count   int
+		err     error
+		copied  int64
+		skipped int64
+	}{
+		{
+			name:  "empty table names",
+			input: &dynamodbx.CloneTableInput{},
+			err:   dynamodbx.ErrCloneTableName,
+		},
+		{
+			name: "clone all items",
+			input: &dynamodbx.CloneTableInput{
+				SourceTableName: "testCloneSource",
+				TargetTableName: "testCloneTarget",
+			},
+			count:  150,
+			copied: 150,
+		},
+		{
+			name: "clone filtered and transformed items",
+			input: &dynamodbx.CloneTableInput{
+				SourceTableName: "testCloneFilterSource",
+				TargetTableName: "testCloneFilterTarget",
+				Filter: func(key map[string]*dynamodb.AttributeValue) bool {
+					n, _ := strconv.Atoi(*key["S"].S)
+					return n%2 == 0
+				},
+				Transform: func(item map[string]*dynamodb.AttributeValue) (map[string]*dynamodb.AttributeValue, error) {
+					if *item["S"].S == "0" {
+						return nil, nil
+					}
+					item["Cloned"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+					return item, nil
+				},
+				WritesPerSecond: 1000,
+				Segments:        2,
+			},
+			count:   100,
+			copied:  49,
+			skipped: 51,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ddb := memdb.NewClient()
+			if tc.err != nil {
+				if _, err := dynamodbx.CloneTable(ddb, tc.input); err != tc.err {
+					t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			_, err := dynamodbx.CreateTableSync(ddb, &dynamodb.CreateTableInput{
+				TableName:   aws.String(tc.input.SourceTableName),
+				BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{
+					{
+						AttributeName: aws.String("S"),
+						AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+					},
+					{
+						AttributeName: aws.String("N"),
+						AttributeType: aws.String(dynamodb.ScalarAttributeTypeN),
+					},
+				},
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{
+						AttributeName: aws.String("S"),
+						KeyType:       aws.String(dynamodb.KeyTypeHash),
+					},
+				},
+				GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+					{
+						IndexName: aws.String("byN"),
+						KeySchema: []*dynamodb.KeySchemaElement{
+							{
+								AttributeName: aws.String("N"),
+								KeyType:       aws.String(dynamodb.KeyTypeHash),
+							},
+						},
+						Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly)},
+					},
+				},
+			})
+			defer ddb.DeleteTable(&dynamodb.DeleteTableInput{
+				TableName: aws.String(tc.input.SourceTableName),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := dynamodbx.BatchPutRequest(tc.input.SourceTableName, testDataSet(tc.count))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := dynamodbx.BatchWriteItem(ddb, &dynamodb.BatchWriteItemInput{RequestItems: req}); err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := dynamodbx.CloneTable(ddb, tc.input)
+			defer ddb.DeleteTable(&dynamodb.DeleteTableInput{
+				TableName: aws.String(tc.input.TargetTableName),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Copied != tc.copied || out.Skipped != tc.skipped {
+				t.Fatalf("expected %d copied and %d skipped, got %d and %d", tc.copied, tc.skipped, out.Copied, out.Skipped)
+			}
+			desc, err := ddb.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(tc.input.TargetTableName)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(desc.Table.GlobalSecondaryIndexes) != 1 {
+				t.Fatalf("expected the index to be cloned, got %d indexes", len(desc.Table.GlobalSecondaryIndexes))
+			}
+			scan, err := ddb.Scan(&dynamodb.ScanInput{TableName: aws.String(tc.input.TargetTableName)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if int64(len(scan.Items)) != tc.copied {
+				t.Fatalf("expected %d items in the clone, got %d", tc.copied, len(scan.Items))
+			}
+		})
+	}
+}
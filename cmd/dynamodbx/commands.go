@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/ghodss/yaml"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+// connFlags are the flags shared by every command to configure the dynamodb client.
+type connFlags struct {
+	endpoint string
+	region   string
+	profile  string
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *connFlags) {
+	fs := flag.NewFlagSet("dynamodbx "+name, flag.ExitOnError)
+	c := &connFlags{}
+	fs.StringVar(&c.endpoint, "endpoint", "", "dynamodb endpoint, e.g. http://localhost:8000 for DynamoDB Local")
+	fs.StringVar(&c.region, "region", "", "AWS region, defaults to the region of the profile or environment")
+	fs.StringVar(&c.profile, "profile", "", "shared config profile to use")
+	return fs, c
+}
+
+func (c *connFlags) client() (*dynamodb.DynamoDB, error) {
+	cfg := aws.NewConfig()
+	if c.endpoint != "" {
+		cfg.Endpoint = aws.String(c.endpoint)
+	}
+	if c.region != "" {
+		cfg.Region = aws.String(c.region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *cfg,
+		Profile:           c.profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dynamodb.New(sess), nil
+}
+
+// parse parses the flags and checks the number of positional arguments is between min and max.
+func parse(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	fs.Parse(args)
+	if n := fs.NArg(); n < min || n > max {
+		return nil, fmt.Errorf("expected %d to %d arguments, got %d", min, max, n)
+	}
+	return fs.Args(), nil
+}
+
+// readSpec reads a CreateTableInput from a JSON or YAML file, or JSON from stdin if path is "-".
+func readSpec(path string) (*dynamodb.CreateTableInput, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	spec := &dynamodb.CreateTableInput{}
+	if err := dec.Decode(spec); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return spec, nil
+}
+
+// parseColumnTypes parses CSV column types written as Name=TYPE pairs separated by commas.
+func parseColumnTypes(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	types := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid column type %q, expected Name=TYPE", pair)
+		}
+		types[kv[0]] = strings.ToUpper(kv[1])
+	}
+	return types, nil
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Printf("%s\n", data)
+	return err
+}
+
+func createTable(ctx context.Context, args []string) error {
+	fs, c := newFlagSet("create-table")
+	name := fs.String("name", "", "table name, overrides the TableName of the spec")
+	args, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	spec, err := readSpec(args[0])
+	if err != nil {
+		return err
+	}
+	if *name != "" {
+		spec.TableName = aws.String(*name)
+	}
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	out, err := dynamodbx.CreateTableSyncWithContext(ctx, client, spec)
+	if err != nil {
+		return err
+	}
+	return printJSON(out.TableDescription)
+}
+
+func deleteTable(ctx context.Context, args []string) error {
+	fs, c := newFlagSet("delete-table")
+	args, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	_, err = dynamodbx.DeleteTableSyncWithContext(ctx, client, &dynamodb.DeleteTableInput{TableName: aws.String(args[0])})
+	return err
+}
+
+func describe(ctx context.Context, args []string) error {
+	fs, c := newFlagSet("describe")
+	args, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	out, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(args[0])})
+	if err != nil {
+		return err
+	}
+	return printJSON(out.Table)
+}
+
+func diff(ctx context.Context, args []string) error {
+	fs, c := newFlagSet("diff")
+	name := fs.String("name", "", "table name, overrides the TableName of the spec")
+	args, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	spec, err := readSpec(args[0])
+	if err != nil {
+		return err
+	}
+	if *name != "" {
+		spec.TableName = aws.String(*name)
+	}
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	d, err := dynamodbx.DiffWithContext(ctx, client, spec)
+	if err != nil {
+		return err
+	}
+	fmt.Print(d)
+	if !d.Equal() {
+		return exitError(1)
+	}
+	return nil
+}
+
+func wait(ctx context.Context, args []string) error {
+	fs, c := newFlagSet("wait")
+	deleted := fs.Bool("deleted", false, "wait for the table to be deleted instead of active")
+	timeout := fs.Duration("timeout", 0, "give up after this long, zero waits forever")
+	args, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	if *deleted {
+		return dynamodbx.WaitTableDeletedWithContext(ctx, client, args[0])
+	}
+	return dynamodbx.WaitTableActiveWithContext(ctx, client, args[0])
+}
+
+func importTable(ctx context.Context, args []string) error {
+	fs, c := newFlagSet("import")
+	input := &dynamodbx.ImportInput{}
+	fs.StringVar(&input.TableName, "table", "", "table to import into")
+	format := fs.String("format", string(dynamodbx.FormatDynamoDBJSON), "input format, one of DYNAMODB_JSON, JSON or CSV")
+	fs.BoolVar(&input.Gzip, "gzip", false, "decompress the input")
+	columns := fs.String("csv-types", "", "CSV column types as Name=TYPE pairs separated by commas, e.g. Age=N,Active=BOOL")
+	fs.IntVar(&input.Concurrency, "concurrency", 4, "number of batches written at once")
+	fs.IntVar(&input.WritesPerSecond, "wps", 0, "maximum items written per second, zero is unlimited")
+	rejected := fs.String("rejected", "", "file to write rejected rows to")
+	args, err := parse(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	if input.TableName == "" {
+		return errors.New("-table is required")
+	}
+	input.Format = dynamodbx.Format(strings.ToUpper(*format))
+	if input.CSVColumnTypes, err = parseColumnTypes(*columns); err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if len(args) == 1 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if *rejected != "" {
+		f, err := os.Create(*rejected)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input.Rejected = f
+	}
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	out, err := dynamodbx.ImportTableWithContext(ctx, client, r, input)
+	if out != nil {
+		fmt.Fprintf(os.Stderr, "imported %d items, rejected %d\n", out.Imported, out.Rejected)
+		for _, e := range out.Errors {
+			fmt.Fprintf(os.Stderr, "  %v\n", e)
+		}
+	}
+	return err
+}
+
+func exportTable(ctx context.Context, args []string) error {
+	fs, c := newFlagSet("export")
+	input := &dynamodbx.ExportInput{}
+	fs.StringVar(&input.TableName, "table", "", "table to export")
+	format := fs.String("format", string(dynamodbx.FormatDynamoDBJSON), "output format, one of DYNAMODB_JSON or JSON")
+	fs.BoolVar(&input.Gzip, "gzip", false, "compress the output")
+	fs.IntVar(&input.Segments, "segments", 1, "number of parallel scan segments")
+	fs.Float64Var(&input.ReadCapacityPerSecond, "rcu", 0, "maximum read capacity used per second, zero is unlimited")
+	manifest := fs.String("manifest", "", "file to write the export manifest to")
+	args, err := parse(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	if input.TableName == "" {
+		return errors.New("-table is required")
+	}
+	input.Format = dynamodbx.Format(strings.ToUpper(*format))
+	var w io.Writer = os.Stdout
+	if len(args) == 1 && args[0] != "-" {
+		f, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	m, err := dynamodbx.ExportTableWithContext(ctx, client, w, input)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d items\n", m.ItemCount)
+	if *manifest == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(*manifest, append(data, '\n'), 0644)
+}
+
+func truncate(ctx context.Context, args []string) error {
+	fs, c := newFlagSet("truncate")
+	args, err := parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	out, err := dynamodbx.TruncateTableWithContext(ctx, client, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "deleted %d items\n", out.Deleted)
+	return nil
+}
+
+func copyTable(ctx context.Context, args []string) error {
+	fs, c := newFlagSet("copy")
+	input := &dynamodbx.CloneTableInput{}
+	fs.IntVar(&input.Segments, "segments", 4, "number of parallel scan segments")
+	fs.IntVar(&input.WritesPerSecond, "wps", 0, "maximum items written per second, zero is unlimited")
+	args, err := parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	input.SourceTableName, input.TargetTableName = args[0], args[1]
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	out, err := dynamodbx.CloneTableWithContext(ctx, client, input)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "copied %d items in %v\n", out.Copied, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func serve(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("dynamodbx serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8000", "address to listen on")
+	if _, err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	srv := &http.Server{Addr: *addr, Handler: memdb.New()}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	log.Printf("serving in-memory dynamodb on %s", *addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
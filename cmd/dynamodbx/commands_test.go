@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestParseColumnTypes(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name   string
+		input  string
+		expect map[string]string
+		err    bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "pairs",
+			input:  "Age=n,Active=BOOL",
+			expect: map[string]string{"Age": "N", "Active": "BOOL"},
+		},
+		{
+			name:  "missing type",
+			input: "Age",
+			err:   true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseColumnTypes(tc.input)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error mismatch: got: %v, want error: %v", err, tc.err)
+			}
+			if diff := pretty.Compare(got, tc.expect); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestReadSpec(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "dynamodbx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, tc := range []struct {
+		name string
+		file string
+		spec string
+		err  bool
+	}{
+		{
+			name: "valid",
+			spec: `{
+				"TableName": "test",
+				"BillingMode": "PAY_PER_REQUEST",
+				"AttributeDefinitions": [{"AttributeName": "ID", "AttributeType": "S"}],
+				"KeySchema": [{"AttributeName": "ID", "KeyType": "HASH"}]
+			}`,
+		},
+		{
+			name: "unknown field",
+			spec: `{"TableName": "test", "Keys": []}`,
+			err:  true,
+		},
+		{
+			name: "invalid",
+			spec: `{"TableName": "test"}`,
+			err:  true,
+		},
+		{
+			name: "yaml",
+			file: "spec.yaml",
+			spec: `
+TableName: test
+BillingMode: PAY_PER_REQUEST
+AttributeDefinitions:
+  - AttributeName: ID
+    AttributeType: S
+KeySchema:
+  - AttributeName: ID
+    KeyType: HASH
+`,
+		},
+		{
+			name: "yml unknown field",
+			file: "spec.yml",
+			spec: "TableName: test\nKeys: []\n",
+			err:  true,
+		},
+		{
+			name: "invalid yaml",
+			file: "invalid.yaml",
+			spec: "TableName: [test\n",
+			err:  true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			file := tc.file
+			if file == "" {
+				file = tc.name + ".json"
+			}
+			path := filepath.Join(dir, file)
+			if err := ioutil.WriteFile(path, []byte(tc.spec), 0644); err != nil {
+				t.Fatal(err)
+			}
+			spec, err := readSpec(path)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error mismatch: got: %v, want error: %v", err, tc.err)
+			}
+			if err == nil && *spec.TableName != "test" {
+				t.Fatalf("expected table name test, got %s", *spec.TableName)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Command dynamodbx manages dynamodb tables and their data using the dynamodbx package.
+//
+// Usage:
+//
+//	dynamodbx <command> [flags] [arguments]
+//
+// Every command accepts -endpoint, -region and -profile, so it can be pointed at DynamoDB Local
+// with -endpoint http://localhost:8000. Run dynamodbx <command> -h for the flags of a command.
+//
+// Table specs are CreateTableInput documents in the JSON format accepted by
+// aws dynamodb create-table --cli-input-json, or the same document written as YAML in a .yaml or
+// .yml file.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+)
+
+// command is a subcommand of the tool. run is called with the arguments after the command name.
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]*command{
+	"create-table": {"create-table [flags] spec.json\n\tcreate a table from a spec and wait for it to be active", createTable},
+	"delete-table": {"delete-table [flags] table\n\tdelete a table and wait for it to be removed", deleteTable},
+	"describe":     {"describe [flags] table\n\tprint the description of a table as JSON", describe},
+	"diff":         {"diff [flags] spec.json\n\tshow how a table differs from a spec, exits with status 1 if it does", diff},
+	"wait":         {"wait [flags] table\n\twait for a table to be active, or deleted with -deleted", wait},
+	"import":       {"import [flags] [file]\n\timport items into a table from a file or stdin", importTable},
+	"export":       {"export [flags] [file]\n\texport the items of a table to a file or stdout", exportTable},
+	"truncate":     {"truncate [flags] table\n\tdelete every item in a table", truncate},
+	"copy":         {"copy [flags] source target\n\tcreate a copy of a table and its items", copyTable},
+	"serve":        {"serve [-addr :8000]\n\tserve an in-memory dynamodb for local development and tests", serve},
+}
+
+// exitError ends the program with a status other than 2 without printing anything.
+type exitError int
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "dynamodbx: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	err := cmd.run(ctx, os.Args[2:])
+	cancel()
+	if code, ok := err.(exitError); ok {
+		os.Exit(int(code))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dynamodbx %s: %v\n", os.Args[1], err)
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dynamodbx <command> [flags] [arguments]\n\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+}
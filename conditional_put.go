@@ -0,0 +1,249 @@
+package dynamodbx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/kynrai/dynamodbx/expr"
+)
+
+var (
+	ErrConditionalPutTableName = errors.New("dynamodbx/ConditionalBatchPut: table name cannot be empty")
+	ErrConditionalPutNilItem   = errors.New("dynamodbx/ConditionalBatchPut: items cannot be nil")
+)
+
+// ConditionalPut is an item to put, along with the condition it is put on.
+type ConditionalPut struct {
+	Item map[string]*dynamodb.AttributeValue
+	// Condition, if set, must hold for the item to be written. It replaces
+	// ConditionalBatchPutInput.Condition for this item.
+	Condition expr.ConditionBuilder
+	// version is the condition that the stored version of a versioned item is current, which must
+	// hold along with the condition of the item or of the input.
+	version expr.ConditionBuilder
+}
+
+// ConditionalBatchPutInput describes the items to put and the table they are written to.
+type ConditionalBatchPutInput struct {
+	TableName string
+	Items     []*ConditionalPut
+	// Condition, if set, is the condition of items which do not have their own, for example
+	// expr.Name("PK").AttributeNotExists() to only put items which do not exist yet.
+	Condition expr.ConditionBuilder
+	// Concurrency is the number of items written at once. Defaults to 4.
+	Concurrency int
+	// WritesPerSecond limits how many items are written per second. Zero is unlimited.
+	WritesPerSecond int
+	// ReturnConsumedCapacity is passed to each PutItem.
+	ReturnConsumedCapacity *string
+}
+
+// ConditionalBatchPutOutput reports the result of a ConditionalBatchPut. Skipped holds the items
+// whose condition failed, in the order they were given.
+type ConditionalBatchPutOutput struct {
+	Written          int64
+	Skipped          []*ConditionalPut
+	ConsumedCapacity []*dynamodb.ConsumedCapacity
+}
+
+// ConditionalPutRequest creates the ConditionalPuts of a slice of go structs, all put on the
+// condition, for use with ConditionalBatchPut. The condition may be unset so that the items use
+// ConditionalBatchPutInput.Condition.
+//
+// The input v must be a slice of golang structs which can be converted to dynamodb attributes
+// using the dynamodbattribute.MarshalMap function. v Cannot be nil.
+//
+// Structs with a version field, tagged `dynamodbx:"version"`, are put with their version
+// incremented, on the condition that the stored version is the version of the struct, along with
+// the condition or ConditionalBatchPutInput.Condition. The structs themselves are not modified. Timestamp and time to live fields are written as they are by
+// BatchPutRequest.
+func ConditionalPutRequest(v interface{}, cond expr.ConditionBuilder, opts ...ItemOption) ([]*ConditionalPut, error) {
+	return conditionalPutRequest(v, cond, clockTime(opts))
+}
+
+// conditionalPutRequest is ConditionalPutRequest stamping items with now.
+func conditionalPutRequest(v interface{}, cond expr.ConditionBuilder, now time.Time) ([]*ConditionalPut, error) {
+	if v == nil {
+		return nil, ErrInterfaceNil
+	}
+	if reflect.TypeOf(v).Kind() != reflect.Slice {
+		return nil, ErrInterfaceSlice
+	}
+	items := reflect.ValueOf(v)
+	var (
+		ver *version
+		ts  *timestamps
+		exp *ttl
+	)
+	if t := structType(v); t != nil {
+		var err error
+		if ver, err = itemVersion(t); err != nil {
+			return nil, err
+		}
+		if ts, err = itemTimestamps(t); err != nil {
+			return nil, err
+		}
+		if exp, err = itemTTL(t); err != nil {
+			return nil, err
+		}
+	}
+	puts := make([]*ConditionalPut, 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		data, err := dynamodbattribute.MarshalMap(items.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		put := &ConditionalPut{Item: data, Condition: cond}
+		if ver != nil {
+			put.version = ver.put(items.Index(i), data, expr.ConditionBuilder{})
+		}
+		if ts != nil {
+			ts.put(items.Index(i), data, now)
+		}
+		if exp != nil {
+			exp.put(items.Index(i), data, now)
+		}
+		puts = append(puts, put)
+	}
+	return puts, nil
+}
+
+// ConditionalBatchPut writes items with a condition each, which BatchWriteItem cannot do. Items are
+// written concurrently with PutItem, and items whose condition fails are reported in Skipped rather
+// than as an error. A transaction is not used, as each item is written on its own and a
+// transaction of a single put is no different from a conditional PutItem.
+//
+// Throttled puts are retried with an exponential backoff once the retries of the client are
+// exhausted, in the same way as the updates of BatchUpdateItem. Any other error stops the writes
+// and is returned along with the output so far, as some items may already have been written.
+func ConditionalBatchPut(client *dynamodb.DynamoDB, input *ConditionalBatchPutInput) (*ConditionalBatchPutOutput, error) {
+	return ConditionalBatchPutWithContext(context.Background(), client, input)
+}
+
+// ConditionalBatchPutWithContext is the same as ConditionalBatchPut.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func ConditionalBatchPutWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *ConditionalBatchPutInput, opts ...request.Option) (*ConditionalBatchPutOutput, error) {
+	if input.TableName == "" {
+		return nil, ErrConditionalPutTableName
+	}
+	// Every request is built first so that invalid conditions are reported before anything is written
+	reqs := make([]*dynamodb.PutItemInput, len(input.Items))
+	for i, put := range input.Items {
+		if put == nil || put.Item == nil {
+			return nil, ErrConditionalPutNilItem
+		}
+		req := &dynamodb.PutItemInput{
+			TableName:              aws.String(input.TableName),
+			Item:                   put.Item,
+			ReturnConsumedCapacity: input.ReturnConsumedCapacity,
+		}
+		cond := put.Condition
+		if !cond.IsSet() {
+			cond = input.Condition
+		}
+		switch {
+		case !put.version.IsSet():
+		case cond.IsSet():
+			cond = put.version.And(cond)
+		default:
+			cond = put.version
+		}
+		if cond.IsSet() {
+			e, err := expr.NewBuilder().WithCondition(cond).Build()
+			if err != nil {
+				return nil, err
+			}
+			req.ConditionExpression = e.Condition()
+			req.ExpressionAttributeNames = e.Names()
+			req.ExpressionAttributeValues = e.Values()
+		}
+		reqs[i] = req
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		out      = &ConditionalBatchPutOutput{}
+		skipped  []int
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	limiter := newRateLimiter(float64(input.WritesPerSecond))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := limiter.wait(ctx, 1); err != nil {
+					fail(err)
+					continue
+				}
+				var res *dynamodb.PutItemOutput
+				err := retryThrottled(ctx, func() (err error) {
+					res, err = client.PutItemWithContext(ctx, reqs[i], opts...)
+					return err
+				})
+				if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+					mu.Lock()
+					skipped = append(skipped, i)
+					mu.Unlock()
+					continue
+				}
+				if err != nil {
+					fail(err)
+					continue
+				}
+				mu.Lock()
+				out.Written++
+				if res.ConsumedCapacity != nil {
+					out.ConsumedCapacity = append(out.ConsumedCapacity, res.ConsumedCapacity)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+send:
+	for i := range reqs {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	sort.Ints(skipped)
+	for _, i := range skipped {
+		out.Skipped = append(out.Skipped, input.Items[i])
+	}
+	out.ConsumedCapacity = sumConsumedCapacity(out.ConsumedCapacity)
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return out, firstErr
+}
@@ -0,0 +1,191 @@
+package dynamodbx_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/dynamodbxtest"
+	"github.com/kynrai/dynamodbx/expr"
+	"github.com/kynrai/dynamodbx/faultdb"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func TestConditionalBatchPut(t *testing.T) {
+	t.Parallel()
+	type Item struct {
+		S string
+		N int
+	}
+	existing := []Item{{"a", 1}, {"c", 1}}
+	for _, tc := range []struct {
+		name    string
+		items   []Item
+		cond    expr.ConditionBuilder
+		input   *dynamodbx.ConditionalBatchPutInput
+		written int64
+		skipped []string
+		expect  []Item
+		err     error
+	}{
+		{
+			name:  "empty table name",
+			input: &dynamodbx.ConditionalBatchPutInput{},
+			err:   dynamodbx.ErrConditionalPutTableName,
+		},
+		{
+			name:  "nil item",
+			input: &dynamodbx.ConditionalBatchPutInput{Items: []*dynamodbx.ConditionalPut{nil}},
+			err:   dynamodbx.ErrConditionalPutNilItem,
+		},
+		{
+			name:  "invalid condition",
+			items: []Item{{"a", 2}},
+			input: &dynamodbx.ConditionalBatchPutInput{Condition: expr.Name("").AttributeNotExists()},
+			err:   expr.ErrEmptyName,
+		},
+		{
+			name:    "unconditional",
+			items:   []Item{{"a", 2}, {"b", 2}},
+			input:   &dynamodbx.ConditionalBatchPutInput{},
+			written: 2,
+			expect:  []Item{{"a", 2}, {"b", 2}, {"c", 1}},
+		},
+		{
+			name:    "insert only if absent",
+			items:   []Item{{"a", 2}, {"b", 2}, {"c", 2}, {"d", 2}},
+			input:   &dynamodbx.ConditionalBatchPutInput{Condition: expr.Name("S").AttributeNotExists(), Concurrency: 2},
+			written: 2,
+			skipped: []string{"a", "c"},
+			expect:  []Item{{"a", 1}, {"b", 2}, {"c", 1}, {"d", 2}},
+		},
+		{
+			name:    "item conditions replace the input condition",
+			items:   []Item{{"a", 2}, {"c", 2}},
+			cond:    expr.Name("N").LessThan(expr.Value(2)),
+			input:   &dynamodbx.ConditionalBatchPutInput{Condition: expr.Name("S").AttributeNotExists(), WritesPerSecond: 100},
+			written: 2,
+			expect:  []Item{{"a", 2}, {"c", 2}},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ddb := memdb.NewClient()
+			tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec, existing)
+			input := *tc.input
+			if tc.err != dynamodbx.ErrConditionalPutTableName {
+				input.TableName = tbl.Name
+			}
+			if input.Items == nil {
+				puts, err := dynamodbx.ConditionalPutRequest(tc.items, tc.cond)
+				if err != nil {
+					t.Fatal(err)
+				}
+				input.Items = puts
+			}
+			out, err := dynamodbx.ConditionalBatchPut(ddb, &input)
+			if err != tc.err {
+				t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			var skipped []string
+			for _, put := range out.Skipped {
+				skipped = append(skipped, aws.StringValue(put.Item["S"].S))
+			}
+			got := []interface{}{out.Written, skipped}
+			expect := []interface{}{tc.written, tc.skipped}
+			if !reflect.DeepEqual(got, expect) {
+				t.Fatal(pretty.Compare(got, expect))
+			}
+			tbl.AssertItems(tc.expect)
+		})
+	}
+}
+
+func TestConditionalBatchPutConsumedCapacity(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec)
+	puts, err := dynamodbx.ConditionalPutRequest([]struct{ S string }{{"a"}, {"b"}, {"c"}}, expr.Name("S").AttributeNotExists())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := dynamodbx.ConditionalBatchPut(ddb, &dynamodbx.ConditionalBatchPutInput{
+		TableName:              tbl.Name,
+		Items:                  puts,
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []*dynamodb.ConsumedCapacity{{TableName: aws.String(tbl.Name), CapacityUnits: aws.Float64(3)}}
+	if diff := pretty.Compare(out.ConsumedCapacity, expect); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestConditionalBatchPutVersioned(t *testing.T) {
+	t.Parallel()
+	type lockable struct {
+		S       string
+		Value   string
+		Version int  `dynamodbx:"version"`
+		Locked  bool `dynamodbav:",omitempty"`
+	}
+	ddb := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec, []lockable{{"a", "1", 1, true}, {"b", "1", 1, false}, {"d", "1", 2, false}})
+	// a is locked, d is stale and only b and c meet both the version and the input condition
+	puts, err := dynamodbx.ConditionalPutRequest([]lockable{{"a", "2", 1, false}, {"b", "2", 1, false}, {"c", "2", 0, false}, {"d", "2", 1, false}}, expr.ConditionBuilder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := dynamodbx.ConditionalBatchPut(ddb, &dynamodbx.ConditionalBatchPutInput{
+		TableName: tbl.Name,
+		Items:     puts,
+		Condition: expr.Name("Locked").AttributeNotExists(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Written != 2 || len(out.Skipped) != 2 || out.Skipped[0] != puts[0] || out.Skipped[1] != puts[3] {
+		t.Fatalf("expected b and c written and a and d skipped, got %d written and %d skipped", out.Written, len(out.Skipped))
+	}
+	tbl.AssertItems([]lockable{{"a", "1", 1, true}, {"b", "2", 2, false}, {"c", "2", 1, false}, {"d", "1", 2, false}})
+}
+
+func TestConditionalBatchPutThrottled(t *testing.T) {
+	t.Parallel()
+	mem := memdb.NewClient(&aws.Config{MaxRetries: aws.Int(0)})
+	tbl := dynamodbxtest.NewTestTable(t, mem, batchWriteSpec)
+	faults := faultdb.New(faultdb.Config{Seed: 1, ThrottleRate: 0.3, Operations: []string{"PutItem"}})
+	ddb := faults.Wrap(mem)
+	type Item struct {
+		S string
+	}
+	var items []Item
+	for i := 0; i < 50; i++ {
+		items = append(items, Item{strconv.Itoa(i)})
+	}
+	puts, err := dynamodbx.ConditionalPutRequest(items, expr.Name("S").AttributeNotExists())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := dynamodbx.ConditionalBatchPut(ddb, &dynamodbx.ConditionalBatchPutInput{TableName: tbl.Name, Items: puts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Written != 50 {
+		t.Fatalf("expected 50 items written, got %d", out.Written)
+	}
+	if faults.Stats().Throttled == 0 {
+		t.Fatal("expected some puts to be throttled")
+	}
+	tbl.AssertItems(items)
+}
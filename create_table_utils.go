@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
@@ -51,3 +52,49 @@ func CreateTableSyncWithContext(ctx context.Context, client *dynamodb.DynamoDB,
 
 	return out, nil
 }
+
+// CreateTableInputFromDescription builds a CreateTableInput which recreates the table described by
+// a DescribeTable response. Key schema, attribute definitions, global and local secondary indexes,
+// billing mode, provisioned throughput and stream settings are copied. The TableName is copied and
+// can be changed before creating the new table.
+func CreateTableInputFromDescription(table *dynamodb.TableDescription) *dynamodb.CreateTableInput {
+	input := &dynamodb.CreateTableInput{
+		TableName:            table.TableName,
+		AttributeDefinitions: table.AttributeDefinitions,
+		KeySchema:            table.KeySchema,
+		BillingMode:          aws.String(dynamodb.BillingModeProvisioned),
+	}
+	if table.BillingModeSummary != nil && table.BillingModeSummary.BillingMode != nil {
+		input.BillingMode = table.BillingModeSummary.BillingMode
+	}
+	provisioned := *input.BillingMode == dynamodb.BillingModeProvisioned
+	throughput := func(p *dynamodb.ProvisionedThroughputDescription) *dynamodb.ProvisionedThroughput {
+		if !provisioned || p == nil {
+			return nil
+		}
+		return &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  p.ReadCapacityUnits,
+			WriteCapacityUnits: p.WriteCapacityUnits,
+		}
+	}
+	input.ProvisionedThroughput = throughput(table.ProvisionedThroughput)
+	for _, v := range table.GlobalSecondaryIndexes {
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndex{
+			IndexName:             v.IndexName,
+			KeySchema:             v.KeySchema,
+			Projection:            v.Projection,
+			ProvisionedThroughput: throughput(v.ProvisionedThroughput),
+		})
+	}
+	for _, v := range table.LocalSecondaryIndexes {
+		input.LocalSecondaryIndexes = append(input.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndex{
+			IndexName:  v.IndexName,
+			KeySchema:  v.KeySchema,
+			Projection: v.Projection,
+		})
+	}
+	if table.StreamSpecification != nil && aws.BoolValue(table.StreamSpecification.StreamEnabled) {
+		input.StreamSpecification = table.StreamSpecification
+	}
+	return input
+}
@@ -0,0 +1,192 @@
+package dynamodbx
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrCursorInvalid = errors.New("dynamodbx/Cursor: cursor is malformed or has been tampered with")
+	ErrCursorKey     = errors.New("dynamodbx/Cursor: cursor does not match the expected key schema")
+)
+
+// cursorVersion is the first byte of every encoded cursor so the format can change later
+const cursorVersion = 1
+
+// CursorCodec converts an ExclusiveStartKey to and from an opaque URL safe string so pagination can
+// be exposed through public APIs without leaking raw keys. The zero value produces unsigned and
+// unencrypted cursors.
+type CursorCodec struct {
+	// SigningKey, if set, is used to HMAC-SHA256 sign cursors. Cursors which have been modified are
+	// rejected by Decode.
+	SigningKey []byte
+	// EncryptionKey, if set, is used to encrypt cursors with AES-GCM. It must be 16, 24 or 32 bytes.
+	EncryptionKey []byte
+	// KeyAttributes, if set, are the attributes and types every decoded key must have, exactly. For
+	// queries on an index this includes both the table and index key attributes.
+	KeyAttributes []*dynamodb.AttributeDefinition
+}
+
+// Encode returns the cursor for key. An empty key, as returned by the last page of results, encodes
+// to an empty string.
+func (c *CursorCodec) Encode(key map[string]*dynamodb.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+	if err := c.validate(key); err != nil {
+		return "", err
+	}
+	compact := make(map[string][2]string, len(key))
+	for k, v := range key {
+		switch {
+		case v.S != nil:
+			compact[k] = [2]string{dynamodb.ScalarAttributeTypeS, *v.S}
+		case v.N != nil:
+			compact[k] = [2]string{dynamodb.ScalarAttributeTypeN, *v.N}
+		case v.B != nil:
+			compact[k] = [2]string{dynamodb.ScalarAttributeTypeB, base64.StdEncoding.EncodeToString(v.B)}
+		default:
+			return "", ErrCursorKey
+		}
+	}
+	payload, err := json.Marshal(compact)
+	if err != nil {
+		return "", err
+	}
+	if len(c.EncryptionKey) > 0 {
+		gcm, err := c.gcm()
+		if err != nil {
+			return "", err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+		payload = gcm.Seal(nonce, nonce, payload, []byte{cursorVersion})
+	}
+	payload = append([]byte{cursorVersion}, payload...)
+	if len(c.SigningKey) > 0 {
+		payload = append(payload, c.sign(payload)...)
+	}
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// Decode returns the key held in cursor, verifying its signature, decrypting it and checking it
+// against KeyAttributes as configured. An empty cursor decodes to a nil key which starts from the
+// first page.
+func (c *CursorCodec) Decode(cursor string) (map[string]*dynamodb.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrCursorInvalid
+	}
+	if len(c.SigningKey) > 0 {
+		if len(payload) < sha256.Size {
+			return nil, ErrCursorInvalid
+		}
+		body, mac := payload[:len(payload)-sha256.Size], payload[len(payload)-sha256.Size:]
+		if !hmac.Equal(mac, c.sign(body)) {
+			return nil, ErrCursorInvalid
+		}
+		payload = body
+	}
+	if len(payload) == 0 || payload[0] != cursorVersion {
+		return nil, ErrCursorInvalid
+	}
+	payload = payload[1:]
+	if len(c.EncryptionKey) > 0 {
+		gcm, err := c.gcm()
+		if err != nil {
+			return nil, err
+		}
+		if len(payload) < gcm.NonceSize() {
+			return nil, ErrCursorInvalid
+		}
+		payload, err = gcm.Open(nil, payload[:gcm.NonceSize()], payload[gcm.NonceSize():], []byte{cursorVersion})
+		if err != nil {
+			return nil, ErrCursorInvalid
+		}
+	}
+
+	var compact map[string][2]string
+	if err := json.Unmarshal(payload, &compact); err != nil || len(compact) == 0 {
+		return nil, ErrCursorInvalid
+	}
+	key := make(map[string]*dynamodb.AttributeValue, len(compact))
+	for k, v := range compact {
+		switch v[0] {
+		case dynamodb.ScalarAttributeTypeS:
+			key[k] = &dynamodb.AttributeValue{S: aws.String(v[1])}
+		case dynamodb.ScalarAttributeTypeN:
+			key[k] = &dynamodb.AttributeValue{N: aws.String(v[1])}
+		case dynamodb.ScalarAttributeTypeB:
+			b, err := base64.StdEncoding.DecodeString(v[1])
+			if err != nil {
+				return nil, ErrCursorInvalid
+			}
+			key[k] = &dynamodb.AttributeValue{B: b}
+		default:
+			return nil, ErrCursorInvalid
+		}
+	}
+	if err := c.validate(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// validate checks key has exactly the KeyAttributes with the expected types.
+func (c *CursorCodec) validate(key map[string]*dynamodb.AttributeValue) error {
+	if len(c.KeyAttributes) == 0 {
+		return nil
+	}
+	if len(key) != len(c.KeyAttributes) {
+		return ErrCursorKey
+	}
+	for _, a := range c.KeyAttributes {
+		v, ok := key[aws.StringValue(a.AttributeName)]
+		if !ok {
+			return ErrCursorKey
+		}
+		switch aws.StringValue(a.AttributeType) {
+		case dynamodb.ScalarAttributeTypeS:
+			ok = v.S != nil
+		case dynamodb.ScalarAttributeTypeN:
+			ok = v.N != nil
+		case dynamodb.ScalarAttributeTypeB:
+			ok = v.B != nil
+		default:
+			ok = false
+		}
+		if !ok {
+			return ErrCursorKey
+		}
+	}
+	return nil
+}
+
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.SigningKey)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (c *CursorCodec) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(c.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,122 @@
+package dynamodbx_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+)
+
+func TestCursorCodec(t *testing.T) {
+	t.Parallel()
+	key := map[string]*dynamodb.AttributeValue{
+		"P": {S: aws.String("user#1")},
+		"S": {N: aws.String("42")},
+		"B": {B: []byte{0, 1, 2}},
+	}
+	schema := []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String("P"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		{AttributeName: aws.String("S"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeN)},
+		{AttributeName: aws.String("B"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeB)},
+	}
+	signing := []byte("signing key")
+	encryption := []byte("0123456789abcdef0123456789abcdef")
+
+	for _, tc := range []struct {
+		name   string
+		codec  *dynamodbx.CursorCodec
+		key    map[string]*dynamodb.AttributeValue
+		tamper func(string) string
+		decode *dynamodbx.CursorCodec
+		err    error
+	}{
+		{
+			name:  "plain",
+			codec: &dynamodbx.CursorCodec{},
+			key:   key,
+		},
+		{
+			name:  "empty key",
+			codec: &dynamodbx.CursorCodec{SigningKey: signing},
+		},
+		{
+			name:  "signed and encrypted",
+			codec: &dynamodbx.CursorCodec{SigningKey: signing, EncryptionKey: encryption, KeyAttributes: schema},
+			key:   key,
+		},
+		{
+			name:  "encrypted",
+			codec: &dynamodbx.CursorCodec{EncryptionKey: encryption},
+			key:   key,
+		},
+		{
+			name:  "tampered signed cursor",
+			codec: &dynamodbx.CursorCodec{SigningKey: signing},
+			key:   key,
+			tamper: func(c string) string {
+				b := []byte(c)
+				b[2] ^= 1
+				return string(b)
+			},
+			err: dynamodbx.ErrCursorInvalid,
+		},
+		{
+			name:   "wrong signing key",
+			codec:  &dynamodbx.CursorCodec{SigningKey: signing},
+			decode: &dynamodbx.CursorCodec{SigningKey: []byte("other key")},
+			key:    key,
+			err:    dynamodbx.ErrCursorInvalid,
+		},
+		{
+			name:   "wrong encryption key",
+			codec:  &dynamodbx.CursorCodec{EncryptionKey: encryption},
+			decode: &dynamodbx.CursorCodec{EncryptionKey: []byte("fedcba9876543210")},
+			key:    key,
+			err:    dynamodbx.ErrCursorInvalid,
+		},
+		{
+			name:   "key schema mismatch",
+			codec:  &dynamodbx.CursorCodec{},
+			decode: &dynamodbx.CursorCodec{KeyAttributes: schema[:2]},
+			key:    key,
+			err:    dynamodbx.ErrCursorKey,
+		},
+		{
+			name:   "garbage",
+			codec:  &dynamodbx.CursorCodec{},
+			key:    key,
+			tamper: func(string) string { return "!!not a cursor" },
+			err:    dynamodbx.ErrCursorInvalid,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			cursor, err := tc.codec.Encode(tc.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.ContainsAny(cursor, "+/=") {
+				t.Fatalf("cursor is not URL safe: %s", cursor)
+			}
+			if tc.tamper != nil {
+				cursor = tc.tamper(cursor)
+			}
+			decode := tc.decode
+			if decode == nil {
+				decode = tc.codec
+			}
+			got, err := decode.Decode(cursor)
+			if err != tc.err {
+				t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+			}
+			if tc.err == nil && !reflect.DeepEqual(got, tc.key) {
+				t.Fatal(pretty.Compare(got, tc.key))
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package dynamodbx
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// DeleteTableSync will delete a dynamodb table and block until the table no longer exists.
+// This is useful in tests which recreate a table with the same name
+func DeleteTableSync(client *dynamodb.DynamoDB, input *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+	return DeleteTableSyncWithContext(context.Background(), client, input)
+}
+
+// DeleteTableSyncWithContext will delete a dynamodb table and block until the table no longer exists.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func DeleteTableSyncWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.DeleteTableInput, opts ...request.Option) (*dynamodb.DeleteTableOutput, error) {
+	out, err := client.DeleteTableWithContext(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := WaitTableDeletedWithContext(ctx, client, aws.StringValue(input.TableName), opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WaitTableDeleted blocks until a table no longer exists.
+func WaitTableDeleted(client *dynamodb.DynamoDB, table string) error {
+	return WaitTableDeletedWithContext(context.Background(), client, table)
+}
+
+// WaitTableDeletedWithContext is the same as WaitTableDeleted.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func WaitTableDeletedWithContext(ctx context.Context, client *dynamodb.DynamoDB, table string, opts ...request.Option) error {
+	for {
+		_, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)}, opts...)
+		if isErrCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := aws.SleepWithContext(ctx, time.Millisecond*100); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package dynamodbx
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// MarshalDynamoDBJSON encodes an item in the typed JSON format used on the wire by dynamodb, where
+// every value is wrapped in an object naming its type, e.g. {"Foo":{"S":"Hello"},"Bar":{"N":"1"}}.
+// Attributes are written in name order and binary values are base64 encoded.
+func MarshalDynamoDBJSON(item map[string]*dynamodb.AttributeValue) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(dynamoDBJSONItem(item)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// dynamoDBJSONItem converts an item to maps holding only the types set on each value, which
+// encoding/json writes as DynamoDB JSON. dynamodb.AttributeValue itself would be written with a null
+// for every type it does not hold.
+func dynamoDBJSONItem(item map[string]*dynamodb.AttributeValue) map[string]interface{} {
+	m := make(map[string]interface{}, len(item))
+	for k, v := range item {
+		m[k] = dynamoDBJSONValue(v)
+	}
+	return m
+}
+
+func dynamoDBJSONValue(v *dynamodb.AttributeValue) map[string]interface{} {
+	m := make(map[string]interface{}, 1)
+	if v == nil {
+		return m
+	}
+	if v.B != nil {
+		m["B"] = v.B
+	}
+	if v.BOOL != nil {
+		m["BOOL"] = *v.BOOL
+	}
+	if v.BS != nil {
+		m["BS"] = v.BS
+	}
+	if v.L != nil {
+		l := make([]interface{}, len(v.L))
+		for i, e := range v.L {
+			l[i] = dynamoDBJSONValue(e)
+		}
+		m["L"] = l
+	}
+	if v.M != nil {
+		m["M"] = dynamoDBJSONItem(v.M)
+	}
+	if v.N != nil {
+		m["N"] = *v.N
+	}
+	if v.NS != nil {
+		m["NS"] = v.NS
+	}
+	if v.NULL != nil {
+		m["NULL"] = *v.NULL
+	}
+	if v.S != nil {
+		m["S"] = *v.S
+	}
+	if v.SS != nil {
+		m["SS"] = v.SS
+	}
+	return m
+}
+
+// UnmarshalDynamoDBJSON decodes an item encoded by MarshalDynamoDBJSON.
+func UnmarshalDynamoDBJSON(data []byte) (map[string]*dynamodb.AttributeValue, error) {
+	// the fields of dynamodb.AttributeValue are named after the types of DynamoDB JSON
+	var item map[string]*dynamodb.AttributeValue
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// MarshalPlainJSON encodes an item as ordinary JSON by unmarshalling it with dynamodbattribute.
+// Numbers are written with their full precision, binary values are base64 encoded and sets become
+// arrays, so the type information of the item is lost.
+func MarshalPlainJSON(item map[string]*dynamodb.AttributeValue) ([]byte, error) {
+	var v map[string]interface{}
+	dec := dynamodbattribute.NewDecoder(func(d *dynamodbattribute.Decoder) {
+		d.UseNumber = true
+	})
+	if err := dec.Decode(&dynamodb.AttributeValue{M: item}, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(plainJSONNumbers(v))
+}
+
+// plainJSONNumbers replaces the dynamodbattribute.Number values produced with UseNumber with
+// json.Number so they are written as JSON numbers rather than strings.
+func plainJSONNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case dynamodbattribute.Number:
+		return json.Number(v)
+	case []dynamodbattribute.Number:
+		out := make([]json.Number, len(v))
+		for i, n := range v {
+			out[i] = json.Number(n)
+		}
+		return out
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = plainJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = plainJSONNumbers(e)
+		}
+	}
+	return v
+}
+
+// UnmarshalPlainJSON converts a plain JSON object to an item with dynamodbattribute. Numbers keep
+// their full precision and are stored as N, everything else is marshalled as dynamodbattribute.
+// MarshalMap would marshal the equivalent go value.
+func UnmarshalPlainJSON(data []byte) (map[string]*dynamodb.AttributeValue, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v map[string]interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return dynamodbattribute.MarshalMap(dynamodbNumbers(v))
+}
+
+// dynamodbNumbers replaces json.Number values with dynamodbattribute.Number so they are marshalled
+// as numbers rather than strings.
+func dynamodbNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		return dynamodbattribute.Number(v)
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = dynamodbNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = dynamodbNumbers(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,68 @@
+package dynamodbx_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+)
+
+func TestDynamoDBJSON(t *testing.T) {
+	t.Parallel()
+	item := map[string]*dynamodb.AttributeValue{
+		"S":    {S: aws.String("Hello <&>")},
+		"N":    {N: aws.String("12345678901234567890")},
+		"B":    {B: []byte("hi")},
+		"BOOL": {BOOL: aws.Bool(true)},
+		"NULL": {NULL: aws.Bool(true)},
+		"SS":   {SS: aws.StringSlice([]string{"a", "b"})},
+		"NS":   {NS: aws.StringSlice([]string{"1", "2.5"})},
+		"BS":   {BS: [][]byte{[]byte("hi"), []byte("yo")}},
+		"L":    {L: []*dynamodb.AttributeValue{{N: aws.String("1")}, {S: aws.String("x")}}},
+		"M":    {M: map[string]*dynamodb.AttributeValue{"Nested": {N: aws.String("1.5")}}},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		marshal func(map[string]*dynamodb.AttributeValue) ([]byte, error)
+		expect  string
+	}{
+		{
+			name:    "dynamodb json",
+			marshal: dynamodbx.MarshalDynamoDBJSON,
+			expect:  `{"B":{"B":"aGk="},"BOOL":{"BOOL":true},"BS":{"BS":["aGk=","eW8="]},"L":{"L":[{"N":"1"},{"S":"x"}]},"M":{"M":{"Nested":{"N":"1.5"}}},"N":{"N":"12345678901234567890"},"NS":{"NS":["1","2.5"]},"NULL":{"NULL":true},"S":{"S":"Hello <&>"},"SS":{"SS":["a","b"]}}`,
+		},
+		{
+			name:    "plain json",
+			marshal: dynamodbx.MarshalPlainJSON,
+			expect:  `{"B":"aGk=","BOOL":true,"BS":["aGk=","eW8="],"L":[1,"x"],"M":{"Nested":1.5},"N":12345678901234567890,"NS":[1,2.5],"NULL":null,"S":"Hello \u003c\u0026\u003e","SS":["a","b"]}`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			data, err := tc.marshal(item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.expect {
+				t.Fatal(pretty.Compare(string(data), tc.expect))
+			}
+		})
+	}
+
+	data, err := dynamodbx.MarshalDynamoDBJSON(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := dynamodbx.UnmarshalDynamoDBJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, item) {
+		t.Fatal(pretty.Compare(got, item))
+	}
+}
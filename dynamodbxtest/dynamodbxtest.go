@@ -0,0 +1,238 @@
+// Package dynamodbxtest provides helpers for tests which use dynamodb tables: ephemeral tables
+// created from a spec and seeded with fixtures, and assertions on the contents of tables.
+//
+//	func TestUsers(t *testing.T) {
+//		ddb := memdb.NewClient()
+//		users := dynamodbxtest.NewTestTable(t, ddb, usersSpec, []*User{{ID: "1", Name: "Ann"}})
+//		// ... code under test writing to users.Name
+//		users.AssertItems(&User{ID: "1", Name: "Ann"}, &User{ID: "2", Name: "Bob"})
+//	}
+//
+// Helpers fail the test with t.Fatal when dynamodb returns an error and report differences in
+// table contents with t.Error.
+package dynamodbxtest
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+)
+
+// maxTableName is the longest table name dynamodb accepts.
+const maxTableName = 255
+
+// tables counts the tables created by this process, so names are unique within it.
+var tables int64
+
+// Table is a table created for a test.
+type Table struct {
+	// Name is the unique name the table was created with.
+	Name   string
+	t      testing.TB
+	client *dynamodb.DynamoDB
+}
+
+// NewTestTable creates a table from spec with a unique name, waits for it to be active and puts
+// the fixtures into it. The table is deleted with DeleteTableSync when the test and its subtests
+// complete. spec is not modified, and its TableName, if any, is used as the prefix of the name.
+//
+// Each fixture is an item, or a slice of items. Items are structs marshalled with
+// dynamodbattribute.MarshalMap, or map[string]*dynamodb.AttributeValue.
+func NewTestTable(t testing.TB, client *dynamodb.DynamoDB, spec *dynamodb.CreateTableInput, fixtures ...interface{}) *Table {
+	t.Helper()
+	input := *spec
+	input.TableName = aws.String(tableName(aws.StringValue(spec.TableName), t.Name()))
+	if _, err := dynamodbx.CreateTableSync(client, &input); err != nil {
+		t.Fatalf("dynamodbxtest: creating table %s: %v", *input.TableName, err)
+	}
+	t.Cleanup(func() {
+		if _, err := dynamodbx.DeleteTableSync(client, &dynamodb.DeleteTableInput{TableName: input.TableName}); err != nil {
+			t.Errorf("dynamodbxtest: deleting table %s: %v", *input.TableName, err)
+		}
+	})
+	tbl := &Table{Name: *input.TableName, t: t, client: client}
+	tbl.Put(fixtures...)
+	return tbl
+}
+
+// tableName returns a unique table name made of the prefix, the test name and a sequence number.
+// Characters dynamodb does not allow in names are replaced by underscores.
+func tableName(prefix, test string) string {
+	if prefix == "" {
+		prefix = "test"
+	}
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatInt(atomic.AddInt64(&tables, 1), 10)
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, prefix+"-"+test)
+	if max := maxTableName - len(suffix) - 1; len(name) > max {
+		name = name[:max]
+	}
+	return name + "-" + suffix
+}
+
+// Put writes items to the table. Each argument is an item or a slice of items, as for the fixtures
+// of NewTestTable.
+func (tbl *Table) Put(items ...interface{}) {
+	tbl.t.Helper()
+	Put(tbl.t, tbl.client, tbl.Name, items...)
+}
+
+// Items returns every item in the table.
+func (tbl *Table) Items() []map[string]*dynamodb.AttributeValue {
+	tbl.t.Helper()
+	return Items(tbl.t, tbl.client, tbl.Name)
+}
+
+// AssertItems checks the table contains exactly the expected items, in any order.
+func (tbl *Table) AssertItems(expect ...interface{}) {
+	tbl.t.Helper()
+	AssertItems(tbl.t, tbl.client, tbl.Name, expect...)
+}
+
+// AssertItem checks the item with the key equals expect, or does not exist if expect is nil.
+func (tbl *Table) AssertItem(key, expect interface{}) {
+	tbl.t.Helper()
+	AssertItem(tbl.t, tbl.client, tbl.Name, key, expect)
+}
+
+// Put writes items to a table. Each argument is an item or a slice of items, as for the fixtures
+// of NewTestTable.
+func Put(t testing.TB, client *dynamodb.DynamoDB, table string, items ...interface{}) {
+	t.Helper()
+	avs := marshalItems(t, items)
+	if len(avs) == 0 {
+		return
+	}
+	reqs := make([]*dynamodb.WriteRequest, len(avs))
+	for i, item := range avs {
+		reqs[i] = &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}}
+	}
+	out, err := dynamodbx.BatchWriteItem(client, &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{table: reqs},
+	})
+	if err != nil {
+		t.Fatalf("dynamodbxtest: putting items into %s: %v", table, err)
+	}
+	if n := len(out.UnprocessedItems[table]); n > 0 {
+		t.Fatalf("dynamodbxtest: putting items into %s: %d items unprocessed", table, n)
+	}
+}
+
+// Items returns every item in a table, read with a consistent scan.
+func Items(t testing.TB, client *dynamodb.DynamoDB, table string) []map[string]*dynamodb.AttributeValue {
+	t.Helper()
+	var items []map[string]*dynamodb.AttributeValue
+	err := client.ScanPages(&dynamodb.ScanInput{
+		TableName:      aws.String(table),
+		ConsistentRead: aws.Bool(true),
+	}, func(out *dynamodb.ScanOutput, last bool) bool {
+		items = append(items, out.Items...)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("dynamodbxtest: scanning %s: %v", table, err)
+	}
+	return items
+}
+
+// AssertItems checks a table contains exactly the expected items, in any order. Each argument is
+// an item or a slice of items, as for the fixtures of NewTestTable. Differences are reported as
+// DynamoDB JSON.
+func AssertItems(t testing.TB, client *dynamodb.DynamoDB, table string, expect ...interface{}) {
+	t.Helper()
+	got := encodeItems(t, Items(t, client, table)...)
+	want := encodeItems(t, marshalItems(t, expect)...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if diff := pretty.Compare(got, want); diff != "" {
+		t.Errorf("dynamodbxtest: items in %s differ (-got +want):\n%s", table, diff)
+	}
+}
+
+// AssertItem checks the item with the key equals expect, or does not exist if expect is nil. The
+// key and expect are structs or map[string]*dynamodb.AttributeValue, and the key must only have
+// the key attributes of the table.
+func AssertItem(t testing.TB, client *dynamodb.DynamoDB, table string, key, expect interface{}) {
+	t.Helper()
+	out, err := client.GetItem(&dynamodb.GetItemInput{
+		TableName:      aws.String(table),
+		Key:            marshalItem(t, key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatalf("dynamodbxtest: getting item from %s: %v", table, err)
+	}
+	if expect == nil {
+		if out.Item != nil {
+			t.Errorf("dynamodbxtest: expected no item in %s, got %s", table, encodeItems(t, out.Item)[0])
+		}
+		return
+	}
+	var got []string
+	if out.Item != nil {
+		got = encodeItems(t, out.Item)
+	}
+	want := encodeItems(t, marshalItem(t, expect))
+	if diff := pretty.Compare(got, want); diff != "" {
+		t.Errorf("dynamodbxtest: item in %s differs (-got +want):\n%s", table, diff)
+	}
+}
+
+// marshalItems marshals items and slices of items into attribute value maps.
+func marshalItems(t testing.TB, items []interface{}) []map[string]*dynamodb.AttributeValue {
+	t.Helper()
+	var avs []map[string]*dynamodb.AttributeValue
+	for _, item := range items {
+		v := reflect.ValueOf(item)
+		if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+			for i := 0; i < v.Len(); i++ {
+				avs = append(avs, marshalItem(t, v.Index(i).Interface()))
+			}
+			continue
+		}
+		avs = append(avs, marshalItem(t, item))
+	}
+	return avs
+}
+
+func marshalItem(t testing.TB, item interface{}) map[string]*dynamodb.AttributeValue {
+	t.Helper()
+	if av, ok := item.(map[string]*dynamodb.AttributeValue); ok {
+		return av
+	}
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		t.Fatalf("dynamodbxtest: marshalling %T: %v", item, err)
+	}
+	return av
+}
+
+// encodeItems encodes items as DynamoDB JSON, which has sorted keys, so they can be compared and
+// sorted as strings.
+func encodeItems(t testing.TB, items ...map[string]*dynamodb.AttributeValue) []string {
+	t.Helper()
+	encoded := make([]string, len(items))
+	for i, item := range items {
+		data, err := dynamodbx.MarshalDynamoDBJSON(item)
+		if err != nil {
+			t.Fatalf("dynamodbxtest: %v", err)
+		}
+		encoded[i] = string(data)
+	}
+	return encoded
+}
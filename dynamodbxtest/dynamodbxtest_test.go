@@ -0,0 +1,114 @@
+package dynamodbxtest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kynrai/dynamodbx/dynamodbxtest"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+type user struct {
+	ID   string
+	Name string
+}
+
+var spec = &dynamodb.CreateTableInput{
+	TableName:            aws.String("users"),
+	BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+	AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("ID"), AttributeType: aws.String("S")}},
+	KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: aws.String("HASH")}},
+}
+
+func id(s string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{"ID": {S: aws.String(s)}}
+}
+
+// recorder records the errors reported by the helpers instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestNewTestTable(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	var names []string
+	t.Run("create", func(t *testing.T) {
+		a := dynamodbxtest.NewTestTable(t, ddb, spec, []*user{{"1", "Ann"}, {"2", "Bob"}})
+		b := dynamodbxtest.NewTestTable(t, ddb, spec, &user{"3", "Cat"}, map[string]*dynamodb.AttributeValue{
+			"ID": {S: aws.String("4")},
+		})
+		names = []string{a.Name, b.Name}
+		if a.Name == b.Name || !strings.HasPrefix(a.Name, "users-TestNewTestTable_create-") {
+			t.Fatalf("expected unique names prefixed by the spec and test, got %v", names)
+		}
+		if aws.StringValue(spec.TableName) != "users" {
+			t.Fatal("expected the spec not to be modified")
+		}
+		a.AssertItems(&user{"2", "Bob"}, &user{"1", "Ann"})
+		a.AssertItem(id("1"), &user{"1", "Ann"})
+		a.AssertItem(id("3"), nil)
+		b.AssertItems([]*user{{"3", "Cat"}}, id("4"))
+		a.Put(&user{"5", "Dan"})
+		if n := len(a.Items()); n != 3 {
+			t.Fatalf("expected 3 items, got %d", n)
+		}
+	})
+	out, err := ddb.ListTables(&dynamodb.ListTablesInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.TableNames) != 0 {
+		t.Fatalf("expected tables %v to be deleted, got %v", names, aws.StringValueSlice(out.TableNames))
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, spec, &user{"1", "Ann"})
+	for _, tc := range []struct {
+		name   string
+		assert func(t testing.TB)
+		errors int
+	}{
+		{
+			name:   "items match",
+			assert: func(t testing.TB) { dynamodbxtest.AssertItems(t, ddb, tbl.Name, &user{"1", "Ann"}) },
+		},
+		{
+			name:   "items differ",
+			assert: func(t testing.TB) { dynamodbxtest.AssertItems(t, ddb, tbl.Name, &user{"1", "Bob"}) },
+			errors: 1,
+		},
+		{
+			name:   "item missing",
+			assert: func(t testing.TB) { dynamodbxtest.AssertItems(t, ddb, tbl.Name) },
+			errors: 1,
+		},
+		{
+			name:   "item differs",
+			assert: func(t testing.TB) { dynamodbxtest.AssertItem(t, ddb, tbl.Name, id("1"), &user{ID: "1"}) },
+			errors: 1,
+		},
+		{
+			name:   "item exists",
+			assert: func(t testing.TB) { dynamodbxtest.AssertItem(t, ddb, tbl.Name, id("1"), nil) },
+			errors: 1,
+		},
+	} {
+		r := &recorder{TB: t}
+		tc.assert(r)
+		if len(r.errors) != tc.errors {
+			t.Fatalf("%s: expected %d errors, got %v", tc.name, tc.errors, r.errors)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package dynamodbx
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrExportTableName = errors.New("dynamodbx/ExportTable: table name cannot be empty")
+	ErrExportFormat    = errors.New("dynamodbx/ExportTable: unknown format")
+)
+
+// Format is the encoding of each line of an exported or imported file.
+type Format string
+
+// Formats supported by ExportTable and ImportTable. ImportTable additionally supports FormatCSV.
+const (
+	// FormatDynamoDBJSON writes one item per line in the typed format used on the wire by dynamodb,
+	// see MarshalDynamoDBJSON.
+	FormatDynamoDBJSON Format = "DYNAMODB_JSON"
+	// FormatJSON writes one item per line as plain JSON, see MarshalPlainJSON.
+	FormatJSON Format = "JSON"
+)
+
+// ExportInput describes the table to export and how it is written.
+type ExportInput struct {
+	TableName string
+	// Format defaults to FormatDynamoDBJSON.
+	Format Format
+	// Gzip compresses the output. The gzip stream is closed even if the export fails.
+	Gzip bool
+	// Segments is the number of parallel scan segments. Defaults to 1. Item order is not stable
+	// with more than one segment.
+	Segments int
+	// ReadCapacityPerSecond limits the read capacity used by the scan. Zero is unlimited.
+	ReadCapacityPerSecond float64
+}
+
+// ExportManifest records what was exported. It is intended to be saved as JSON alongside the export
+// so the file can be checked and imported into a table with a matching schema.
+type ExportManifest struct {
+	TableName            string                          `json:"tableName"`
+	Format               Format                          `json:"format"`
+	Gzip                 bool                            `json:"gzip"`
+	ItemCount            int64                           `json:"itemCount"`
+	KeySchema            []*dynamodb.KeySchemaElement    `json:"keySchema"`
+	AttributeDefinitions []*dynamodb.AttributeDefinition `json:"attributeDefinitions"`
+	StartTime            time.Time                       `json:"startTime"`
+	EndTime              time.Time                       `json:"endTime"`
+}
+
+// ExportTable scans a table and writes every item to w as JSON Lines, one item per line.
+func ExportTable(client *dynamodb.DynamoDB, w io.Writer, input *ExportInput) (*ExportManifest, error) {
+	return ExportTableWithContext(context.Background(), client, w, input)
+}
+
+// ExportTableWithContext is the same as ExportTable.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func ExportTableWithContext(ctx context.Context, client *dynamodb.DynamoDB, w io.Writer, input *ExportInput, opts ...request.Option) (manifest *ExportManifest, err error) {
+	if input.TableName == "" {
+		return nil, ErrExportTableName
+	}
+	format := input.Format
+	if format == "" {
+		format = FormatDynamoDBJSON
+	}
+	var marshal func(map[string]*dynamodb.AttributeValue) ([]byte, error)
+	switch format {
+	case FormatDynamoDBJSON:
+		marshal = MarshalDynamoDBJSON
+	case FormatJSON:
+		marshal = MarshalPlainJSON
+	default:
+		return nil, ErrExportFormat
+	}
+
+	desc, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(input.TableName)}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	manifest = &ExportManifest{
+		TableName:            input.TableName,
+		Format:               format,
+		Gzip:                 input.Gzip,
+		KeySchema:            desc.Table.KeySchema,
+		AttributeDefinitions: desc.Table.AttributeDefinitions,
+		StartTime:            time.Now().UTC(),
+	}
+
+	var gz *gzip.Writer
+	if input.Gzip {
+		gz = gzip.NewWriter(w)
+		w = gz
+	}
+	bw := bufio.NewWriter(w)
+	// Flush and close on every path, keeping the first error, so a partial export is still valid gzip
+	defer func() {
+		if ferr := bw.Flush(); ferr != nil && err == nil {
+			err = ferr
+		}
+		if gz != nil {
+			if cerr := gz.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			manifest = nil
+		}
+	}()
+
+	var mu sync.Mutex
+	scanner := &ParallelScanner{
+		Segments:              input.Segments,
+		ReadCapacityPerSecond: input.ReadCapacityPerSecond,
+	}
+	err = scanner.scanPages(ctx, client, &dynamodb.ScanInput{
+		TableName: aws.String(input.TableName),
+	}, func(_ int, page *dynamodb.ScanOutput) error {
+		lines := make([][]byte, 0, len(page.Items))
+		for _, item := range page.Items {
+			line, err := marshal(item)
+			if err != nil {
+				return err
+			}
+			lines = append(lines, line)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, line := range lines {
+			if _, err := bw.Write(line); err != nil {
+				return err
+			}
+			if err := bw.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+		manifest.ItemCount += int64(len(lines))
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	manifest.EndTime = time.Now().UTC()
+	return manifest, nil
+}
@@ -0,0 +1,179 @@
+package dynamodbx_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func TestExportTable(t *testing.T) {
+	t.Parallel()
+	type TestData struct {
+		S string
+	}
+	testDataSet := func(count int) []*TestData {
+		data := make([]*TestData, count)
+		for i := 0; i < count; i++ {
+			data[i] = &TestData{
+				S: strconv.Itoa(i),
+			}
+		}
+		return data
+	}
+	for _, tc := range []struct {
+		name  string
+		input *dynamodbx.ExportInput
+		count int
+		err   error
+	}{
+		{
+			name:  "empty table name",
+			input: &dynamodbx.ExportInput{},
+			err:   dynamodbx.ErrExportTableName,
+		},
+		{
+			name:  "unknown format",
+			input: &dynamodbx.ExportInput{TableName: "test", Format: "XML"},
+			err:   dynamodbx.ErrExportFormat,
+		},
+		{
+			name:  "dynamodb json",
+			input: &dynamodbx.ExportInput{TableName: "testExportDynamoDBJSON"},
+			count: 60,
+		},
+		{
+			name: "gzipped plain json in parallel",
+			input: &dynamodbx.ExportInput{
+				TableName: "testExportJSON",
+				Format:    dynamodbx.FormatJSON,
+				Gzip:      true,
+				Segments:  3,
+			},
+			count: 60,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ddb := memdb.NewClient()
+			var buf bytes.Buffer
+			if tc.err != nil {
+				if _, err := dynamodbx.ExportTable(ddb, &buf, tc.input); err != tc.err {
+					t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			_, err := dynamodbx.CreateTableSync(ddb, &dynamodb.CreateTableInput{
+				TableName:   aws.String(tc.input.TableName),
+				BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{
+					{
+						AttributeName: aws.String("S"),
+						AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+					},
+				},
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{
+						AttributeName: aws.String("S"),
+						KeyType:       aws.String(dynamodb.KeyTypeHash),
+					},
+				},
+			})
+			defer ddb.DeleteTable(&dynamodb.DeleteTableInput{
+				TableName: aws.String(tc.input.TableName),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := dynamodbx.BatchPutRequest(tc.input.TableName, testDataSet(tc.count))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := dynamodbx.BatchWriteItem(ddb, &dynamodb.BatchWriteItemInput{RequestItems: req}); err != nil {
+				t.Fatal(err)
+			}
+
+			manifest, err := dynamodbx.ExportTable(ddb, &buf, tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if manifest.ItemCount != int64(tc.count) || len(manifest.KeySchema) != 1 {
+				t.Fatalf("unexpected manifest: %+v", manifest)
+			}
+			var r io.Reader = &buf
+			if tc.input.Gzip {
+				if r, err = gzip.NewReader(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			lines := 0
+			s := bufio.NewScanner(r)
+			for s.Scan() {
+				lines++
+				if tc.input.Format == "" {
+					if _, err := dynamodbx.UnmarshalDynamoDBJSON(s.Bytes()); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if err := s.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if lines != tc.count {
+				t.Fatalf("expected %d lines, got %d", tc.count, lines)
+			}
+		})
+	}
+}
+
+func TestExportTableFailedScan(t *testing.T) {
+	t.Parallel()
+	errScan := errors.New("scan failed")
+	ddb, _ := stubClient(func(op string, input interface{}) (interface{}, error) {
+		switch in := input.(type) {
+		case *dynamodb.DescribeTableInput:
+			return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{TableName: in.TableName}}, nil
+		case *dynamodb.ScanInput:
+			if in.ExclusiveStartKey != nil {
+				return nil, errScan
+			}
+			last := map[string]*dynamodb.AttributeValue{"S": {S: aws.String("b")}}
+			return &dynamodb.ScanOutput{
+				Items:            []map[string]*dynamodb.AttributeValue{{"S": {S: aws.String("a")}}, last},
+				LastEvaluatedKey: last,
+			}, nil
+		}
+		t.Fatalf("unexpected operation %s", op)
+		return nil, nil
+	})
+	var buf bytes.Buffer
+	manifest, err := dynamodbx.ExportTable(ddb, &buf, &dynamodbx.ExportInput{TableName: "test", Gzip: true})
+	if err != errScan {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, errScan)
+	}
+	if manifest != nil {
+		t.Fatalf("expected no manifest, got %+v", manifest)
+	}
+	// The partial export is still a complete gzip stream with the items scanned so far
+	r, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(b, []byte("\n")); lines != 2 {
+		t.Fatalf("expected 2 lines, got %d", lines)
+	}
+}
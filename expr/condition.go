@@ -0,0 +1,263 @@
+package expr
+
+import "strings"
+
+// ConditionBuilder is a condition or filter expression. The zero ConditionBuilder is unset, and
+// is an error if it is used.
+type ConditionBuilder struct {
+	build func(a *aliases) (string, error)
+}
+
+// IsSet reports whether the condition has been set.
+func (c ConditionBuilder) IsSet() bool {
+	return c.build != nil
+}
+
+func (c ConditionBuilder) builder() func(a *aliases) (string, error) {
+	if c.build == nil {
+		return unset
+	}
+	return c.build
+}
+
+// format returns a condition which renders the operands and substitutes them, in order, for the
+// %s verbs of f.
+func format(f string, operands ...OperandBuilder) ConditionBuilder {
+	return ConditionBuilder{func(a *aliases) (string, error) {
+		args := make([]string, len(operands))
+		for i, op := range operands {
+			if op == nil {
+				return "", ErrNilOperand
+			}
+			s, err := op.buildOperand(a)
+			if err != nil {
+				return "", err
+			}
+			args[i] = s
+		}
+		parts := strings.Split(f, "%s")
+		var b strings.Builder
+		for i, part := range parts {
+			b.WriteString(part)
+			if i < len(args) {
+				b.WriteString(args[i])
+			}
+		}
+		return b.String(), nil
+	}}
+}
+
+// Equal returns the condition l = r.
+func Equal(l, r OperandBuilder) ConditionBuilder { return format("%s = %s", l, r) }
+
+// NotEqual returns the condition l <> r.
+func NotEqual(l, r OperandBuilder) ConditionBuilder { return format("%s <> %s", l, r) }
+
+// LessThan returns the condition l < r.
+func LessThan(l, r OperandBuilder) ConditionBuilder { return format("%s < %s", l, r) }
+
+// LessThanEqual returns the condition l <= r.
+func LessThanEqual(l, r OperandBuilder) ConditionBuilder { return format("%s <= %s", l, r) }
+
+// GreaterThan returns the condition l > r.
+func GreaterThan(l, r OperandBuilder) ConditionBuilder { return format("%s > %s", l, r) }
+
+// GreaterThanEqual returns the condition l >= r.
+func GreaterThanEqual(l, r OperandBuilder) ConditionBuilder { return format("%s >= %s", l, r) }
+
+// Between returns the condition that op is between lower and upper inclusive.
+func Between(op, lower, upper OperandBuilder) ConditionBuilder {
+	return format("%s BETWEEN %s AND %s", op, lower, upper)
+}
+
+// maxInOperands is the most values dynamodb accepts in an IN condition.
+const maxInOperands = 100
+
+// In returns the condition that op equals one of the values, of which there can be at most 100.
+func In(op OperandBuilder, values ...OperandBuilder) ConditionBuilder {
+	if len(values) == 0 {
+		return ConditionBuilder{func(*aliases) (string, error) { return "", ErrNoOperands }}
+	}
+	if len(values) > maxInOperands {
+		return ConditionBuilder{func(*aliases) (string, error) { return "", ErrTooManyOperands }}
+	}
+	return format("%s IN ("+strings.Repeat(", %s", len(values))[2:]+")", append([]OperandBuilder{op}, values...)...)
+}
+
+// AttributeExists returns the condition that the attribute exists.
+func AttributeExists(name NameBuilder) ConditionBuilder {
+	return format("attribute_exists(%s)", name)
+}
+
+// AttributeNotExists returns the condition that the attribute does not exist.
+func AttributeNotExists(name NameBuilder) ConditionBuilder {
+	return format("attribute_not_exists(%s)", name)
+}
+
+// AttributeType returns the condition that the attribute has the type, such as
+// dynamodb.ScalarAttributeTypeS or "SS".
+func AttributeType(name NameBuilder, attributeType string) ConditionBuilder {
+	return format("attribute_type(%s, %s)", name, Value(attributeType))
+}
+
+// BeginsWith returns the condition that the attribute begins with the prefix.
+func BeginsWith(name NameBuilder, prefix string) ConditionBuilder {
+	return format("begins_with(%s, %s)", name, Value(prefix))
+}
+
+// Contains returns the condition that the attribute contains the value: a substring of a string,
+// or an element of a set or list.
+func Contains(name NameBuilder, value interface{}) ConditionBuilder {
+	return format("contains(%s, %s)", name, Value(value))
+}
+
+// And returns the condition that all of the conditions are true.
+func And(l, r ConditionBuilder, more ...ConditionBuilder) ConditionBuilder {
+	return join(" AND ", append([]ConditionBuilder{l, r}, more...))
+}
+
+// Or returns the condition that any of the conditions is true.
+func Or(l, r ConditionBuilder, more ...ConditionBuilder) ConditionBuilder {
+	return join(" OR ", append([]ConditionBuilder{l, r}, more...))
+}
+
+// Not returns the condition that c is false.
+func Not(c ConditionBuilder) ConditionBuilder {
+	return ConditionBuilder{func(a *aliases) (string, error) {
+		s, err := c.builder()(a)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + s + ")", nil
+	}}
+}
+
+func join(op string, conds []ConditionBuilder) ConditionBuilder {
+	return ConditionBuilder{func(a *aliases) (string, error) {
+		parts := make([]string, len(conds))
+		for i, c := range conds {
+			s, err := c.builder()(a)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = "(" + s + ")"
+		}
+		return strings.Join(parts, op), nil
+	}}
+}
+
+// And returns the condition that c and the other conditions are true.
+func (c ConditionBuilder) And(r ConditionBuilder, more ...ConditionBuilder) ConditionBuilder {
+	return And(c, r, more...)
+}
+
+// Or returns the condition that c or any of the other conditions is true.
+func (c ConditionBuilder) Or(r ConditionBuilder, more ...ConditionBuilder) ConditionBuilder {
+	return Or(c, r, more...)
+}
+
+// Not returns the condition that c is false.
+func (c ConditionBuilder) Not() ConditionBuilder {
+	return Not(c)
+}
+
+// Equal returns the condition n = r.
+func (n NameBuilder) Equal(r OperandBuilder) ConditionBuilder { return Equal(n, r) }
+
+// NotEqual returns the condition n <> r.
+func (n NameBuilder) NotEqual(r OperandBuilder) ConditionBuilder { return NotEqual(n, r) }
+
+// LessThan returns the condition n < r.
+func (n NameBuilder) LessThan(r OperandBuilder) ConditionBuilder { return LessThan(n, r) }
+
+// LessThanEqual returns the condition n <= r.
+func (n NameBuilder) LessThanEqual(r OperandBuilder) ConditionBuilder { return LessThanEqual(n, r) }
+
+// GreaterThan returns the condition n > r.
+func (n NameBuilder) GreaterThan(r OperandBuilder) ConditionBuilder { return GreaterThan(n, r) }
+
+// GreaterThanEqual returns the condition n >= r.
+func (n NameBuilder) GreaterThanEqual(r OperandBuilder) ConditionBuilder {
+	return GreaterThanEqual(n, r)
+}
+
+// Between returns the condition that n is between lower and upper inclusive.
+func (n NameBuilder) Between(lower, upper OperandBuilder) ConditionBuilder {
+	return Between(n, lower, upper)
+}
+
+// In returns the condition that n equals one of the values.
+func (n NameBuilder) In(values ...OperandBuilder) ConditionBuilder { return In(n, values...) }
+
+// AttributeExists returns the condition that the attribute exists.
+func (n NameBuilder) AttributeExists() ConditionBuilder { return AttributeExists(n) }
+
+// AttributeNotExists returns the condition that the attribute does not exist.
+func (n NameBuilder) AttributeNotExists() ConditionBuilder { return AttributeNotExists(n) }
+
+// AttributeType returns the condition that the attribute has the type.
+func (n NameBuilder) AttributeType(attributeType string) ConditionBuilder {
+	return AttributeType(n, attributeType)
+}
+
+// BeginsWith returns the condition that the attribute begins with the prefix.
+func (n NameBuilder) BeginsWith(prefix string) ConditionBuilder { return BeginsWith(n, prefix) }
+
+// Contains returns the condition that the attribute contains the value.
+func (n NameBuilder) Contains(value interface{}) ConditionBuilder { return Contains(n, value) }
+
+// Equal returns the condition v = r.
+func (v ValueBuilder) Equal(r OperandBuilder) ConditionBuilder { return Equal(v, r) }
+
+// NotEqual returns the condition v <> r.
+func (v ValueBuilder) NotEqual(r OperandBuilder) ConditionBuilder { return NotEqual(v, r) }
+
+// LessThan returns the condition v < r.
+func (v ValueBuilder) LessThan(r OperandBuilder) ConditionBuilder { return LessThan(v, r) }
+
+// LessThanEqual returns the condition v <= r.
+func (v ValueBuilder) LessThanEqual(r OperandBuilder) ConditionBuilder { return LessThanEqual(v, r) }
+
+// GreaterThan returns the condition v > r.
+func (v ValueBuilder) GreaterThan(r OperandBuilder) ConditionBuilder { return GreaterThan(v, r) }
+
+// GreaterThanEqual returns the condition v >= r.
+func (v ValueBuilder) GreaterThanEqual(r OperandBuilder) ConditionBuilder {
+	return GreaterThanEqual(v, r)
+}
+
+// Between returns the condition that v is between lower and upper inclusive.
+func (v ValueBuilder) Between(lower, upper OperandBuilder) ConditionBuilder {
+	return Between(v, lower, upper)
+}
+
+// In returns the condition that v equals one of the values.
+func (v ValueBuilder) In(values ...OperandBuilder) ConditionBuilder { return In(v, values...) }
+
+// Equal returns the condition s = r.
+func (s SizeBuilder) Equal(r OperandBuilder) ConditionBuilder { return Equal(s, r) }
+
+// NotEqual returns the condition s <> r.
+func (s SizeBuilder) NotEqual(r OperandBuilder) ConditionBuilder { return NotEqual(s, r) }
+
+// LessThan returns the condition s < r.
+func (s SizeBuilder) LessThan(r OperandBuilder) ConditionBuilder { return LessThan(s, r) }
+
+// LessThanEqual returns the condition s <= r.
+func (s SizeBuilder) LessThanEqual(r OperandBuilder) ConditionBuilder { return LessThanEqual(s, r) }
+
+// GreaterThan returns the condition s > r.
+func (s SizeBuilder) GreaterThan(r OperandBuilder) ConditionBuilder { return GreaterThan(s, r) }
+
+// GreaterThanEqual returns the condition s >= r.
+func (s SizeBuilder) GreaterThanEqual(r OperandBuilder) ConditionBuilder {
+	return GreaterThanEqual(s, r)
+}
+
+// Between returns the condition that s is between lower and upper inclusive.
+func (s SizeBuilder) Between(lower, upper OperandBuilder) ConditionBuilder {
+	return Between(s, lower, upper)
+}
+
+// In returns the condition that s equals one of the values.
+func (s SizeBuilder) In(values ...OperandBuilder) ConditionBuilder { return In(s, values...) }
@@ -0,0 +1,264 @@
+// Package expr builds dynamodb condition, filter, key condition, update and projection expressions,
+// replacing hand-written expression strings and their ExpressionAttributeNames and
+// ExpressionAttributeValues maps.
+//
+// Attribute names and values are replaced by generated placeholders, #n0, #n1, ... and :v0,
+// :v1, ..., which are unique across all the expressions of a Builder, so reserved words and
+// special characters in names need no escaping:
+//
+//	cond := expr.Name("Status").Equal(expr.Value("active")).
+//		And(expr.Name("Age").Between(expr.Value(18), expr.Value(65)))
+//	e, err := expr.NewBuilder().
+//		WithKeyCondition(expr.Key("PK").Equal(expr.Value("user#1"))).
+//		WithFilter(cond).
+//		Build()
+//	if err != nil {
+//		return err
+//	}
+//	out, err := client.Query(&dynamodb.QueryInput{
+//		TableName:                 aws.String("users"),
+//		KeyConditionExpression:    e.KeyCondition(),
+//		FilterExpression:          e.Filter(),
+//		ExpressionAttributeNames:  e.Names(),
+//		ExpressionAttributeValues: e.Values(),
+//	})
+//
+// Values are marshalled with dynamodbattribute.Marshal, or used as they are if they are a
+// *dynamodb.AttributeValue. Errors from building operands, such as values which cannot be
+// marshalled, are returned by Build.
+package expr
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// Errors returned by Build.
+var (
+	ErrEmptyBuilder    = errors.New("dynamodbx/expr: the builder has no expressions")
+	ErrEmptyName       = errors.New("dynamodbx/expr: attribute names cannot be empty")
+	ErrInvalidPath     = errors.New("dynamodbx/expr: invalid document path")
+	ErrUnsetCondition  = errors.New("dynamodbx/expr: conditions cannot be unset")
+	ErrNoOperands      = errors.New("dynamodbx/expr: In requires at least one value")
+	ErrTooManyOperands = errors.New("dynamodbx/expr: In accepts at most 100 values")
+	ErrKeyCondition    = errors.New("dynamodbx/expr: key conditions can only be combined as a partition key equality and a sort key condition")
+	ErrEmptyUpdate     = errors.New("dynamodbx/expr: updates must have at least one action")
+	ErrNilOperand      = errors.New("dynamodbx/expr: operands cannot be nil")
+	ErrEmptyProjection = errors.New("dynamodbx/expr: projections must have at least one attribute")
+	ErrProjectionType  = errors.New("dynamodbx/expr: projections can only be derived from struct types")
+)
+
+// kind identifies an expression of a Builder.
+type kind int
+
+const (
+	keyCondition kind = iota
+	condition
+	filter
+	update
+	projection
+	numKinds
+)
+
+// Builder collects the expressions of a request. The zero Builder is empty and ready to use.
+// Builders are values, so each With method returns a new Builder.
+type Builder struct {
+	builders [numKinds]func(*aliases) (string, error)
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() Builder {
+	return Builder{}
+}
+
+// WithKeyCondition sets the key condition expression of a Query.
+func (b Builder) WithKeyCondition(k KeyConditionBuilder) Builder {
+	b.builders[keyCondition] = k.build
+	if b.builders[keyCondition] == nil {
+		b.builders[keyCondition] = unset
+	}
+	return b
+}
+
+// WithCondition sets the condition expression of a write.
+func (b Builder) WithCondition(c ConditionBuilder) Builder {
+	b.builders[condition] = c.builder()
+	return b
+}
+
+// WithFilter sets the filter expression of a Query or Scan.
+func (b Builder) WithFilter(c ConditionBuilder) Builder {
+	b.builders[filter] = c.builder()
+	return b
+}
+
+// WithUpdate sets the update expression of an UpdateItem.
+func (b Builder) WithUpdate(u UpdateBuilder) Builder {
+	b.builders[update] = u.build
+	return b
+}
+
+// WithProjection sets the projection expression of a read.
+func (b Builder) WithProjection(p ProjectionBuilder) Builder {
+	b.builders[projection] = p.build
+	return b
+}
+
+// Build renders the expressions and their placeholders. Expressions are rendered in a fixed order,
+// so the same Builder always produces the same Expression.
+func (b Builder) Build() (Expression, error) {
+	a := &aliases{}
+	e := Expression{}
+	empty := true
+	for k, build := range b.builders {
+		if build == nil {
+			continue
+		}
+		empty = false
+		s, err := build(a)
+		if err != nil {
+			return Expression{}, err
+		}
+		e.expressions[k] = aws.String(s)
+	}
+	if empty {
+		return Expression{}, ErrEmptyBuilder
+	}
+	e.names, e.values = a.names, a.values
+	return e, nil
+}
+
+func unset(*aliases) (string, error) {
+	return "", ErrUnsetCondition
+}
+
+// Expression is a set of built expressions and their placeholders. Accessors return nil for
+// expressions which were not set and for empty placeholder maps, as dynamodb rejects empty maps.
+type Expression struct {
+	expressions [numKinds]*string
+	names       map[string]*string
+	values      map[string]*dynamodb.AttributeValue
+}
+
+// KeyCondition returns the key condition expression.
+func (e Expression) KeyCondition() *string {
+	return e.expressions[keyCondition]
+}
+
+// Condition returns the condition expression.
+func (e Expression) Condition() *string {
+	return e.expressions[condition]
+}
+
+// Filter returns the filter expression.
+func (e Expression) Filter() *string {
+	return e.expressions[filter]
+}
+
+// Update returns the update expression.
+func (e Expression) Update() *string {
+	return e.expressions[update]
+}
+
+// Projection returns the projection expression.
+func (e Expression) Projection() *string {
+	return e.expressions[projection]
+}
+
+// Names returns the ExpressionAttributeNames of the expressions.
+func (e Expression) Names() map[string]*string {
+	return e.names
+}
+
+// Values returns the ExpressionAttributeValues of the expressions.
+func (e Expression) Values() map[string]*dynamodb.AttributeValue {
+	return e.values
+}
+
+// aliases generates the placeholders of the expressions of a Builder. The same name is always
+// given the same placeholder, each value is given a new one.
+type aliases struct {
+	names  map[string]*string
+	byName map[string]string
+	values map[string]*dynamodb.AttributeValue
+}
+
+// name returns the placeholder for an attribute name.
+func (a *aliases) name(name string) string {
+	if alias, ok := a.byName[name]; ok {
+		return alias
+	}
+	if a.names == nil {
+		a.names = make(map[string]*string)
+		a.byName = make(map[string]string)
+	}
+	alias := "#n" + strconv.Itoa(len(a.names))
+	a.names[alias] = aws.String(name)
+	a.byName[name] = alias
+	return alias
+}
+
+// path returns the placeholders for a document path such as a.b[0].c, replacing each attribute
+// name and keeping list indexes.
+func (a *aliases) path(path string) (string, error) {
+	if path == "" {
+		return "", ErrEmptyName
+	}
+	var b strings.Builder
+	for i, part := range strings.Split(path, ".") {
+		name := part
+		var indexes string
+		if j := strings.IndexByte(part, '['); j >= 0 {
+			name, indexes = part[:j], part[j:]
+			if !validIndexes(indexes) {
+				return "", ErrInvalidPath
+			}
+		}
+		if name == "" {
+			return "", ErrInvalidPath
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(a.name(name))
+		b.WriteString(indexes)
+	}
+	return b.String(), nil
+}
+
+// validIndexes reports whether s is a sequence of list indexes such as [0][12].
+func validIndexes(s string) bool {
+	for s != "" {
+		end := strings.IndexByte(s, ']')
+		if s[0] != '[' || end < 2 {
+			return false
+		}
+		if _, err := strconv.ParseUint(s[1:end], 10, 32); err != nil {
+			return false
+		}
+		s = s[end+1:]
+	}
+	return true
+}
+
+// value returns the placeholder for a value.
+func (a *aliases) value(v interface{}) (string, error) {
+	av, ok := v.(*dynamodb.AttributeValue)
+	if !ok {
+		var err error
+		if av, err = dynamodbattribute.Marshal(v); err != nil {
+			return "", err
+		}
+	}
+	if a.values == nil {
+		a.values = make(map[string]*dynamodb.AttributeValue)
+	}
+	alias := ":v" + strconv.Itoa(len(a.values))
+	a.values[alias] = av
+	return alias, nil
+}
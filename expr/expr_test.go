@@ -0,0 +1,463 @@
+package expr_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx/expr"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+type Base struct {
+	ID      string
+	Version int `json:"v"`
+}
+
+type Address struct {
+	City  string
+	Lines []string
+	Geo   *struct{ Lat, Lng float64 }
+}
+
+type Node struct {
+	Value string
+	Next  *Node
+}
+
+type Profile struct {
+	Base    `dynamodbav:"base"`
+	ID      string `dynamodbav:"pk"`
+	Name    string `dynamodbav:",omitempty"`
+	Secret  string `dynamodbav:"-"`
+	private string
+	Home    Address
+	Created time.Time
+	Tags    map[string]string
+	List    Node
+	Empty   struct{}
+}
+
+// values returns n distinct value operands.
+func values(n int) []expr.OperandBuilder {
+	ops := make([]expr.OperandBuilder, n)
+	for i := range ops {
+		ops[i] = expr.Value(i)
+	}
+	return ops
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name         string
+		builder      expr.Builder
+		keyCondition *string
+		condition    *string
+		filter       *string
+		update       *string
+		projection   *string
+		names        map[string]*string
+		values       map[string]*dynamodb.AttributeValue
+		err          error
+	}{
+		{
+			name:    "empty",
+			builder: expr.NewBuilder(),
+			err:     expr.ErrEmptyBuilder,
+		},
+		{
+			name:      "comparison",
+			builder:   expr.NewBuilder().WithCondition(expr.Name("A").Equal(expr.Value(1))),
+			condition: aws.String("#n0 = :v0"),
+			names:     map[string]*string{"#n0": aws.String("A")},
+			values:    map[string]*dynamodb.AttributeValue{":v0": {N: aws.String("1")}},
+		},
+		{
+			name: "and or not",
+			builder: expr.NewBuilder().WithCondition(
+				expr.Name("A").GreaterThan(expr.Value(1)).
+					And(expr.Name("B").NotEqual(expr.Name("A")), expr.Name("C").LessThanEqual(expr.Value("x"))).
+					Or(expr.Not(expr.Name("D").AttributeExists())),
+			),
+			condition: aws.String("((#n0 > :v0) AND (#n1 <> #n0) AND (#n2 <= :v1)) OR (NOT (attribute_exists(#n3)))"),
+			names:     map[string]*string{"#n0": aws.String("A"), "#n1": aws.String("B"), "#n2": aws.String("C"), "#n3": aws.String("D")},
+			values: map[string]*dynamodb.AttributeValue{
+				":v0": {N: aws.String("1")},
+				":v1": {S: aws.String("x")},
+			},
+		},
+		{
+			name: "functions",
+			builder: expr.NewBuilder().WithFilter(expr.And(
+				expr.BeginsWith(expr.Name("S"), "pre"),
+				expr.Contains(expr.Name("SS"), "x"),
+				expr.Name("L").Size().Between(expr.Value(1), expr.Value(3)),
+				expr.Name("N").In(expr.Value(1), expr.Value(2)),
+				expr.AttributeType(expr.Name("M"), dynamodb.ScalarAttributeTypeS),
+				expr.Name("X").AttributeNotExists(),
+			)),
+			filter: aws.String("(begins_with(#n0, :v0)) AND (contains(#n1, :v1)) AND (size(#n2) BETWEEN :v2 AND :v3) AND (#n3 IN (:v4, :v5)) AND (attribute_type(#n4, :v6)) AND (attribute_not_exists(#n5))"),
+			names: map[string]*string{
+				"#n0": aws.String("S"), "#n1": aws.String("SS"), "#n2": aws.String("L"),
+				"#n3": aws.String("N"), "#n4": aws.String("M"), "#n5": aws.String("X"),
+			},
+			values: map[string]*dynamodb.AttributeValue{
+				":v0": {S: aws.String("pre")},
+				":v1": {S: aws.String("x")},
+				":v2": {N: aws.String("1")},
+				":v3": {N: aws.String("3")},
+				":v4": {N: aws.String("1")},
+				":v5": {N: aws.String("2")},
+				":v6": {S: aws.String("S")},
+			},
+		},
+		{
+			name:      "document paths",
+			builder:   expr.NewBuilder().WithCondition(expr.Name("a.b[1][2].a").Equal(expr.AttributeName("a.b"))),
+			condition: aws.String("#n0.#n1[1][2].#n0 = #n2"),
+			names:     map[string]*string{"#n0": aws.String("a"), "#n1": aws.String("b"), "#n2": aws.String("a.b")},
+		},
+		{
+			name: "key condition and filter share placeholders",
+			builder: expr.NewBuilder().
+				WithKeyCondition(expr.Key("P").Equal(expr.Value("p")).And(expr.Key("R").BeginsWith("r"))).
+				WithFilter(expr.Name("P").NotEqual(expr.Value(&dynamodb.AttributeValue{NULL: aws.Bool(true)}))),
+			keyCondition: aws.String("#n0 = :v0 AND begins_with(#n1, :v1)"),
+			filter:       aws.String("#n0 <> :v2"),
+			names:        map[string]*string{"#n0": aws.String("P"), "#n1": aws.String("R")},
+			values: map[string]*dynamodb.AttributeValue{
+				":v0": {S: aws.String("p")},
+				":v1": {S: aws.String("r")},
+				":v2": {NULL: aws.Bool(true)},
+			},
+		},
+		{
+			name: "update",
+			builder: expr.NewBuilder().
+				WithCondition(expr.Name("V").Equal(expr.Value(1))).
+				WithUpdate(expr.Set(expr.Name("A"), expr.Value("a")).
+					Add(expr.Name("V"), expr.Value(1)).
+					Set(expr.Name("C"), expr.Name("C").IfNotExists(expr.Value(0))).
+					Delete(expr.Name("SS"), expr.Value(&dynamodb.AttributeValue{SS: aws.StringSlice([]string{"x"})})).
+					Remove(expr.Name("B[0]")).
+					Set(expr.Name("L"), expr.ListAppend(expr.Name("L"), expr.Value([]int{1}))).
+					Set(expr.Name("M.N"), expr.Name("M.N").Plus(expr.Value(2))).
+					Set(expr.AttributeName("M").Child("N.O"), expr.Name("V").Minus(expr.Value(3)))),
+			condition: aws.String("#n0 = :v0"),
+			update:    aws.String("SET #n1 = :v1, #n2 = if_not_exists(#n2, :v3), #n5 = list_append(#n5, :v5), #n6.#n7 = #n6.#n7 + :v6, #n6.#n8 = #n0 - :v7 REMOVE #n4[0] ADD #n0 :v2 DELETE #n3 :v4"),
+			names: map[string]*string{
+				"#n0": aws.String("V"), "#n1": aws.String("A"), "#n2": aws.String("C"), "#n3": aws.String("SS"),
+				"#n4": aws.String("B"), "#n5": aws.String("L"), "#n6": aws.String("M"), "#n7": aws.String("N"),
+				"#n8": aws.String("N.O"),
+			},
+			values: map[string]*dynamodb.AttributeValue{
+				":v0": {N: aws.String("1")},
+				":v1": {S: aws.String("a")},
+				":v2": {N: aws.String("1")},
+				":v3": {N: aws.String("0")},
+				":v4": {SS: aws.StringSlice([]string{"x"})},
+				":v5": {L: []*dynamodb.AttributeValue{{N: aws.String("1")}}},
+				":v6": {N: aws.String("2")},
+				":v7": {N: aws.String("3")},
+			},
+		},
+		{
+			name:    "empty update",
+			builder: expr.NewBuilder().WithUpdate(expr.UpdateBuilder{}),
+			err:     expr.ErrEmptyUpdate,
+		},
+		{
+			name:    "nil operand",
+			builder: expr.NewBuilder().WithUpdate(expr.Set(expr.Name("A"), nil)),
+			err:     expr.ErrNilOperand,
+		},
+		{
+			name: "projection",
+			builder: expr.NewBuilder().
+				WithProjection(expr.NamesList(expr.Name("A"), expr.Name("B.C[1]")).AddNames(expr.AttributeName("B.C"))).
+				WithFilter(expr.Name("A").AttributeExists()),
+			filter:     aws.String("attribute_exists(#n0)"),
+			projection: aws.String("#n0, #n1.#n2[1], #n3"),
+			names:      map[string]*string{"#n0": aws.String("A"), "#n1": aws.String("B"), "#n2": aws.String("C"), "#n3": aws.String("B.C")},
+		},
+		{
+			name:       "projection for struct",
+			builder:    expr.NewBuilder().WithProjection(expr.ProjectionFor(&[]*Profile{})),
+			projection: aws.String("#n0, #n1, #n2.#n3, #n2.#n4, #n2.#n5.#n6, #n2.#n5.#n7, #n8, #n9, #n10.#n11, #n10.#n12, #n13, #n14, #n15"),
+			names: map[string]*string{
+				"#n0": aws.String("pk"), "#n1": aws.String("Name"), "#n2": aws.String("Home"), "#n3": aws.String("City"),
+				"#n4": aws.String("Lines"), "#n5": aws.String("Geo"), "#n6": aws.String("Lat"), "#n7": aws.String("Lng"),
+				"#n8": aws.String("Created"), "#n9": aws.String("Tags"), "#n10": aws.String("List"), "#n11": aws.String("Value"),
+				"#n12": aws.String("Next"), "#n13": aws.String("Empty"), "#n14": aws.String("ID"),
+				"#n15": aws.String("v"),
+			},
+		},
+		{
+			name:    "projection for non struct",
+			builder: expr.NewBuilder().WithProjection(expr.ProjectionFor(map[string]string{})),
+			err:     expr.ErrProjectionType,
+		},
+		{
+			name:    "empty projection",
+			builder: expr.NewBuilder().WithProjection(expr.ProjectionBuilder{}),
+			err:     expr.ErrEmptyProjection,
+		},
+		{
+			name:    "empty name",
+			builder: expr.NewBuilder().WithCondition(expr.Name("").AttributeExists()),
+			err:     expr.ErrEmptyName,
+		},
+		{
+			name:    "invalid path",
+			builder: expr.NewBuilder().WithCondition(expr.Name("a[x]").AttributeExists()),
+			err:     expr.ErrInvalidPath,
+		},
+		{
+			name:    "unset condition",
+			builder: expr.NewBuilder().WithFilter(expr.ConditionBuilder{}),
+			err:     expr.ErrUnsetCondition,
+		},
+		{
+			name:    "in without values",
+			builder: expr.NewBuilder().WithFilter(expr.Name("A").In()),
+			err:     expr.ErrNoOperands,
+		},
+		{
+			name:    "in with too many values",
+			builder: expr.NewBuilder().WithFilter(expr.Name("A").In(values(101)...)),
+			err:     expr.ErrTooManyOperands,
+		},
+		{
+			name:    "sort key condition first",
+			builder: expr.NewBuilder().WithKeyCondition(expr.Key("R").GreaterThan(expr.Value(1)).And(expr.Key("P").Equal(expr.Value(1)))),
+			err:     expr.ErrKeyCondition,
+		},
+		{
+			name: "three key conditions",
+			builder: expr.NewBuilder().WithKeyCondition(expr.KeyAnd(
+				expr.KeyEqual(expr.Key("P"), expr.Value(1)),
+				expr.KeyAnd(expr.KeyEqual(expr.Key("R"), expr.Value(1)), expr.KeyEqual(expr.Key("S"), expr.Value(1))),
+			)),
+			err: expr.ErrKeyCondition,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			e, err := tc.builder.Build()
+			if err != tc.err {
+				t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+			}
+			got := []interface{}{e.KeyCondition(), e.Condition(), e.Filter(), e.Update(), e.Projection(), e.Names(), e.Values()}
+			expect := []interface{}{tc.keyCondition, tc.condition, tc.filter, tc.update, tc.projection, tc.names, tc.values}
+			if !reflect.DeepEqual(got, expect) {
+				t.Fatal(pretty.Compare(got, expect))
+			}
+		})
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	_, err := ddb.CreateTable(&dynamodb.CreateTableInput{
+		TableName:   aws.String("test"),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("P"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("R"), AttributeType: aws.String("N")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("P"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("R"), KeyType: aws.String("RANGE")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type item struct {
+		P      string
+		R      int
+		Status string
+		Tags   []string `dynamodbav:",stringset"`
+	}
+	for _, it := range []item{
+		{"a", 1, "active", []string{"x"}},
+		{"a", 2, "deleted", []string{"x", "y"}},
+		{"a", 3, "active", []string{"y"}},
+		{"b", 1, "active", []string{"x"}},
+	} {
+		// Conditions use reserved words such as Status without escaping
+		e, err := expr.NewBuilder().WithCondition(expr.Name("Status").AttributeNotExists()).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		av, err := dynamodbattribute.MarshalMap(it)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ddb.PutItem(&dynamodb.PutItemInput{
+			TableName:                 aws.String("test"),
+			Item:                      av,
+			ConditionExpression:       e.Condition(),
+			ExpressionAttributeNames:  e.Names(),
+			ExpressionAttributeValues: e.Values(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	e, err := expr.NewBuilder().
+		WithKeyCondition(expr.Key("P").Equal(expr.Value("a")).And(expr.Key("R").Between(expr.Value(1), expr.Value(3)))).
+		WithFilter(expr.Name("Status").Equal(expr.Value("active")).And(expr.Name("Tags").Contains("y"))).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ddb.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("test"),
+		KeyConditionExpression:    e.KeyCondition(),
+		FilterExpression:          e.Filter(),
+		ExpressionAttributeNames:  e.Names(),
+		ExpressionAttributeValues: e.Values(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.Int64Value(out.Count) != 1 || aws.StringValue(out.Items[0]["R"].N) != "3" {
+		t.Fatalf("expected item 3, got %v", out.Items)
+	}
+
+	// Reads only return the attributes of the destination type
+	type summary struct {
+		R      int
+		Status string
+	}
+	e, err = expr.NewBuilder().WithProjection(expr.ProjectionFor([]summary{})).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	get, err := ddb.BatchGetItem(&dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{"test": {
+			Keys:                     []map[string]*dynamodb.AttributeValue{{"P": {S: aws.String("b")}, "R": {N: aws.String("1")}}},
+			ProjectionExpression:     e.Projection(),
+			ExpressionAttributeNames: e.Names(),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []map[string]*dynamodb.AttributeValue{{"R": {N: aws.String("1")}, "Status": {S: aws.String("active")}}}
+	if diff := pretty.Compare(get.Responses["test"], expect); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	s := func(v string) *dynamodb.AttributeValue { return &dynamodb.AttributeValue{S: aws.String(v)} }
+	old := map[string]*dynamodb.AttributeValue{
+		"ID":      s("1"),
+		"Name":    s("a"),
+		"Removed": s("x"),
+		"Address": {M: map[string]*dynamodb.AttributeValue{"City": s("c"), "Street.Name": s("s"), "Zip": s("z")}},
+		"Tags":    {L: []*dynamodb.AttributeValue{s("x")}},
+	}
+	new := map[string]*dynamodb.AttributeValue{
+		"ID":      s("1"),
+		"Name":    s("b"),
+		"Added":   s("y"),
+		"Address": {M: map[string]*dynamodb.AttributeValue{"City": s("c"), "Street.Name": s("t")}},
+		"Tags":    {L: []*dynamodb.AttributeValue{s("x"), s("y")}},
+	}
+	e, err := expr.NewBuilder().WithUpdate(expr.Diff(old, new)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []interface{}{e.Update(), e.Names(), e.Values()}
+	expect := []interface{}{
+		aws.String("SET #n0 = :v0, #n1.#n2 = :v1, #n4 = :v2, #n5 = :v3 REMOVE #n1.#n3, #n6"),
+		map[string]*string{
+			"#n0": aws.String("Added"), "#n1": aws.String("Address"), "#n2": aws.String("Street.Name"),
+			"#n3": aws.String("Zip"), "#n4": aws.String("Name"), "#n5": aws.String("Tags"), "#n6": aws.String("Removed"),
+		},
+		map[string]*dynamodb.AttributeValue{":v0": s("y"), ":v1": s("t"), ":v2": s("b"), ":v3": new["Tags"]},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatal(pretty.Compare(got, expect))
+	}
+	if expr.Diff(old, old).IsSet() {
+		t.Fatal("expected no actions for equal items")
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	_, err := ddb.CreateTable(&dynamodb.CreateTableInput{
+		TableName:   aws.String("test"),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("P"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("P"), KeyType: aws.String("HASH")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type item struct {
+		P       string
+		Count   int
+		Created string
+		List    []int
+		Map     map[string]int
+		Tags    []string `dynamodbav:",stringset,omitempty"`
+		Gone    string   `dynamodbav:",omitempty"`
+	}
+	av, err := dynamodbattribute.MarshalMap(item{P: "a", Count: 1, Created: "then", List: []int{1}, Map: map[string]int{"x": 1}, Tags: []string{"x", "y"}, Gone: "g"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ddb.PutItem(&dynamodb.PutItemInput{TableName: aws.String("test"), Item: av}); err != nil {
+		t.Fatal(err)
+	}
+	e, err := expr.NewBuilder().
+		WithCondition(expr.Name("Count").Equal(expr.Value(1))).
+		WithUpdate(expr.Set(expr.Name("Count"), expr.Name("Count").Plus(expr.Value(2))).
+			Set(expr.Name("Created"), expr.Name("Created").IfNotExists(expr.Value("now"))).
+			Set(expr.Name("List"), expr.Name("List").ListAppend(expr.Value([]int{2}))).
+			Set(expr.Name("Map.x"), expr.Value(2)).
+			Delete(expr.Name("Tags"), expr.Value(&dynamodb.AttributeValue{SS: aws.StringSlice([]string{"x"})})).
+			Remove(expr.Name("Gone"))).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ddb.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String("test"),
+		Key:                       map[string]*dynamodb.AttributeValue{"P": {S: aws.String("a")}},
+		ConditionExpression:       e.Condition(),
+		UpdateExpression:          e.Update(),
+		ExpressionAttributeNames:  e.Names(),
+		ExpressionAttributeValues: e.Values(),
+		ReturnValues:              aws.String(dynamodb.ReturnValueAllNew),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got item
+	if err := dynamodbattribute.UnmarshalMap(out.Attributes, &got); err != nil {
+		t.Fatal(err)
+	}
+	expect := item{P: "a", Count: 3, Created: "then", List: []int{1, 2}, Map: map[string]int{"x": 2}, Tags: []string{"y"}}
+	if diff := pretty.Compare(got, expect); diff != "" {
+		t.Fatal(diff)
+	}
+}
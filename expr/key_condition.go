@@ -0,0 +1,122 @@
+package expr
+
+// KeyBuilder is a key attribute of a table or index.
+type KeyBuilder struct {
+	name string
+}
+
+// Key returns the key attribute with the name. Key names are used as they are, as keys are always
+// top level attributes.
+func Key(name string) KeyBuilder {
+	return KeyBuilder{name: name}
+}
+
+func (k KeyBuilder) buildOperand(a *aliases) (string, error) {
+	return AttributeName(k.name).buildOperand(a)
+}
+
+// KeyConditionBuilder is a key condition expression: a partition key equality, optionally and a
+// sort key condition.
+type KeyConditionBuilder struct {
+	build func(a *aliases) (string, error)
+	// equal is true for a single equality, which can be the partition key condition of And
+	equal bool
+	// and is true for a partition and sort key condition, which cannot be combined further
+	and bool
+}
+
+// IsSet reports whether the key condition has been set.
+func (k KeyConditionBuilder) IsSet() bool {
+	return k.build != nil
+}
+
+func keyCond(f string, equal bool, operands ...OperandBuilder) KeyConditionBuilder {
+	return KeyConditionBuilder{build: format(f, operands...).build, equal: equal}
+}
+
+// KeyEqual returns the key condition k = v.
+func KeyEqual(k KeyBuilder, v ValueBuilder) KeyConditionBuilder {
+	return keyCond("%s = %s", true, k, v)
+}
+
+// KeyLessThan returns the key condition k < v.
+func KeyLessThan(k KeyBuilder, v ValueBuilder) KeyConditionBuilder {
+	return keyCond("%s < %s", false, k, v)
+}
+
+// KeyLessThanEqual returns the key condition k <= v.
+func KeyLessThanEqual(k KeyBuilder, v ValueBuilder) KeyConditionBuilder {
+	return keyCond("%s <= %s", false, k, v)
+}
+
+// KeyGreaterThan returns the key condition k > v.
+func KeyGreaterThan(k KeyBuilder, v ValueBuilder) KeyConditionBuilder {
+	return keyCond("%s > %s", false, k, v)
+}
+
+// KeyGreaterThanEqual returns the key condition k >= v.
+func KeyGreaterThanEqual(k KeyBuilder, v ValueBuilder) KeyConditionBuilder {
+	return keyCond("%s >= %s", false, k, v)
+}
+
+// KeyBetween returns the key condition that k is between lower and upper inclusive.
+func KeyBetween(k KeyBuilder, lower, upper ValueBuilder) KeyConditionBuilder {
+	return keyCond("%s BETWEEN %s AND %s", false, k, lower, upper)
+}
+
+// KeyBeginsWith returns the key condition that k begins with the prefix.
+func KeyBeginsWith(k KeyBuilder, prefix string) KeyConditionBuilder {
+	return keyCond("begins_with(%s, %s)", false, k, Value(prefix))
+}
+
+// KeyAnd returns the key condition of a partition key equality and a sort key condition.
+func KeyAnd(partition, sort KeyConditionBuilder) KeyConditionBuilder {
+	if !partition.equal || !sort.IsSet() || sort.and {
+		return KeyConditionBuilder{build: func(*aliases) (string, error) { return "", ErrKeyCondition }, and: true}
+	}
+	return KeyConditionBuilder{build: func(a *aliases) (string, error) {
+		p, err := partition.build(a)
+		if err != nil {
+			return "", err
+		}
+		s, err := sort.build(a)
+		if err != nil {
+			return "", err
+		}
+		return p + " AND " + s, nil
+	}, and: true}
+}
+
+// And returns the key condition of k, a partition key equality, and a sort key condition.
+func (k KeyConditionBuilder) And(sort KeyConditionBuilder) KeyConditionBuilder {
+	return KeyAnd(k, sort)
+}
+
+// Equal returns the key condition k = v.
+func (k KeyBuilder) Equal(v ValueBuilder) KeyConditionBuilder { return KeyEqual(k, v) }
+
+// LessThan returns the key condition k < v.
+func (k KeyBuilder) LessThan(v ValueBuilder) KeyConditionBuilder { return KeyLessThan(k, v) }
+
+// LessThanEqual returns the key condition k <= v.
+func (k KeyBuilder) LessThanEqual(v ValueBuilder) KeyConditionBuilder {
+	return KeyLessThanEqual(k, v)
+}
+
+// GreaterThan returns the key condition k > v.
+func (k KeyBuilder) GreaterThan(v ValueBuilder) KeyConditionBuilder { return KeyGreaterThan(k, v) }
+
+// GreaterThanEqual returns the key condition k >= v.
+func (k KeyBuilder) GreaterThanEqual(v ValueBuilder) KeyConditionBuilder {
+	return KeyGreaterThanEqual(k, v)
+}
+
+// Between returns the key condition that k is between lower and upper inclusive.
+func (k KeyBuilder) Between(lower, upper ValueBuilder) KeyConditionBuilder {
+	return KeyBetween(k, lower, upper)
+}
+
+// BeginsWith returns the key condition that k begins with the prefix.
+func (k KeyBuilder) BeginsWith(prefix string) KeyConditionBuilder {
+	return KeyBeginsWith(k, prefix)
+}
@@ -0,0 +1,94 @@
+package expr
+
+// OperandBuilder is an operand of a condition: an attribute name, a value or the size of an
+// attribute.
+type OperandBuilder interface {
+	buildOperand(a *aliases) (string, error)
+}
+
+// NameBuilder is an attribute name or document path.
+type NameBuilder struct {
+	path     string
+	literal  bool
+	children []string
+}
+
+// Name returns an operand for an attribute name or document path. Nested attributes are separated
+// by dots and list elements are indexed with brackets, as in Address.Lines[0].
+func Name(path string) NameBuilder {
+	return NameBuilder{path: path}
+}
+
+// AttributeName returns an operand for a top level attribute whose name is used as it is, for
+// names containing dots or brackets.
+func AttributeName(name string) NameBuilder {
+	return NameBuilder{path: name, literal: true}
+}
+
+// Child returns the path of the attribute with the name in the map n. The name is used as it is,
+// as for AttributeName.
+func (n NameBuilder) Child(name string) NameBuilder {
+	n.children = append(n.children[:len(n.children):len(n.children)], name)
+	return n
+}
+
+func (n NameBuilder) buildOperand(a *aliases) (string, error) {
+	var s string
+	if n.literal {
+		if n.path == "" {
+			return "", ErrEmptyName
+		}
+		s = a.name(n.path)
+	} else {
+		var err error
+		if s, err = a.path(n.path); err != nil {
+			return "", err
+		}
+	}
+	for _, child := range n.children {
+		if child == "" {
+			return "", ErrEmptyName
+		}
+		s += "." + a.name(child)
+	}
+	return s, nil
+}
+
+// ValueBuilder is a value.
+type ValueBuilder struct {
+	value interface{}
+}
+
+// Value returns an operand for a value, marshalled with dynamodbattribute.Marshal unless it is a
+// *dynamodb.AttributeValue.
+func Value(v interface{}) ValueBuilder {
+	return ValueBuilder{value: v}
+}
+
+func (v ValueBuilder) buildOperand(a *aliases) (string, error) {
+	return a.value(v.value)
+}
+
+// SizeBuilder is the size of an attribute.
+type SizeBuilder struct {
+	name NameBuilder
+}
+
+// Size returns an operand for the size of an attribute: the length of a string, binary, list, map
+// or set.
+func Size(name NameBuilder) SizeBuilder {
+	return SizeBuilder{name: name}
+}
+
+// Size returns an operand for the size of the attribute.
+func (n NameBuilder) Size() SizeBuilder {
+	return Size(n)
+}
+
+func (s SizeBuilder) buildOperand(a *aliases) (string, error) {
+	name, err := s.name.buildOperand(a)
+	if err != nil {
+		return "", err
+	}
+	return "size(" + name + ")", nil
+}
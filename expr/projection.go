@@ -0,0 +1,178 @@
+package expr
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// ProjectionBuilder is a projection expression: the attributes a read returns. The zero
+// ProjectionBuilder has no attributes, and is an error if it is used.
+type ProjectionBuilder struct {
+	names []NameBuilder
+	err   error
+}
+
+// IsSet reports whether the projection has any attributes.
+func (p ProjectionBuilder) IsSet() bool {
+	return len(p.names) > 0 || p.err != nil
+}
+
+// NamesList returns the projection of the attributes.
+func NamesList(name NameBuilder, more ...NameBuilder) ProjectionBuilder {
+	return ProjectionBuilder{}.AddNames(append([]NameBuilder{name}, more...)...)
+}
+
+// AddNames returns the projection which also returns the attributes.
+func (p ProjectionBuilder) AddNames(names ...NameBuilder) ProjectionBuilder {
+	p.names = append(p.names[:len(p.names):len(p.names)], names...)
+	return p
+}
+
+// ProjectionFor returns the projection of the attributes v is unmarshalled from by
+// dynamodbattribute.UnmarshalMap, so reads only return the attributes the destination type uses.
+// v is a struct, a pointer to one or a slice of them, such as the out argument of QueryAll.
+//
+// Field names follow dynamodbattribute: the dynamodbav tag, or the json tag if there is none, names
+// the attribute, fields tagged "-" and unexported fields are omitted and the fields of embedded
+// structs are promoted. Nested structs are projected field by field as document paths. Maps,
+// slices, time.Time and types implementing dynamodbattribute.Unmarshaler are projected whole.
+func ProjectionFor(v interface{}) ProjectionBuilder {
+	t := reflect.TypeOf(v)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return ProjectionBuilder{err: ErrProjectionType}
+	}
+	return ProjectionBuilder{names: structNames(t, nil, map[reflect.Type]bool{})}
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	unmarshalerType = reflect.TypeOf((*dynamodbattribute.Unmarshaler)(nil)).Elem()
+)
+
+// structNames returns the paths of the attributes of the struct type t at the path. A nil path is
+// the item. seen holds the struct types being walked, so recursive types are projected whole.
+func structNames(t reflect.Type, path *NameBuilder, seen map[reflect.Type]bool) []NameBuilder {
+	seen[t] = true
+	defer delete(seen, t)
+	var names []NameBuilder
+	for _, f := range structFields(t) {
+		var name NameBuilder
+		if path == nil {
+			name = AttributeName(f.name)
+		} else {
+			name = path.Child(f.name)
+		}
+		ft := f.typ
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct || ft == timeType || seen[ft] ||
+			reflect.PtrTo(ft).Implements(unmarshalerType) || f.typ.Implements(unmarshalerType) {
+			names = append(names, name)
+			continue
+		}
+		// structs without attributes are still projected, as they may be stored as empty maps
+		if nested := structNames(ft, &name, seen); len(nested) > 0 {
+			names = append(names, nested...)
+		} else {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// field is an attribute of a struct.
+type field struct {
+	name string
+	typ  reflect.Type
+}
+
+// structFields returns the attributes of the struct type t in field order, followed by the
+// promoted fields of embedded structs. A field hides the fields of the same name embedded more
+// deeply.
+func structFields(t reflect.Type) []field {
+	var fields []field
+	seen := map[string]bool{}
+	level := []reflect.Type{t}
+	visited := map[reflect.Type]bool{}
+	for len(level) > 0 {
+		var next []reflect.Type
+		names := map[string]bool{}
+		for _, t := range level {
+			if visited[t] {
+				continue
+			}
+			visited[t] = true
+			for i := 0; i < t.NumField(); i++ {
+				sf := t.Field(i)
+				if sf.PkgPath != "" && !sf.Anonymous {
+					continue
+				}
+				name, ok := fieldName(sf)
+				if !ok {
+					continue
+				}
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				// embedded structs are always promoted, even if they are tagged
+				if sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, ft)
+					continue
+				}
+				if sf.PkgPath != "" {
+					continue
+				}
+				if name == "" {
+					name = sf.Name
+				}
+				if seen[name] {
+					continue
+				}
+				names[name] = true
+				fields = append(fields, field{name, sf.Type})
+			}
+		}
+		for name := range names {
+			seen[name] = true
+		}
+		level = next
+	}
+	return fields
+}
+
+// fieldName returns the attribute name of the field from its tags, empty if it is untagged, and
+// false if the field is omitted.
+func fieldName(sf reflect.StructField) (string, bool) {
+	tag, ok := sf.Tag.Lookup("dynamodbav")
+	if !ok || tag == "" {
+		tag = sf.Tag.Get("json")
+	}
+	name := strings.Split(tag, ",")[0]
+	return name, name != "-"
+}
+
+func (p ProjectionBuilder) build(a *aliases) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	if len(p.names) == 0 {
+		return "", ErrEmptyProjection
+	}
+	parts := make([]string, len(p.names))
+	for i, name := range p.names {
+		s, err := name.buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = s
+	}
+	return strings.Join(parts, ", "), nil
+}
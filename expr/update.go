@@ -0,0 +1,222 @@
+package expr
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Clauses of an update expression, in the order they are rendered.
+const (
+	setClause    = "SET"
+	removeClause = "REMOVE"
+	addClause    = "ADD"
+	deleteClause = "DELETE"
+)
+
+var clauses = []string{setClause, removeClause, addClause, deleteClause}
+
+// updateAction is an action of an update expression.
+type updateAction struct {
+	clause string
+	name   NameBuilder
+	value  OperandBuilder
+}
+
+// UpdateBuilder is an update expression. The zero UpdateBuilder has no actions, and is an error
+// if it is used. UpdateBuilders are values, so each method returns a new UpdateBuilder.
+type UpdateBuilder struct {
+	actions []updateAction
+}
+
+// IsSet reports whether the update has any actions.
+func (u UpdateBuilder) IsSet() bool {
+	return len(u.actions) > 0
+}
+
+func (u UpdateBuilder) with(clause string, name NameBuilder, value OperandBuilder) UpdateBuilder {
+	u.actions = append(u.actions[:len(u.actions):len(u.actions)], updateAction{clause, name, value})
+	return u
+}
+
+// Set returns an update which also sets the attribute to the value, which may be a Value, another
+// attribute or a value built with IfNotExists, ListAppend, Plus or Minus.
+func Set(name NameBuilder, value OperandBuilder) UpdateBuilder {
+	return UpdateBuilder{}.Set(name, value)
+}
+
+// Remove returns an update which also removes the attribute.
+func Remove(name NameBuilder) UpdateBuilder {
+	return UpdateBuilder{}.Remove(name)
+}
+
+// Add returns an update which also adds the value to a number attribute, or the elements of the
+// value to a set attribute. Attributes which do not exist are created.
+func Add(name NameBuilder, value ValueBuilder) UpdateBuilder {
+	return UpdateBuilder{}.Add(name, value)
+}
+
+// Delete returns an update which also deletes the elements of the value from a set attribute.
+func Delete(name NameBuilder, value ValueBuilder) UpdateBuilder {
+	return UpdateBuilder{}.Delete(name, value)
+}
+
+// Set returns an update which also sets the attribute to the value.
+func (u UpdateBuilder) Set(name NameBuilder, value OperandBuilder) UpdateBuilder {
+	return u.with(setClause, name, value)
+}
+
+// Remove returns an update which also removes the attribute.
+func (u UpdateBuilder) Remove(name NameBuilder) UpdateBuilder {
+	return u.with(removeClause, name, nil)
+}
+
+// Add returns an update which also adds the value to a number or set attribute.
+func (u UpdateBuilder) Add(name NameBuilder, value ValueBuilder) UpdateBuilder {
+	return u.with(addClause, name, value)
+}
+
+// Delete returns an update which also deletes the elements of the value from a set attribute.
+func (u UpdateBuilder) Delete(name NameBuilder, value ValueBuilder) UpdateBuilder {
+	return u.with(deleteClause, name, value)
+}
+
+func (u UpdateBuilder) build(a *aliases) (string, error) {
+	if len(u.actions) == 0 {
+		return "", ErrEmptyUpdate
+	}
+	actions := make(map[string][]string)
+	for _, action := range u.actions {
+		name, err := action.name.buildOperand(a)
+		if err != nil {
+			return "", err
+		}
+		if action.clause != removeClause && action.value == nil {
+			return "", ErrNilOperand
+		}
+		var s string
+		switch action.clause {
+		case setClause:
+			value, err := action.value.buildOperand(a)
+			if err != nil {
+				return "", err
+			}
+			s = name + " = " + value
+		case removeClause:
+			s = name
+		default:
+			value, err := action.value.buildOperand(a)
+			if err != nil {
+				return "", err
+			}
+			s = name + " " + value
+		}
+		actions[action.clause] = append(actions[action.clause], s)
+	}
+	var parts []string
+	for _, clause := range clauses {
+		if len(actions[clause]) > 0 {
+			parts = append(parts, clause+" "+strings.Join(actions[clause], ", "))
+		}
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// SetValueBuilder is a value computed by a SET action.
+type SetValueBuilder struct {
+	build func(a *aliases) (string, error)
+}
+
+func (s SetValueBuilder) buildOperand(a *aliases) (string, error) {
+	return s.build(a)
+}
+
+func setValue(f string, operands ...OperandBuilder) SetValueBuilder {
+	return SetValueBuilder{format(f, operands...).build}
+}
+
+// IfNotExists returns the value of the attribute if it exists, and value otherwise. Setting an
+// attribute to IfNotExists of itself only sets it when it does not exist.
+func IfNotExists(name NameBuilder, value OperandBuilder) SetValueBuilder {
+	return setValue("if_not_exists(%s, %s)", name, value)
+}
+
+// ListAppend returns the concatenation of two lists.
+func ListAppend(l, r OperandBuilder) SetValueBuilder {
+	return setValue("list_append(%s, %s)", l, r)
+}
+
+// Plus returns the sum of two numbers.
+func Plus(l, r OperandBuilder) SetValueBuilder {
+	return setValue("%s + %s", l, r)
+}
+
+// Minus returns the difference of two numbers.
+func Minus(l, r OperandBuilder) SetValueBuilder {
+	return setValue("%s - %s", l, r)
+}
+
+// IfNotExists returns the value of the attribute if it exists, and value otherwise.
+func (n NameBuilder) IfNotExists(value OperandBuilder) SetValueBuilder {
+	return IfNotExists(n, value)
+}
+
+// ListAppend returns the list n with the elements of list r appended.
+func (n NameBuilder) ListAppend(r OperandBuilder) SetValueBuilder {
+	return ListAppend(n, r)
+}
+
+// Plus returns the sum of the number n and r.
+func (n NameBuilder) Plus(r OperandBuilder) SetValueBuilder {
+	return Plus(n, r)
+}
+
+// Minus returns the number n less r.
+func (n NameBuilder) Minus(r OperandBuilder) SetValueBuilder {
+	return Minus(n, r)
+}
+
+// Diff returns an update which changes the item old into new: it sets the attributes which differ
+// and removes the attributes new does not have. Maps in both items are compared attribute by
+// attribute, so only the nested attributes which differ are set, other values are set whole. The
+// update has no actions if the items are equal.
+func Diff(old, new map[string]*dynamodb.AttributeValue) UpdateBuilder {
+	return diff(UpdateBuilder{}, nil, old, new)
+}
+
+// diff adds the actions changing the map old into new at the path to u. A nil path is the item.
+func diff(u UpdateBuilder, path *NameBuilder, old, new map[string]*dynamodb.AttributeValue) UpdateBuilder {
+	child := func(name string) NameBuilder {
+		if path == nil {
+			return AttributeName(name)
+		}
+		return path.Child(name)
+	}
+	for _, name := range sortedNames(new) {
+		o, n := old[name], new[name]
+		switch {
+		case o != nil && o.M != nil && n.M != nil:
+			p := child(name)
+			u = diff(u, &p, o.M, n.M)
+		case !reflect.DeepEqual(o, n):
+			u = u.Set(child(name), Value(n))
+		}
+	}
+	for _, name := range sortedNames(old) {
+		if _, ok := new[name]; !ok {
+			u = u.Remove(child(name))
+		}
+	}
+	return u
+}
+
+func sortedNames(m map[string]*dynamodb.AttributeValue) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
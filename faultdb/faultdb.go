@@ -0,0 +1,292 @@
+// Package faultdb wraps a dynamodb client to inject the faults dynamodb produces under load:
+// unprocessed batch items and keys, throttling, latency and transient server errors. Faults are
+// chosen with a seeded random number generator so tests of retry and backoff code are repeatable.
+//
+// The wrapped client can be any *dynamodb.DynamoDB, usually one returned by memdb.NewClient:
+//
+//	faults := faultdb.New(faultdb.Config{Seed: 1, UnprocessedRate: 0.3})
+//	ddb := faults.Wrap(memdb.NewClient())
+//
+// Faults are only repeatable when requests are made in a deterministic order.
+package faultdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Config sets the probability of each fault. Rates are between 0 and 1.
+type Config struct {
+	// Seed seeds the random number generator which chooses faults.
+	Seed int64
+	// UnprocessedRate is the probability each item of a BatchWriteItem or key of a BatchGetItem is
+	// held back and returned in UnprocessedItems or UnprocessedKeys. At least one item or key of
+	// each request is always processed, as dynamodb fails the whole request when it can process
+	// none of it.
+	UnprocessedRate float64
+	// ThrottleRate is the probability a request fails with ProvisionedThroughputExceededException.
+	ThrottleRate float64
+	// ServerErrorRate is the probability a request fails with a 500 InternalServerError.
+	ServerErrorRate float64
+	// Latency is added to every request, plus a random duration up to LatencyJitter.
+	Latency       time.Duration
+	LatencyJitter time.Duration
+	// Operations limits faults to the named operations, such as "BatchWriteItem". All operations
+	// are affected if it is empty.
+	Operations []string
+}
+
+// Stats counts the requests seen and the faults injected.
+type Stats struct {
+	Requests         int
+	Throttled        int
+	ServerErrors     int
+	UnprocessedItems int
+	UnprocessedKeys  int
+}
+
+// Injector injects faults into the requests of the clients it wraps. It is safe for concurrent
+// use.
+type Injector struct {
+	cfg   Config
+	mu    sync.Mutex
+	rng   *rand.Rand
+	stats Stats
+}
+
+// New returns an Injector for the config.
+func New(cfg Config) *Injector {
+	return &Injector{
+		cfg: cfg,
+		rng: rand.New(rand.NewSource(cfg.Seed)),
+	}
+}
+
+// Wrap returns a copy of the client whose requests are subject to faults. The client passed in
+// is not modified.
+func (i *Injector) Wrap(client *dynamodb.DynamoDB) *dynamodb.DynamoDB {
+	c := *client.Client
+	c.Handlers = client.Handlers.Copy()
+	// Unprocessed items are held back before the request is built so they are never sent
+	c.Handlers.Build.PushFrontNamed(request.NamedHandler{Name: "faultdb.Unprocessed", Fn: i.unprocessed})
+	c.Handlers.Send.PushFrontNamed(request.NamedHandler{Name: "faultdb.Send", Fn: i.send})
+	c.Handlers.Send.AfterEachFn = request.HandlerListStopOnError
+	return &dynamodb.DynamoDB{Client: &c}
+}
+
+// Stats returns the counts of requests and faults so far.
+func (i *Injector) Stats() Stats {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.stats
+}
+
+// Reset clears the stats and reseeds the random number generator.
+func (i *Injector) Reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stats = Stats{}
+	i.rng = rand.New(rand.NewSource(i.cfg.Seed))
+}
+
+func (i *Injector) applies(r *request.Request) bool {
+	if len(i.cfg.Operations) == 0 {
+		return true
+	}
+	for _, op := range i.cfg.Operations {
+		if op == r.Operation.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// chance returns true with the given probability. i.mu must be held.
+func (i *Injector) chance(rate float64) bool {
+	return rate > 0 && i.rng.Float64() < rate
+}
+
+func (i *Injector) send(r *request.Request) {
+	if !i.applies(r) {
+		return
+	}
+	i.mu.Lock()
+	i.stats.Requests++
+	delay := i.cfg.Latency
+	if i.cfg.LatencyJitter > 0 {
+		delay += time.Duration(i.rng.Int63n(int64(i.cfg.LatencyJitter)))
+	}
+	var code, message string
+	status := http.StatusBadRequest
+	switch {
+	case i.chance(i.cfg.ThrottleRate):
+		i.stats.Throttled++
+		code = dynamodb.ErrCodeProvisionedThroughputExceededException
+		message = "The level of configured provisioned throughput for the table was exceeded. Consider increasing your provisioning level with the UpdateTable API."
+	case i.chance(i.cfg.ServerErrorRate):
+		i.stats.ServerErrors++
+		code = dynamodb.ErrCodeInternalServerError
+		message = "Internal server error"
+		status = http.StatusInternalServerError
+	}
+	i.mu.Unlock()
+
+	if delay > 0 {
+		if err := aws.SleepWithContext(r.Context(), delay); err != nil {
+			r.Error = awserr.New(request.CanceledErrorCode, "request context canceled", err)
+			r.Retryable = aws.Bool(false)
+			return
+		}
+	}
+	if code == "" {
+		return
+	}
+	r.HTTPResponse = &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}
+	r.Error = awserr.NewRequestFailure(awserr.New(code, message, nil), status, "")
+}
+
+// unprocessed replaces the input of batch requests with a copy holding back some of the items or
+// keys, and adds them to the output once the request succeeds.
+func (i *Injector) unprocessed(r *request.Request) {
+	if i.cfg.UnprocessedRate <= 0 || !i.applies(r) {
+		return
+	}
+	switch in := r.Params.(type) {
+	case *dynamodb.BatchWriteItemInput:
+		send, held := i.splitWrites(in.RequestItems)
+		if len(held) == 0 {
+			return
+		}
+		sent := *in
+		sent.RequestItems = send
+		r.Params = &sent
+		r.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+			out := r.Data.(*dynamodb.BatchWriteItemOutput)
+			if out.UnprocessedItems == nil {
+				out.UnprocessedItems = make(map[string][]*dynamodb.WriteRequest)
+			}
+			for table, reqs := range held {
+				out.UnprocessedItems[table] = append(out.UnprocessedItems[table], reqs...)
+			}
+		})
+	case *dynamodb.BatchGetItemInput:
+		send, held := i.splitKeys(in.RequestItems)
+		if len(held) == 0 {
+			return
+		}
+		sent := *in
+		sent.RequestItems = send
+		r.Params = &sent
+		r.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+			out := r.Data.(*dynamodb.BatchGetItemOutput)
+			if out.UnprocessedKeys == nil {
+				out.UnprocessedKeys = make(map[string]*dynamodb.KeysAndAttributes)
+			}
+			for table, ka := range held {
+				if u, ok := out.UnprocessedKeys[table]; ok {
+					u.Keys = append(u.Keys, ka.Keys...)
+					continue
+				}
+				out.UnprocessedKeys[table] = ka
+			}
+		})
+	}
+}
+
+func (i *Injector) splitWrites(items map[string][]*dynamodb.WriteRequest) (send, held map[string][]*dynamodb.WriteRequest) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	send = make(map[string][]*dynamodb.WriteRequest)
+	held = make(map[string][]*dynamodb.WriteRequest)
+	total, n := 0, 0
+	for _, table := range sortedKeys(items) {
+		for _, req := range items[table] {
+			total++
+			if i.chance(i.cfg.UnprocessedRate) {
+				held[table] = append(held[table], req)
+				n++
+				continue
+			}
+			send[table] = append(send[table], req)
+		}
+	}
+	if n > 0 && n == total {
+		// Keep the first item so the request is not empty
+		table := sortedKeys(held)[0]
+		send[table] = held[table][:1]
+		if held[table] = held[table][1:]; len(held[table]) == 0 {
+			delete(held, table)
+		}
+		n--
+	}
+	i.stats.UnprocessedItems += n
+	return send, held
+}
+
+func (i *Injector) splitKeys(items map[string]*dynamodb.KeysAndAttributes) (send, held map[string]*dynamodb.KeysAndAttributes) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	send = make(map[string]*dynamodb.KeysAndAttributes)
+	held = make(map[string]*dynamodb.KeysAndAttributes)
+	total, n := 0, 0
+	split := func(m map[string]*dynamodb.KeysAndAttributes, table string, key map[string]*dynamodb.AttributeValue) {
+		if _, ok := m[table]; !ok {
+			ka := *items[table]
+			ka.Keys = nil
+			m[table] = &ka
+		}
+		m[table].Keys = append(m[table].Keys, key)
+	}
+	for _, table := range sortedKeys(items) {
+		for _, key := range items[table].Keys {
+			total++
+			if i.chance(i.cfg.UnprocessedRate) {
+				split(held, table, key)
+				n++
+				continue
+			}
+			split(send, table, key)
+		}
+	}
+	if n > 0 && n == total {
+		table := sortedKeys(held)[0]
+		split(send, table, held[table].Keys[0])
+		if held[table].Keys = held[table].Keys[1:]; len(held[table].Keys) == 0 {
+			delete(held, table)
+		}
+		n--
+	}
+	i.stats.UnprocessedKeys += n
+	return send, held
+}
+
+// sortedKeys returns the keys of a map of tables in order so faults do not depend on map order.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string][]*dynamodb.WriteRequest:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*dynamodb.KeysAndAttributes:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
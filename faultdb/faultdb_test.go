@@ -0,0 +1,191 @@
+package faultdb_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx/faultdb"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func newTable(t *testing.T, ddb *dynamodb.DynamoDB) {
+	t.Helper()
+	_, err := ddb.CreateTable(&dynamodb.CreateTableInput{
+		TableName:            aws.String("test"),
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("P"), AttributeType: aws.String("S")}},
+		KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("P"), KeyType: aws.String("HASH")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func key(i int) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{"P": {S: aws.String(strconv.Itoa(i))}}
+}
+
+func writes(n int) map[string][]*dynamodb.WriteRequest {
+	reqs := make([]*dynamodb.WriteRequest, n)
+	for i := range reqs {
+		reqs[i] = &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: key(i)}}
+	}
+	return map[string][]*dynamodb.WriteRequest{"test": reqs}
+}
+
+func errCode(err error) string {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
+	}
+	return ""
+}
+
+func TestUnprocessedItems(t *testing.T) {
+	t.Parallel()
+	var first []*dynamodb.WriteRequest
+	for run := 0; run < 2; run++ {
+		mem := memdb.NewClient()
+		newTable(t, mem)
+		faults := faultdb.New(faultdb.Config{Seed: 1, UnprocessedRate: 0.5})
+		ddb := faults.Wrap(mem)
+		out, err := ddb.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: writes(25)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		unprocessed := out.UnprocessedItems["test"]
+		if len(unprocessed) == 0 || len(unprocessed) == 25 {
+			t.Fatalf("expected some items unprocessed, got %d", len(unprocessed))
+		}
+		if got := faults.Stats().UnprocessedItems; got != len(unprocessed) {
+			t.Fatalf("expected %d unprocessed items counted, got %d", len(unprocessed), got)
+		}
+		scan, err := mem.Scan(&dynamodb.ScanInput{TableName: aws.String("test")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := int(aws.Int64Value(scan.Count)); got != 25-len(unprocessed) {
+			t.Fatalf("expected %d items written, got %d", 25-len(unprocessed), got)
+		}
+		if run == 0 {
+			first = unprocessed
+			continue
+		}
+		if !reflect.DeepEqual(unprocessed, first) {
+			t.Fatal(pretty.Compare(unprocessed, first))
+		}
+	}
+}
+
+func TestUnprocessedKeys(t *testing.T) {
+	t.Parallel()
+	mem := memdb.NewClient()
+	newTable(t, mem)
+	if _, err := mem.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: writes(10)}); err != nil {
+		t.Fatal(err)
+	}
+	faults := faultdb.New(faultdb.Config{Seed: 2, UnprocessedRate: 0.5})
+	ddb := faults.Wrap(mem)
+	keys := make([]map[string]*dynamodb.AttributeValue, 10)
+	for i := range keys {
+		keys[i] = key(i)
+	}
+	out, err := ddb.BatchGetItem(&dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{"test": {Keys: keys, ConsistentRead: aws.Bool(true)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unprocessed := out.UnprocessedKeys["test"]
+	if unprocessed == nil || len(unprocessed.Keys) == 0 {
+		t.Fatal("expected some keys unprocessed")
+	}
+	if !aws.BoolValue(unprocessed.ConsistentRead) {
+		t.Fatal("expected unprocessed keys to keep ConsistentRead")
+	}
+	if got := len(out.Responses["test"]) + len(unprocessed.Keys); got != 10 {
+		t.Fatalf("expected 10 keys returned or unprocessed, got %d", got)
+	}
+	if got := faults.Stats().UnprocessedKeys; got != len(unprocessed.Keys) {
+		t.Fatalf("expected %d unprocessed keys counted, got %d", len(unprocessed.Keys), got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name string
+		cfg  faultdb.Config
+		code string
+	}{
+		{
+			name: "throttled",
+			cfg:  faultdb.Config{ThrottleRate: 1},
+			code: dynamodb.ErrCodeProvisionedThroughputExceededException,
+		},
+		{
+			name: "server error",
+			cfg:  faultdb.Config{ServerErrorRate: 1},
+			code: dynamodb.ErrCodeInternalServerError,
+		},
+		{
+			name: "other operation",
+			cfg:  faultdb.Config{ThrottleRate: 1, Operations: []string{"GetItem"}},
+		},
+		{
+			name: "no faults",
+			cfg:  faultdb.Config{},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mem := memdb.NewClient(&aws.Config{MaxRetries: aws.Int(0)})
+			newTable(t, mem)
+			ddb := faultdb.New(tc.cfg).Wrap(mem)
+			_, err := ddb.PutItem(&dynamodb.PutItemInput{TableName: aws.String("test"), Item: key(1)})
+			if code := errCode(err); code != tc.code {
+				t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.code)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	t.Parallel()
+	mem := memdb.NewClient()
+	newTable(t, mem)
+	faults := faultdb.New(faultdb.Config{Seed: 3, ThrottleRate: 0.3, ServerErrorRate: 0.2})
+	ddb := faults.Wrap(mem)
+	for i := 0; i < 20; i++ {
+		if _, err := ddb.PutItem(&dynamodb.PutItemInput{TableName: aws.String("test"), Item: key(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats := faults.Stats()
+	if stats.Throttled == 0 || stats.ServerErrors == 0 {
+		t.Fatalf("expected throttles and server errors, got %+v", stats)
+	}
+	if stats.Requests != 20+stats.Throttled+stats.ServerErrors {
+		t.Fatalf("expected every fault to be retried, got %+v", stats)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	t.Parallel()
+	mem := memdb.NewClient()
+	newTable(t, mem)
+	ddb := faultdb.New(faultdb.Config{Latency: 20 * time.Millisecond}).Wrap(mem)
+	start := time.Now()
+	if _, err := ddb.GetItem(&dynamodb.GetItemInput{TableName: aws.String("test"), Key: key(1)}); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Fatalf("expected at least 20ms latency, got %v", d)
+	}
+}
@@ -0,0 +1,510 @@
+package dynamodbx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrImportTableName   = errors.New("dynamodbx/ImportTable: table name cannot be empty")
+	ErrImportFormat      = errors.New("dynamodbx/ImportTable: unknown format")
+	ErrImportItemSize    = errors.New("dynamodbx/ImportTable: item exceeds the 400KB item size limit")
+	ErrImportMissingKey  = errors.New("dynamodbx/ImportTable: item is missing a key attribute or it has the wrong type")
+	ErrImportColumnType  = errors.New("dynamodbx/ImportTable: unknown CSV column type")
+	ErrImportUnprocessed = errors.New("dynamodbx/ImportTable: item was not processed by BatchWriteItem")
+)
+
+// FormatCSV reads items from CSV with a header row naming the attribute of each column. Column
+// types are set with ImportInput.CSVColumnTypes.
+const FormatCSV Format = "CSV"
+
+const (
+	// maxItemSize is the largest item dynamodb accepts
+	maxItemSize = 400 * 1024
+	// maxRowErrors is the number of row errors kept in ImportOutput
+	maxRowErrors = 100
+)
+
+// ImportInput describes the data to import and the table it is written to.
+type ImportInput struct {
+	TableName string
+	// Format defaults to FormatDynamoDBJSON.
+	Format Format
+	// Gzip decompresses the input.
+	Gzip bool
+	// CSVColumnTypes maps CSV column names to the attribute type of their values, one of S, N, B or
+	// BOOL. Columns default to S. Empty cells are left out of the item.
+	CSVColumnTypes map[string]string
+	// Validate, if set, is called with every parsed item. Items for which it returns an error are
+	// rejected.
+	Validate func(item map[string]*dynamodb.AttributeValue) error
+	// Concurrency is the number of batches written at once. Defaults to 4.
+	Concurrency int
+	// WritesPerSecond limits how many items are written per second. Zero is unlimited.
+	WritesPerSecond int
+	// Rejected, if set, receives every row which could not be parsed, failed validation or could not
+	// be written, in the same format as the input so it can be fixed and imported again.
+	Rejected io.Writer
+}
+
+// RowError records why a row was rejected. Line is the 1 based line or CSV record number.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// ImportOutput reports the result of an import. Errors holds the first 100 row errors.
+type ImportOutput struct {
+	Imported         int64
+	Rejected         int64
+	Errors           []*RowError
+	ConsumedCapacity []*dynamodb.ConsumedCapacity
+}
+
+// importRow is a parsed item along with the raw line or CSV record it came from.
+type importRow struct {
+	line   int
+	raw    []byte
+	record []string
+	item   map[string]*dynamodb.AttributeValue
+}
+
+// ImportTable reads items from r and writes them to a table with BatchWriteItem. Each item is
+// checked for the table's key attributes and the item size limit before it is written. Rows which
+// are rejected are counted and written to ImportInput.Rejected.
+//
+// BatchWriteItem refuses a batch holding two items with the same key, so a row with the key of an
+// earlier row in the batch starts a new batch. Batches are written concurrently, so which of the
+// rows is stored last is only certain with a Concurrency of 1. The rows of a batch dynamodb
+// refuses are rejected with its error; the import carries on if the batch failed validation, and
+// stops otherwise.
+func ImportTable(client *dynamodb.DynamoDB, r io.Reader, input *ImportInput) (*ImportOutput, error) {
+	return ImportTableWithContext(context.Background(), client, r, input)
+}
+
+// ImportTableWithContext is the same as ImportTable.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func ImportTableWithContext(ctx context.Context, client *dynamodb.DynamoDB, r io.Reader, input *ImportInput, opts ...request.Option) (*ImportOutput, error) {
+	if input.TableName == "" {
+		return nil, ErrImportTableName
+	}
+	format := input.Format
+	if format == "" {
+		format = FormatDynamoDBJSON
+	}
+	switch format {
+	case FormatDynamoDBJSON, FormatJSON, FormatCSV:
+	default:
+		return nil, ErrImportFormat
+	}
+	for _, t := range input.CSVColumnTypes {
+		switch t {
+		case dynamodb.ScalarAttributeTypeS, dynamodb.ScalarAttributeTypeN, dynamodb.ScalarAttributeTypeB, "BOOL":
+		default:
+			return nil, ErrImportColumnType
+		}
+	}
+	desc, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(input.TableName)}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	keyTypes := make(map[string]string)
+	for _, a := range desc.Table.AttributeDefinitions {
+		keyTypes[aws.StringValue(a.AttributeName)] = aws.StringValue(a.AttributeType)
+	}
+	keys := make(map[string]string, len(desc.Table.KeySchema))
+	for _, k := range desc.Table.KeySchema {
+		keys[aws.StringValue(k.AttributeName)] = keyTypes[aws.StringValue(k.AttributeName)]
+	}
+
+	if input.Gzip {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		out      = &ImportOutput{}
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+	var rejectCSV *csv.Writer
+	if format == FormatCSV && input.Rejected != nil {
+		rejectCSV = csv.NewWriter(input.Rejected)
+	}
+	reject := func(row *importRow, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		out.Rejected++
+		if len(out.Errors) < maxRowErrors {
+			out.Errors = append(out.Errors, &RowError{Line: row.line, Err: err})
+		}
+		if input.Rejected == nil || firstErr != nil || (row.raw == nil && row.record == nil) {
+			return
+		}
+		var werr error
+		if rejectCSV != nil {
+			werr = rejectCSV.Write(row.record)
+			rejectCSV.Flush()
+			if werr == nil {
+				werr = rejectCSV.Error()
+			}
+		} else {
+			_, werr = input.Rejected.Write(append(row.raw, '\n'))
+		}
+		if werr != nil && firstErr == nil {
+			firstErr = werr
+			cancel()
+		}
+	}
+
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	limiter := newRateLimiter(float64(input.WritesPerSecond))
+	batches := make(chan []*importRow)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				if err := limiter.wait(ctx, float64(len(batch))); err != nil {
+					fail(err)
+					continue
+				}
+				// Unprocessed items are matched to their rows by key, as over HTTP they are decoded
+				// from the response rather than being the requests that were sent
+				rows := make(map[string]*importRow, len(batch))
+				reqs := make([]*dynamodb.WriteRequest, 0, len(batch))
+				for _, row := range batch {
+					rows[importKey(row.item, keys)] = row
+					reqs = append(reqs, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: row.item}})
+				}
+				res, err := batchWriteItem(ctx, client, &dynamodb.BatchWriteItemInput{
+					ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+					RequestItems:           map[string][]*dynamodb.WriteRequest{input.TableName: reqs},
+				}, opts...)
+				if err != nil {
+					for _, row := range batch {
+						reject(row, err)
+					}
+					if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != "ValidationException" {
+						fail(err)
+					}
+					continue
+				}
+				unprocessed := res.UnprocessedItems[input.TableName]
+				for _, req := range unprocessed {
+					row, ok := rows[importKey(req.PutRequest.Item, keys)]
+					if !ok {
+						row = &importRow{}
+					}
+					reject(row, ErrImportUnprocessed)
+				}
+				mu.Lock()
+				out.Imported += int64(len(reqs) - len(unprocessed))
+				out.ConsumedCapacity = append(out.ConsumedCapacity, res.ConsumedCapacity...)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var pending []*importRow
+	pendingKeys := make(map[string]bool)
+	send := func() bool {
+		if len(pending) == 0 {
+			return true
+		}
+		select {
+		case batches <- pending:
+			pending = nil
+			pendingKeys = make(map[string]bool)
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	readErr := readImportRows(r, format, input.CSVColumnTypes, rejectCSV, func(row *importRow, err error) bool {
+		if err == nil {
+			err = validateImportItem(row.item, keys)
+		}
+		if err == nil && input.Validate != nil {
+			err = input.Validate(row.item)
+		}
+		if err != nil {
+			reject(row, err)
+			return true
+		}
+		key := importKey(row.item, keys)
+		if pendingKeys[key] && !send() {
+			return false
+		}
+		pendingKeys[key] = true
+		pending = append(pending, row)
+		if len(pending) < batchWriteSize {
+			return true
+		}
+		return send()
+	})
+	send()
+	close(batches)
+	wg.Wait()
+	if readErr != nil && firstErr == nil {
+		firstErr = readErr
+	}
+	out.ConsumedCapacity = sumConsumedCapacity(out.ConsumedCapacity)
+	return out, firstErr
+}
+
+// importKey encodes the key attributes of an item, which identify it within a batch.
+func importKey(item map[string]*dynamodb.AttributeValue, keys map[string]string) string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		v := item[name]
+		if v == nil {
+			v = &dynamodb.AttributeValue{}
+		}
+		b.WriteString(strconv.Quote(name))
+		b.WriteString(strconv.Quote(aws.StringValue(v.S)))
+		b.WriteString(strconv.Quote(aws.StringValue(v.N)))
+		b.WriteString(strconv.Quote(string(v.B)))
+	}
+	return b.String()
+}
+
+// readImportRows parses r calling fn with every row, or with the error parsing it. Reading stops
+// when fn returns false. Only errors reading r are returned.
+func readImportRows(r io.Reader, format Format, columnTypes map[string]string, rejectCSV *csv.Writer, fn func(*importRow, error) bool) error {
+	if format == FormatCSV {
+		return readImportCSV(r, columnTypes, rejectCSV, fn)
+	}
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		raw, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		raw = bytes.TrimRight(raw, "\r\n")
+		if len(bytes.TrimSpace(raw)) > 0 {
+			row := &importRow{line: line, raw: raw}
+			var perr error
+			if format == FormatDynamoDBJSON {
+				row.item, perr = UnmarshalDynamoDBJSON(raw)
+			} else {
+				row.item, perr = UnmarshalPlainJSON(raw)
+			}
+			if !fn(row, perr) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func readImportCSV(r io.Reader, columnTypes map[string]string, rejectCSV *csv.Writer, fn func(*importRow, error) bool) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if rejectCSV != nil {
+		if err := rejectCSV.Write(header); err != nil {
+			return err
+		}
+		rejectCSV.Flush()
+	}
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return err
+			}
+		}
+		row := &importRow{line: line, record: record}
+		if err == nil {
+			row.item, err = csvItem(header, record, columnTypes)
+		}
+		if !fn(row, err) {
+			return nil
+		}
+	}
+}
+
+// csvItem converts a CSV record to an item using the header for attribute names.
+func csvItem(header, record []string, columnTypes map[string]string) (map[string]*dynamodb.AttributeValue, error) {
+	if len(record) != len(header) {
+		return nil, fmt.Errorf("dynamodbx/ImportTable: expected %d fields, got %d", len(header), len(record))
+	}
+	item := make(map[string]*dynamodb.AttributeValue, len(header))
+	for i, name := range header {
+		v := record[i]
+		if v == "" {
+			continue
+		}
+		switch columnTypes[name] {
+		case "", dynamodb.ScalarAttributeTypeS:
+			item[name] = &dynamodb.AttributeValue{S: aws.String(v)}
+		case dynamodb.ScalarAttributeTypeN:
+			if _, ok := new(big.Float).SetString(v); !ok {
+				return nil, fmt.Errorf("dynamodbx/ImportTable: column %s: %q is not a number", name, v)
+			}
+			item[name] = &dynamodb.AttributeValue{N: aws.String(v)}
+		case dynamodb.ScalarAttributeTypeB:
+			b, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("dynamodbx/ImportTable: column %s: %v", name, err)
+			}
+			item[name] = &dynamodb.AttributeValue{B: b}
+		case "BOOL":
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("dynamodbx/ImportTable: column %s: %v", name, err)
+			}
+			item[name] = &dynamodb.AttributeValue{BOOL: aws.Bool(b)}
+		}
+	}
+	return item, nil
+}
+
+// validateImportItem checks the item has every key attribute with the right type and fits in the
+// item size limit.
+func validateImportItem(item map[string]*dynamodb.AttributeValue, keys map[string]string) error {
+	for name, t := range keys {
+		v, ok := item[name]
+		if !ok {
+			return ErrImportMissingKey
+		}
+		switch t {
+		case dynamodb.ScalarAttributeTypeS:
+			ok = v.S != nil && *v.S != ""
+		case dynamodb.ScalarAttributeTypeN:
+			ok = v.N != nil
+		case dynamodb.ScalarAttributeTypeB:
+			ok = len(v.B) > 0
+		}
+		if !ok {
+			return ErrImportMissingKey
+		}
+	}
+	if ItemSize(item) > maxItemSize {
+		return ErrImportItemSize
+	}
+	return nil
+}
+
+// ItemSize estimates the size of an item in bytes as dynamodb counts it against the 400KB item size
+// limit: the length of every attribute name plus the size of every value.
+func ItemSize(item map[string]*dynamodb.AttributeValue) int {
+	size := 0
+	for k, v := range item {
+		size += len(k) + attributeValueSize(v)
+	}
+	return size
+}
+
+func attributeValueSize(v *dynamodb.AttributeValue) int {
+	switch {
+	case v == nil:
+		return 0
+	case v.S != nil:
+		return len(*v.S)
+	case v.N != nil:
+		return numberSize(*v.N)
+	case v.B != nil:
+		return len(v.B)
+	case v.BOOL != nil, v.NULL != nil:
+		return 1
+	case v.SS != nil:
+		size := 0
+		for _, s := range v.SS {
+			size += len(aws.StringValue(s))
+		}
+		return size
+	case v.NS != nil:
+		size := 0
+		for _, n := range v.NS {
+			size += numberSize(aws.StringValue(n))
+		}
+		return size
+	case v.BS != nil:
+		size := 0
+		for _, b := range v.BS {
+			size += len(b)
+		}
+		return size
+	case v.L != nil:
+		size := 3
+		for _, e := range v.L {
+			size += 1 + attributeValueSize(e)
+		}
+		return size
+	case v.M != nil:
+		size := 3
+		for k, e := range v.M {
+			size += 1 + len(k) + attributeValueSize(e)
+		}
+		return size
+	}
+	return 0
+}
+
+// numberSize approximates the size of a number, one byte per two significant digits plus one.
+func numberSize(n string) int {
+	digits := 0
+	for _, r := range strings.TrimLeft(strings.TrimLeft(n, "-+"), "0.") {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return (digits+1)/2 + 1
+}
@@ -0,0 +1,342 @@
+package dynamodbx_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func TestImportTable(t *testing.T) {
+	t.Parallel()
+	gzipped := func(s string) string {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(s))
+		gz.Close()
+		return buf.String()
+	}
+	for _, tc := range []struct {
+		name     string
+		input    *dynamodbx.ImportInput
+		data     string
+		imported int64
+		rejected string
+		err      error
+	}{
+		{
+			name:  "empty table name",
+			input: &dynamodbx.ImportInput{},
+			err:   dynamodbx.ErrImportTableName,
+		},
+		{
+			name:  "unknown format",
+			input: &dynamodbx.ImportInput{TableName: "test", Format: "XML"},
+			err:   dynamodbx.ErrImportFormat,
+		},
+		{
+			name:  "unknown column type",
+			input: &dynamodbx.ImportInput{TableName: "test", Format: dynamodbx.FormatCSV, CSVColumnTypes: map[string]string{"S": "SS"}},
+			err:   dynamodbx.ErrImportColumnType,
+		},
+		{
+			name:  "dynamodb json",
+			input: &dynamodbx.ImportInput{TableName: "testImportDynamoDBJSON"},
+			data: `{"S":{"S":"a"},"N":{"N":"1"}}
+{"S":{"S":"b"},"N":{"N":"2"}}
+
+{"N":{"N":"3"}}
+not json
+`,
+			imported: 2,
+			rejected: "{\"N\":{\"N\":\"3\"}}\nnot json\n",
+		},
+		{
+			name:     "gzipped plain json",
+			input:    &dynamodbx.ImportInput{TableName: "testImportJSON", Format: dynamodbx.FormatJSON, Gzip: true},
+			data:     gzipped(`{"S":"a","N":1}` + "\n" + `{"S":"b","N":2.5,"L":[1,"x"]}` + "\n" + `{"S":1}`),
+			imported: 2,
+			rejected: "{\"S\":1}\n",
+		},
+		{
+			name: "csv",
+			input: &dynamodbx.ImportInput{
+				TableName:      "testImportCSV",
+				Format:         dynamodbx.FormatCSV,
+				CSVColumnTypes: map[string]string{"N": "N", "BOOL": "BOOL"},
+				Concurrency:    2,
+			},
+			data:     "S,N,BOOL\na,1,true\nb,,false\nc,x,true\n,4,true\n",
+			imported: 2,
+			rejected: "S,N,BOOL\nc,x,true\n,4,true\n",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ddb := memdb.NewClient()
+			var rejected bytes.Buffer
+			tc.input.Rejected = &rejected
+			if tc.err != nil {
+				if _, err := dynamodbx.ImportTable(ddb, strings.NewReader(tc.data), tc.input); err != tc.err {
+					t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			_, err := dynamodbx.CreateTableSync(ddb, &dynamodb.CreateTableInput{
+				TableName:   aws.String(tc.input.TableName),
+				BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{
+					{
+						AttributeName: aws.String("S"),
+						AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+					},
+				},
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{
+						AttributeName: aws.String("S"),
+						KeyType:       aws.String(dynamodb.KeyTypeHash),
+					},
+				},
+			})
+			defer ddb.DeleteTable(&dynamodb.DeleteTableInput{
+				TableName: aws.String(tc.input.TableName),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := dynamodbx.ImportTable(ddb, strings.NewReader(tc.data), tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Imported != tc.imported {
+				t.Fatalf("expected %d items imported, got %d", tc.imported, out.Imported)
+			}
+			if rejected.String() != tc.rejected {
+				t.Fatal(pretty.Compare(rejected.String(), tc.rejected))
+			}
+			if int(out.Rejected) != len(out.Errors) {
+				t.Fatalf("expected %d row errors, got %d", out.Rejected, len(out.Errors))
+			}
+			scan, err := ddb.Scan(&dynamodb.ScanInput{TableName: aws.String(tc.input.TableName)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if aws.Int64Value(scan.Count) != tc.imported {
+				t.Fatalf("expected %d items in the table, got %d", tc.imported, aws.Int64Value(scan.Count))
+			}
+		})
+	}
+}
+
+func TestImportTableUnprocessed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("unprocessed items are retried with a backoff for around 20s")
+	}
+	t.Parallel()
+	// The server returns every item of a BatchWriteItem as unprocessed, so the unprocessed items the
+	// client sees are decoded from the response rather than being the items it sent
+	db := memdb.New()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "DynamoDB_20120810.BatchWriteItem" {
+			db.ServeHTTP(w, r)
+			return
+		}
+		var input struct {
+			RequestItems json.RawMessage
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		json.NewEncoder(w).Encode(map[string]json.RawMessage{"UnprocessedItems": input.RequestItems})
+	}))
+	defer srv.Close()
+	ddb := dynamodb.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("eu-west-1"),
+		Endpoint:    aws.String(srv.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})))
+	if _, err := dynamodbx.CreateTableSync(ddb, batchWriteSpec); err != nil {
+		t.Fatal(err)
+	}
+
+	data := `{"S":{"S":"a"}}
+{"S":{"S":"b"},"N":{"N":"1"}}
+{"S":{"S":"c"}}
+`
+	var rejected bytes.Buffer
+	out, err := dynamodbx.ImportTable(ddb, strings.NewReader(data), &dynamodbx.ImportInput{
+		TableName: aws.StringValue(batchWriteSpec.TableName),
+		Rejected:  &rejected,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Imported != 0 || out.Rejected != 3 {
+		t.Fatalf("expected 3 items rejected, got %d imported and %d rejected", out.Imported, out.Rejected)
+	}
+	for i, rerr := range out.Errors {
+		if rerr.Line != i+1 || rerr.Err != dynamodbx.ErrImportUnprocessed {
+			t.Fatalf("unexpected row error: %v", rerr)
+		}
+	}
+	if rejected.String() != data {
+		t.Fatal(pretty.Compare(rejected.String(), data))
+	}
+}
+
+func TestImportTableDuplicateKeys(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	if _, err := dynamodbx.CreateTableSync(ddb, batchWriteSpec); err != nil {
+		t.Fatal(err)
+	}
+	// Rows with the same key are written in separate batches, in order with a concurrency of 1
+	data := "S,N\na,1\nb,1\na,2\nc,1\nb,2\nb,3\n"
+	var rejected bytes.Buffer
+	out, err := dynamodbx.ImportTable(ddb, strings.NewReader(data), &dynamodbx.ImportInput{
+		TableName:      aws.StringValue(batchWriteSpec.TableName),
+		Format:         dynamodbx.FormatCSV,
+		CSVColumnTypes: map[string]string{"N": "N"},
+		Concurrency:    1,
+		Rejected:       &rejected,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Imported != 6 || out.Rejected != 0 {
+		t.Fatalf("expected 6 items imported, got %d imported and %d rejected: %v", out.Imported, out.Rejected, out.Errors)
+	}
+	if expect := "S,N\n"; rejected.String() != expect {
+		t.Fatal(pretty.Compare(rejected.String(), expect))
+	}
+	type item struct {
+		S string
+		N int
+	}
+	scan, err := ddb.Scan(&dynamodb.ScanInput{TableName: batchWriteSpec.TableName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []item
+	if err := dynamodbattribute.UnmarshalListOfMaps(scan.Items, &items); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].S < items[j].S })
+	if expect := []item{{"a", 2}, {"b", 3}, {"c", 1}}; !reflect.DeepEqual(items, expect) {
+		t.Fatal(pretty.Compare(items, expect))
+	}
+}
+
+func TestImportTableFailedBatch(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	if _, err := dynamodbx.CreateTableSync(ddb, batchWriteSpec); err != nil {
+		t.Fatal(err)
+	}
+	// dynamodb refuses the first batch for its invalid number, and the import carries on
+	var data strings.Builder
+	data.WriteString(`{"S":{"S":"a"},"N":{"N":"x"}}` + "\n")
+	for i := 0; i < 25; i++ {
+		fmt.Fprintf(&data, `{"S":{"S":"%d"}}`+"\n", i)
+	}
+	var rejected bytes.Buffer
+	out, err := dynamodbx.ImportTable(ddb, strings.NewReader(data.String()), &dynamodbx.ImportInput{
+		TableName:   aws.StringValue(batchWriteSpec.TableName),
+		Concurrency: 1,
+		Rejected:    &rejected,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Imported != 1 || out.Rejected != 25 {
+		t.Fatalf("expected 1 item imported and 25 rejected, got %d imported and %d rejected", out.Imported, out.Rejected)
+	}
+	for i, rerr := range out.Errors {
+		if aerr, ok := rerr.Err.(awserr.Error); rerr.Line != i+1 || !ok || aerr.Code() != "ValidationException" {
+			t.Fatalf("unexpected row error: %v", rerr)
+		}
+	}
+	lines := strings.SplitAfter(data.String(), "\n")
+	if expect := strings.Join(lines[:25], ""); rejected.String() != expect {
+		t.Fatal(pretty.Compare(rejected.String(), expect))
+	}
+}
+
+func TestUnmarshalPlainJSON(t *testing.T) {
+	t.Parallel()
+	got, err := dynamodbx.UnmarshalPlainJSON([]byte(`{"S":"Hello","N":12345678901234567890,"L":[1.5,"x"],"M":{"BOOL":true,"NULL":null}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]*dynamodb.AttributeValue{
+		"S": {S: aws.String("Hello")},
+		"N": {N: aws.String("12345678901234567890")},
+		"L": {L: []*dynamodb.AttributeValue{{N: aws.String("1.5")}, {S: aws.String("x")}}},
+		"M": {M: map[string]*dynamodb.AttributeValue{
+			"BOOL": {BOOL: aws.Bool(true)},
+			"NULL": {NULL: aws.Bool(true)},
+		}},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Fatal(pretty.Compare(got, expect))
+	}
+}
+
+func TestItemSize(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name   string
+		item   map[string]*dynamodb.AttributeValue
+		expect int
+	}{
+		{
+			name:   "string",
+			item:   map[string]*dynamodb.AttributeValue{"Name": {S: aws.String("Hello")}},
+			expect: 9,
+		},
+		{
+			name:   "number",
+			item:   map[string]*dynamodb.AttributeValue{"N": {N: aws.String("-12345")}},
+			expect: 5,
+		},
+		{
+			name: "nested",
+			item: map[string]*dynamodb.AttributeValue{"M": {M: map[string]*dynamodb.AttributeValue{
+				"B": {BOOL: aws.Bool(true)},
+				"L": {L: []*dynamodb.AttributeValue{{S: aws.String("ab")}}},
+			}}},
+			expect: 1 + 3 + (1 + 1 + 1) + (1 + 1 + 3 + 1 + 2),
+		},
+		{
+			name:   "too large",
+			item:   map[string]*dynamodb.AttributeValue{"B": {B: make([]byte, 400*1024)}},
+			expect: 1 + 400*1024,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := dynamodbx.ItemSize(tc.item); got != tc.expect {
+				t.Fatalf("expected size %d, got %d", tc.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,496 @@
+package memdb
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// getPath returns the value at a path in an item, or nil if it does not exist.
+func getPath(it item, path docPath) *dynamodb.AttributeValue {
+	v := it[path[0].name]
+	for _, e := range path[1:] {
+		switch {
+		case v == nil:
+			return nil
+		case e.index >= 0:
+			if v.L == nil || e.index >= len(v.L) {
+				return nil
+			}
+			v = v.L[e.index]
+		default:
+			if v.M == nil {
+				return nil
+			}
+			v = v.M[e.name]
+		}
+	}
+	return v
+}
+
+// operand evaluates a path, value or size function. It returns nil for paths which do not exist.
+func (n *node) operand(it item) (*dynamodb.AttributeValue, *apiError) {
+	switch n.kind {
+	case nodePath:
+		return getPath(it, n.path), nil
+	case nodeValue:
+		return n.value, nil
+	case nodeFunc:
+		if n.name == "size" {
+			v := getPath(it, n.args[0].path)
+			size := 0
+			switch valueType(v) {
+			case "":
+				return nil, nil
+			case "S":
+				size = len(*v.S)
+			case "B":
+				size = len(v.B)
+			case "SS", "NS", "BS":
+				size = len(v.SS) + len(v.NS) + len(v.BS)
+			case "L":
+				size = len(v.L)
+			case "M":
+				size = len(v.M)
+			default:
+				return nil, validationError("Invalid ConditionExpression: Incorrect operand type for operator or function; operator or function: size, operand type: %s", valueType(v))
+			}
+			return &dynamodb.AttributeValue{N: aws.String(big.NewInt(int64(size)).String())}, nil
+		}
+	}
+	return nil, validationError("memdb: unexpected operand")
+}
+
+// eval evaluates a condition against an item. A nil condition is true.
+func (n *node) eval(it item) (bool, *apiError) {
+	if n == nil {
+		return true, nil
+	}
+	switch n.kind {
+	case nodeAnd, nodeOr:
+		left, err := n.args[0].eval(it)
+		if err != nil {
+			return false, err
+		}
+		right, err := n.args[1].eval(it)
+		if err != nil {
+			return false, err
+		}
+		if n.kind == nodeAnd {
+			return left && right, nil
+		}
+		return left || right, nil
+	case nodeNot:
+		ok, err := n.args[0].eval(it)
+		return !ok, err
+	case nodeCompare:
+		a, err := n.args[0].operand(it)
+		if err != nil {
+			return false, err
+		}
+		b, err := n.args[1].operand(it)
+		if err != nil || a == nil || b == nil {
+			return false, err
+		}
+		switch n.name {
+		case "=":
+			return equalValues(a, b), nil
+		case "<>":
+			return !equalValues(a, b), nil
+		}
+		c, ok := compareValues(a, b)
+		if !ok {
+			return false, nil
+		}
+		switch n.name {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	case nodeBetween:
+		var vs [3]*dynamodb.AttributeValue
+		for i, arg := range n.args {
+			v, err := arg.operand(it)
+			if err != nil || v == nil {
+				return false, err
+			}
+			vs[i] = v
+		}
+		if c, ok := compareValues(vs[1], vs[2]); ok && c > 0 {
+			return false, validationError("Invalid ConditionExpression: The BETWEEN operator requires upper bound to be greater than or equal to lower bound")
+		}
+		low, lok := compareValues(vs[0], vs[1])
+		high, hok := compareValues(vs[0], vs[2])
+		return lok && hok && low >= 0 && high <= 0, nil
+	case nodeIn:
+		v, err := n.args[0].operand(it)
+		if err != nil || v == nil {
+			return false, err
+		}
+		for _, arg := range n.args[1:] {
+			e, err := arg.operand(it)
+			if err != nil {
+				return false, err
+			}
+			if e != nil && equalValues(v, e) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case nodeFunc:
+		return n.evalFunc(it)
+	}
+	return false, validationError("memdb: unexpected condition")
+}
+
+func (n *node) evalFunc(it item) (bool, *apiError) {
+	v, err := n.args[0].operand(it)
+	if err != nil {
+		return false, err
+	}
+	switch n.name {
+	case "attribute_exists":
+		return v != nil, nil
+	case "attribute_not_exists":
+		return v == nil, nil
+	}
+	arg, err := n.args[1].operand(it)
+	if err != nil || v == nil || arg == nil {
+		return false, err
+	}
+	switch n.name {
+	case "attribute_type":
+		if arg.S == nil {
+			return false, validationError("Invalid ConditionExpression: Incorrect operand type for operator or function; operator or function: attribute_type, operand type: %s", valueType(arg))
+		}
+		return valueType(v) == *arg.S, nil
+	case "begins_with":
+		switch {
+		case v.S != nil && arg.S != nil:
+			return strings.HasPrefix(*v.S, *arg.S), nil
+		case v.B != nil && arg.B != nil:
+			return bytes.HasPrefix(v.B, arg.B), nil
+		}
+		return false, nil
+	case "contains":
+		switch valueType(v) {
+		case "S":
+			return arg.S != nil && strings.Contains(*v.S, *arg.S), nil
+		case "B":
+			return arg.B != nil && bytes.Contains(v.B, arg.B), nil
+		case "SS", "NS", "BS":
+			for _, e := range setElements(v) {
+				if equalValues(e, arg) {
+					return true, nil
+				}
+			}
+		case "L":
+			for _, e := range v.L {
+				if equalValues(e, arg) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+	return false, validationError("memdb: unexpected function %s", n.name)
+}
+
+// setValue evaluates the value of a SET action against the item before the update.
+func (n *node) setValue(it item) (*dynamodb.AttributeValue, *apiError) {
+	switch {
+	case n.kind == nodeArith:
+		a, err := n.args[0].setValue(it)
+		if err != nil {
+			return nil, err
+		}
+		b, err := n.args[1].setValue(it)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil || b == nil {
+			return nil, validationError("The provided expression refers to an attribute that does not exist in the item")
+		}
+		if a.N == nil || b.N == nil {
+			return nil, validationError("An operand in the update expression has an incorrect data type")
+		}
+		ar, _ := parseNumber(*a.N)
+		br, _ := parseNumber(*b.N)
+		if n.name == "+" {
+			ar = new(big.Rat).Add(ar, br)
+		} else {
+			ar = new(big.Rat).Sub(ar, br)
+		}
+		return &dynamodb.AttributeValue{N: aws.String(formatNumber(ar))}, nil
+	case n.kind == nodeFunc && n.name == "if_not_exists":
+		if v := getPath(it, n.args[0].path); v != nil {
+			return v, nil
+		}
+		return n.args[1].setValue(it)
+	case n.kind == nodeFunc && n.name == "list_append":
+		a, err := n.args[0].setValue(it)
+		if err != nil {
+			return nil, err
+		}
+		b, err := n.args[1].setValue(it)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil || b == nil {
+			return nil, validationError("The provided expression refers to an attribute that does not exist in the item")
+		}
+		if a.L == nil || b.L == nil {
+			return nil, validationError("An operand in the update expression has an incorrect data type")
+		}
+		l := make([]*dynamodb.AttributeValue, 0, len(a.L)+len(b.L))
+		return &dynamodb.AttributeValue{L: append(append(l, a.L...), b.L...)}, nil
+	}
+	v, err := n.operand(it)
+	if err == nil && v == nil {
+		return nil, validationError("The provided expression refers to an attribute that does not exist in the item")
+	}
+	return v, err
+}
+
+// applyUpdate returns a copy of the item with the update actions applied. All values are evaluated
+// against the item before the update, as dynamodb does.
+func applyUpdate(old item, actions []*updateAction) (item, *apiError) {
+	values := make([]*dynamodb.AttributeValue, len(actions))
+	for i, a := range actions {
+		switch a.clause {
+		case "SET":
+			v, err := a.value.setValue(old)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = copyValue(v)
+		case "ADD", "DELETE":
+			values[i] = a.value.value
+		}
+	}
+	for i, a := range actions {
+		for _, b := range actions[i+1:] {
+			if pathsOverlap(a.path, b.path) {
+				return nil, validationError("Invalid UpdateExpression: Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [%s], path two: [%s]", a.path, b.path)
+			}
+		}
+	}
+
+	it := copyItem(old)
+	if it == nil {
+		it = make(item)
+	}
+	// List elements are removed from the highest index down so earlier removals do not shift them
+	removes := make([]int, 0, len(actions))
+	for i, a := range actions {
+		if a.clause == "REMOVE" {
+			removes = append(removes, i)
+			continue
+		}
+		cur := getPath(it, a.path)
+		v := values[i]
+		switch a.clause {
+		case "ADD":
+			switch {
+			case v.N != nil && (cur == nil || cur.N != nil):
+				sum, _ := parseNumber(*v.N)
+				if cur != nil {
+					c, _ := parseNumber(*cur.N)
+					sum = new(big.Rat).Add(sum, c)
+				}
+				v = &dynamodb.AttributeValue{N: aws.String(formatNumber(sum))}
+			case (v.SS != nil || v.NS != nil || v.BS != nil) && (cur == nil || valueType(cur) == valueType(v)):
+				v = setUnion(cur, v)
+			default:
+				return nil, validationError("An operand in the update expression has an incorrect data type")
+			}
+		case "DELETE":
+			if v.SS == nil && v.NS == nil && v.BS == nil || cur != nil && valueType(cur) != valueType(v) {
+				return nil, validationError("An operand in the update expression has an incorrect data type")
+			}
+			if cur == nil {
+				continue
+			}
+			if v = setDifference(cur, v); v == nil {
+				removePath(it, a.path)
+				continue
+			}
+		}
+		if err := setPath(it, a.path, v); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(removes, func(i, j int) bool {
+		pi, pj := actions[removes[i]].path, actions[removes[j]].path
+		return pi[len(pi)-1].index > pj[len(pj)-1].index
+	})
+	for _, i := range removes {
+		removePath(it, actions[i].path)
+	}
+	return it, nil
+}
+
+// pathsOverlap reports whether one path is a prefix of the other.
+func pathsOverlap(a, b docPath) bool {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// setPath sets the value at a path. The parent of the path must exist. Setting a list index past
+// the end of the list appends to it.
+func setPath(it item, path docPath, v *dynamodb.AttributeValue) *apiError {
+	if len(path) == 1 {
+		it[path[0].name] = v
+		return nil
+	}
+	parent := getPath(it, path[:len(path)-1])
+	last := path[len(path)-1]
+	switch {
+	case parent == nil:
+	case last.index >= 0 && parent.L != nil:
+		if last.index < len(parent.L) {
+			parent.L[last.index] = v
+		} else {
+			parent.L = append(parent.L, v)
+		}
+		return nil
+	case last.index < 0 && parent.M != nil:
+		parent.M[last.name] = v
+		return nil
+	}
+	return validationError("The document path provided in the update expression is invalid for update")
+}
+
+func removePath(it item, path docPath) {
+	if len(path) == 1 {
+		delete(it, path[0].name)
+		return
+	}
+	parent := getPath(it, path[:len(path)-1])
+	last := path[len(path)-1]
+	switch {
+	case parent == nil:
+	case last.index >= 0 && parent.L != nil && last.index < len(parent.L):
+		parent.L = append(parent.L[:last.index], parent.L[last.index+1:]...)
+	case last.index < 0 && parent.M != nil:
+		delete(parent.M, last.name)
+	}
+}
+
+func setUnion(a, b *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	out := copyValue(b)
+	if a == nil {
+		return out
+	}
+	out = copyValue(a)
+	seen := make(map[string]bool)
+	for _, e := range setElements(a) {
+		seen[encodeScalar(e)] = true
+	}
+	for _, e := range setElements(b) {
+		if seen[encodeScalar(e)] {
+			continue
+		}
+		switch {
+		case e.S != nil:
+			out.SS = append(out.SS, aws.String(*e.S))
+		case e.N != nil:
+			out.NS = append(out.NS, aws.String(*e.N))
+		case e.B != nil:
+			out.BS = append(out.BS, append([]byte{}, e.B...))
+		}
+	}
+	return out
+}
+
+// setDifference removes the elements of b from a, returning nil if none are left.
+func setDifference(a, b *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	remove := make(map[string]bool)
+	for _, e := range setElements(b) {
+		remove[encodeScalar(e)] = true
+	}
+	out := &dynamodb.AttributeValue{}
+	n := 0
+	for _, e := range setElements(a) {
+		if remove[encodeScalar(e)] {
+			continue
+		}
+		n++
+		switch {
+		case e.S != nil:
+			out.SS = append(out.SS, aws.String(*e.S))
+		case e.N != nil:
+			out.NS = append(out.NS, aws.String(*e.N))
+		case e.B != nil:
+			out.BS = append(out.BS, append([]byte{}, e.B...))
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	return out
+}
+
+// project returns a copy of the item containing only the projected paths.
+func project(it item, paths []docPath) item {
+	if paths == nil {
+		return copyItem(it)
+	}
+	out := make(item)
+	for _, path := range paths {
+		v := getPath(it, path)
+		if v == nil {
+			continue
+		}
+		// Build the containers leading to the value, list indexes become positions in a new list
+		cur := out
+		var list *dynamodb.AttributeValue
+		for i, e := range path {
+			last := i == len(path)-1
+			next := path[min(i+1, len(path)-1)]
+			var child *dynamodb.AttributeValue
+			if last {
+				child = copyValue(v)
+			} else if next.index >= 0 {
+				child = &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}
+			} else {
+				child = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{}}
+			}
+			if list != nil {
+				list.L = append(list.L, child)
+			} else if existing, ok := cur[e.name]; ok && !last {
+				child = existing
+			} else {
+				cur[e.name] = child
+			}
+			list = nil
+			if child.L != nil && !last {
+				list = child
+			}
+			cur = child.M
+		}
+	}
+	return out
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
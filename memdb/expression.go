@@ -0,0 +1,589 @@
+package memdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// nodeKind is the kind of a node in a parsed expression.
+type nodeKind int
+
+const (
+	nodePath    nodeKind = iota // a document path
+	nodeValue                   // an expression attribute value
+	nodeFunc                    // a function call, name holds the function
+	nodeCompare                 // args[0] name args[1], name holds the comparator
+	nodeBetween                 // args[0] BETWEEN args[1] AND args[2]
+	nodeIn                      // args[0] IN (args[1:]...)
+	nodeAnd
+	nodeOr
+	nodeNot
+	nodeArith // args[0] name args[1] in a SET action, name is + or -
+)
+
+// node is a parsed expression.
+type node struct {
+	kind  nodeKind
+	name  string
+	path  docPath
+	value *dynamodb.AttributeValue
+	args  []*node
+}
+
+// pathElem is a map key, or a list index when index is not negative.
+type pathElem struct {
+	name  string
+	index int
+}
+
+type docPath []pathElem
+
+func (p docPath) String() string {
+	var b strings.Builder
+	for i, e := range p {
+		switch {
+		case e.index >= 0:
+			fmt.Fprintf(&b, "[%d]", e.index)
+		case i > 0:
+			b.WriteString("." + e.name)
+		default:
+			b.WriteString(e.name)
+		}
+	}
+	return b.String()
+}
+
+// updateAction is a single action of an update expression. value is unset for REMOVE.
+type updateAction struct {
+	clause string
+	path   docPath
+	value  *node
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokName
+	tokValue
+	tokNumber
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// exprContext parses the expressions of a request, resolving names and values and recording which
+// ones are used so unused ones can be rejected as dynamodb does.
+type exprContext struct {
+	names      map[string]*string
+	values     map[string]*dynamodb.AttributeValue
+	usedNames  map[string]bool
+	usedValues map[string]bool
+}
+
+func newExprContext(names map[string]*string, values map[string]*dynamodb.AttributeValue) *exprContext {
+	return &exprContext{
+		names:      names,
+		values:     values,
+		usedNames:  make(map[string]bool),
+		usedValues: make(map[string]bool),
+	}
+}
+
+// checkUnused returns an error for names or values which no expression used.
+func (c *exprContext) checkUnused() *apiError {
+	for k := range c.names {
+		if !c.usedNames[k] {
+			return validationError("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}", k)
+		}
+	}
+	for k := range c.values {
+		if !c.usedValues[k] {
+			return validationError("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}", k)
+		}
+	}
+	return nil
+}
+
+// parser is a recursive descent parser for one expression.
+type parser struct {
+	ctx    *exprContext
+	kind   string
+	expr   string
+	tokens []token
+	pos    int
+}
+
+func (c *exprContext) parser(kind string, expr *string) (*parser, *apiError) {
+	if expr == nil {
+		return nil, nil
+	}
+	if strings.TrimSpace(*expr) == "" {
+		return nil, validationError("Invalid %s: The expression can not be empty;", kind)
+	}
+	tokens, err := tokenize(*expr)
+	if err != nil {
+		return nil, validationError("Invalid %s: %s", kind, err)
+	}
+	return &parser{ctx: c, kind: kind, expr: *expr, tokens: tokens}, nil
+}
+
+// condition parses a condition, filter or key condition expression. A nil expression returns nil.
+func (c *exprContext) condition(kind string, expr *string) (*node, *apiError) {
+	p, err := c.parser(kind, expr)
+	if p == nil || err != nil {
+		return nil, err
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	return n, p.expectEOF()
+}
+
+// projection parses a projection expression. A nil expression returns nil.
+func (c *exprContext) projection(expr *string) ([]docPath, *apiError) {
+	p, err := c.parser("ProjectionExpression", expr)
+	if p == nil || err != nil {
+		return nil, err
+	}
+	var paths []docPath
+	for {
+		path, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+		if !p.accept(",") {
+			break
+		}
+	}
+	return paths, p.expectEOF()
+}
+
+// update parses an update expression. A nil expression returns nil.
+func (c *exprContext) update(expr *string) ([]*updateAction, *apiError) {
+	p, err := c.parser("UpdateExpression", expr)
+	if p == nil || err != nil {
+		return nil, err
+	}
+	var actions []*updateAction
+	seen := make(map[string]bool)
+	for p.peek().kind != tokEOF {
+		t := p.next()
+		clause := strings.ToUpper(t.text)
+		if t.kind != tokIdent || (clause != "SET" && clause != "REMOVE" && clause != "ADD" && clause != "DELETE") {
+			return nil, p.syntaxError(t)
+		}
+		if seen[clause] {
+			return nil, validationError("Invalid UpdateExpression: The \"%s\" section can only be used once in an update expression;", clause)
+		}
+		seen[clause] = true
+		for {
+			path, err := p.parsePath()
+			if err != nil {
+				return nil, err
+			}
+			action := &updateAction{clause: clause, path: path}
+			switch clause {
+			case "SET":
+				if err := p.expect("="); err != nil {
+					return nil, err
+				}
+				if action.value, err = p.parseSetValue(); err != nil {
+					return nil, err
+				}
+			case "ADD", "DELETE":
+				if action.value, err = p.parseOperand(); err != nil {
+					return nil, err
+				}
+				if action.value.kind != nodeValue {
+					return nil, validationError("Invalid UpdateExpression: Incorrect operand type for operator or function; operator: %s, operand type: PATH", clause)
+				}
+			}
+			actions = append(actions, action)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if len(actions) == 0 {
+		return nil, validationError("Invalid UpdateExpression: The expression can not be empty;")
+	}
+	return actions, nil
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{kind: tokEOF}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given punctuation or case insensitive keyword.
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokPunct || t.kind == tokIdent) && strings.EqualFold(t.text, text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) *apiError {
+	if !p.accept(text) {
+		return p.syntaxError(p.peek())
+	}
+	return nil
+}
+
+func (p *parser) expectEOF() *apiError {
+	if t := p.peek(); t.kind != tokEOF {
+		return p.syntaxError(t)
+	}
+	return nil
+}
+
+func (p *parser) syntaxError(t token) *apiError {
+	if t.kind == tokEOF {
+		return validationError("Invalid %s: Syntax error; token: \"<EOF>\", near: %q", p.kind, p.expr)
+	}
+	return validationError("Invalid %s: Syntax error; token: %q, near: %q", p.kind, t.text, p.expr)
+}
+
+func (p *parser) parseOr() (*node, *apiError) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeOr, args: []*node{left, right}}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (*node, *apiError) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &node{kind: nodeAnd, args: []*node{left, right}}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (*node, *apiError) {
+	if p.accept("NOT") {
+		n, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeNot, args: []*node{n}}, nil
+	}
+	return p.parsePrimary()
+}
+
+var conditionFuncs = map[string]int{
+	"attribute_exists":     1,
+	"attribute_not_exists": 1,
+	"attribute_type":       2,
+	"begins_with":          2,
+	"contains":             2,
+}
+
+func (p *parser) parsePrimary() (*node, *apiError) {
+	if p.accept("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	}
+	if t := p.peek(); t.kind == tokIdent && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].text == "(" {
+		if arity, ok := conditionFuncs[t.text]; ok {
+			p.pos += 2
+			n := &node{kind: nodeFunc, name: t.text}
+			for i := 0; i < arity; i++ {
+				if i > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				arg, err := p.parseOperand()
+				if err != nil {
+					return nil, err
+				}
+				n.args = append(n.args, arg)
+			}
+			if n.args[0].kind != nodePath && t.text != "contains" {
+				return nil, validationError("Invalid %s: Incorrect operand type for operator or function; operator or function: %s, operand type: %s", p.kind, t.text, operandType(n.args[0]))
+			}
+			return n, p.expect(")")
+		}
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("BETWEEN") {
+		low, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeBetween, args: []*node{left, low, high}}, nil
+	}
+	if p.accept("IN") {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		n := &node{kind: nodeIn, args: []*node{left}}
+		for {
+			arg, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, arg)
+			if !p.accept(",") {
+				break
+			}
+		}
+		if len(n.args) > 101 {
+			return nil, validationError("Invalid %s: The IN operator is provided with too many operands; number of operands: %d", p.kind, len(n.args)-1)
+		}
+		return n, p.expect(")")
+	}
+	t := p.next()
+	switch t.text {
+	case "=", "<>", "<", "<=", ">", ">=":
+	default:
+		return nil, p.syntaxError(t)
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &node{kind: nodeCompare, name: t.text, args: []*node{left, right}}, nil
+}
+
+func operandType(n *node) string {
+	switch n.kind {
+	case nodePath:
+		return "PATH"
+	case nodeValue:
+		return valueType(n.value)
+	}
+	return "FUNCTION"
+}
+
+// parseOperand parses a path, a value or the size function.
+func (p *parser) parseOperand() (*node, *apiError) {
+	t := p.peek()
+	switch t.kind {
+	case tokValue:
+		p.pos++
+		v, ok := p.ctx.values[t.text]
+		if !ok {
+			return nil, validationError("Invalid %s: An expression attribute value used in expression is not defined; attribute value: %s", p.kind, t.text)
+		}
+		p.ctx.usedValues[t.text] = true
+		return &node{kind: nodeValue, value: v}, nil
+	case tokIdent:
+		if p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].text == "(" {
+			if t.text != "size" {
+				return nil, validationError("Invalid %s: Invalid function name; function: %s", p.kind, t.text)
+			}
+			p.pos += 2
+			path, err := p.parsePath()
+			if err != nil {
+				return nil, err
+			}
+			return &node{kind: nodeFunc, name: "size", args: []*node{{kind: nodePath, path: path}}}, p.expect(")")
+		}
+	}
+	path, err := p.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	return &node{kind: nodePath, path: path}, nil
+}
+
+// parseSetValue parses the value of a SET action, which may add or subtract two operands and use
+// the if_not_exists and list_append functions.
+func (p *parser) parseSetValue() (*node, *apiError) {
+	left, err := p.parseSetOperand()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokPunct && (t.text == "+" || t.text == "-") {
+		p.pos++
+		right, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeArith, name: t.text, args: []*node{left, right}}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseSetOperand() (*node, *apiError) {
+	t := p.peek()
+	if t.kind != tokIdent || p.pos+1 >= len(p.tokens) || p.tokens[p.pos+1].text != "(" {
+		return p.parseOperand()
+	}
+	if t.text != "if_not_exists" && t.text != "list_append" {
+		return nil, validationError("Invalid %s: Invalid function name; function: %s", p.kind, t.text)
+	}
+	p.pos += 2
+	n := &node{kind: nodeFunc, name: t.text}
+	for i := 0; i < 2; i++ {
+		if i > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		n.args = append(n.args, arg)
+	}
+	if t.text == "if_not_exists" && n.args[0].kind != nodePath {
+		return nil, validationError("Invalid %s: Operator or function requires a document path; operator or function: if_not_exists", p.kind)
+	}
+	return n, p.expect(")")
+}
+
+// parsePath parses a document path such as a.#b[1].c.
+func (p *parser) parsePath() (docPath, *apiError) {
+	var path docPath
+	name, err := p.parsePathName()
+	if err != nil {
+		return nil, err
+	}
+	path = append(path, pathElem{name: name, index: -1})
+	for {
+		switch {
+		case p.accept("."):
+			name, err := p.parsePathName()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, pathElem{name: name, index: -1})
+		case p.accept("["):
+			t := p.next()
+			if t.kind != tokNumber {
+				return nil, p.syntaxError(t)
+			}
+			i, perr := strconv.Atoi(t.text)
+			if perr != nil {
+				return nil, p.syntaxError(t)
+			}
+			path = append(path, pathElem{index: i})
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		default:
+			return path, nil
+		}
+	}
+}
+
+func (p *parser) parsePathName() (string, *apiError) {
+	t := p.next()
+	switch t.kind {
+	case tokIdent:
+		return t.text, nil
+	case tokName:
+		name, ok := p.ctx.names[t.text]
+		if !ok {
+			return "", validationError("Invalid %s: An expression attribute name used in the document path is not defined; attribute name: %s", p.kind, t.text)
+		}
+		p.ctx.usedNames[t.text] = true
+		return aws.StringValue(name), nil
+	}
+	return "", p.syntaxError(t)
+}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	isWord := func(c byte) bool {
+		return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || c == ':':
+			j := i + 1
+			for j < len(expr) && isWord(expr[j]) {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("Syntax error; token: %q, near: %q", string(c), expr)
+			}
+			kind := tokName
+			if c == ':' {
+				kind = tokValue
+			}
+			tokens = append(tokens, token{kind: kind, text: expr[i:j]})
+			i = j
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && expr[j] >= '0' && expr[j] <= '9' {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: expr[i:j]})
+			i = j
+		case isWord(c):
+			j := i
+			for j < len(expr) && isWord(expr[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: expr[i:j]})
+			i = j
+		case strings.HasPrefix(expr[i:], "<=") || strings.HasPrefix(expr[i:], ">=") || strings.HasPrefix(expr[i:], "<>"):
+			tokens = append(tokens, token{kind: tokPunct, text: expr[i : i+2]})
+			i += 2
+		case strings.IndexByte("=<>()[],.+-", c) >= 0:
+			tokens = append(tokens, token{kind: tokPunct, text: expr[i : i+1]})
+			i++
+		default:
+			return nil, fmt.Errorf("Invalid character encountered in expression; character: %q", string(c))
+		}
+	}
+	return tokens, nil
+}
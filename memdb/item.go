@@ -0,0 +1,562 @@
+package memdb
+
+import (
+	"math"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// maxItemSize is the largest item dynamodb accepts
+const maxItemSize = 400 * 1024
+
+// checkKey checks a key has exactly the key attributes of the table with the right types.
+func (t *table) checkKey(key item) *apiError {
+	names := t.keys.names()
+	if len(key) != len(names) {
+		return validationError("The provided key element does not match the schema")
+	}
+	for _, name := range names {
+		if v, ok := key[name]; !ok || valueType(v) != t.attrs[name] {
+			return validationError("The provided key element does not match the schema")
+		}
+	}
+	return nil
+}
+
+// checkItem checks an item has valid values, the key attributes of the table, correctly typed
+// index key attributes and fits in the item size limit.
+func (t *table) checkItem(it item) *apiError {
+	for _, v := range it {
+		if v == nil {
+			return validationError("Supplied AttributeValue is empty, must contain exactly one of the supported datatypes")
+		}
+		if err := validateValue(v); err != nil {
+			return err
+		}
+	}
+	for _, name := range t.keys.names() {
+		v, ok := it[name]
+		if !ok {
+			return validationError("One or more parameter values were invalid: Missing the key %s in the item", name)
+		}
+		if valueType(v) != t.attrs[name] {
+			return validationError("One or more parameter values were invalid: Type mismatch for key %s expected: %s actual: %s", name, t.attrs[name], valueType(v))
+		}
+		if v.S != nil && *v.S == "" || v.B != nil && len(v.B) == 0 {
+			return validationError("One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: %s", name)
+		}
+	}
+	for _, idx := range append(append([]*index{}, t.gsis...), t.lsis...) {
+		for _, name := range idx.keys.names() {
+			v, ok := it[name]
+			if !ok {
+				continue
+			}
+			if valueType(v) != t.attrs[name] {
+				return validationError("One or more parameter values were invalid: Type mismatch for Index Key %s Expected: %s Actual: %s IndexName: %s", name, t.attrs[name], valueType(v), idx.name)
+			}
+			if v.S != nil && *v.S == "" || v.B != nil && len(v.B) == 0 {
+				return validationError("One or more parameter values are not valid. A value specified for a secondary index key is not supported. The AttributeValue for a key attribute cannot contain an empty string value. IndexName: %s, IndexKey: %s", idx.name, name)
+			}
+		}
+	}
+	if itemSize(it) > maxItemSize {
+		return validationError("Item size has exceeded the maximum allowed size")
+	}
+	return nil
+}
+
+// checkLegacy rejects the legacy parameters which were replaced by expressions.
+func checkLegacy(params map[string]bool) *apiError {
+	for name, set := range params {
+		if set {
+			return validationError("memdb: legacy parameter %s is not supported, use expressions instead", name)
+		}
+	}
+	return nil
+}
+
+// capacity accumulates the capacity units consumed by a request.
+type capacity struct {
+	mode  string
+	table float64
+	gsis  map[string]float64
+	lsis  map[string]float64
+}
+
+func newCapacity(mode *string) *capacity {
+	return &capacity{mode: aws.StringValue(mode)}
+}
+
+func writeUnits(size int) float64 {
+	return math.Max(1, math.Ceil(float64(size)/1024))
+}
+
+func readUnits(size int, consistent bool) float64 {
+	units := math.Max(1, math.Ceil(float64(size)/4096))
+	if !consistent {
+		units /= 2
+	}
+	return units
+}
+
+// write records the capacity of replacing old with new, including writes to indexes whose keys
+// are in either item.
+func (c *capacity) write(t *table, old, new item) {
+	size := math.Max(float64(itemSize(old)), float64(itemSize(new)))
+	c.table += writeUnits(int(size))
+	for _, idx := range t.gsis {
+		if hasKeys(old, idx.keys) || hasKeys(new, idx.keys) {
+			c.add(&c.gsis, idx.name, writeUnits(int(size)))
+		}
+	}
+	for _, idx := range t.lsis {
+		if hasKeys(old, idx.keys) || hasKeys(new, idx.keys) {
+			c.add(&c.lsis, idx.name, writeUnits(int(size)))
+		}
+	}
+}
+
+func (c *capacity) read(idx *index, units float64) {
+	switch {
+	case idx == nil:
+		c.table += units
+	case idx.local:
+		c.add(&c.lsis, idx.name, units)
+	default:
+		c.add(&c.gsis, idx.name, units)
+	}
+}
+
+func (c *capacity) add(m *map[string]float64, name string, units float64) {
+	if *m == nil {
+		*m = make(map[string]float64)
+	}
+	(*m)[name] += units
+}
+
+func hasKeys(it item, keys keySchema) bool {
+	return it != nil && it[keys.hash] != nil && (keys.rng == "" || it[keys.rng] != nil)
+}
+
+// consumed returns the ConsumedCapacity to report for a table, or nil if none was requested.
+func (c *capacity) consumed(table string) *dynamodb.ConsumedCapacity {
+	if c.mode != dynamodb.ReturnConsumedCapacityTotal && c.mode != dynamodb.ReturnConsumedCapacityIndexes {
+		return nil
+	}
+	total := c.table
+	for _, u := range c.gsis {
+		total += u
+	}
+	for _, u := range c.lsis {
+		total += u
+	}
+	cc := &dynamodb.ConsumedCapacity{TableName: aws.String(table), CapacityUnits: aws.Float64(total)}
+	if c.mode == dynamodb.ReturnConsumedCapacityIndexes {
+		cc.Table = &dynamodb.Capacity{CapacityUnits: aws.Float64(c.table)}
+		for name, u := range c.gsis {
+			if cc.GlobalSecondaryIndexes == nil {
+				cc.GlobalSecondaryIndexes = make(map[string]*dynamodb.Capacity)
+			}
+			cc.GlobalSecondaryIndexes[name] = &dynamodb.Capacity{CapacityUnits: aws.Float64(u)}
+		}
+		for name, u := range c.lsis {
+			if cc.LocalSecondaryIndexes == nil {
+				cc.LocalSecondaryIndexes = make(map[string]*dynamodb.Capacity)
+			}
+			cc.LocalSecondaryIndexes[name] = &dynamodb.Capacity{CapacityUnits: aws.Float64(u)}
+		}
+	}
+	return cc
+}
+
+// itemCollectionMetrics returns the metrics dynamodb reports for tables with local secondary
+// indexes, or nil if none were requested.
+func (t *table) itemCollectionMetrics(mode *string, it item) *dynamodb.ItemCollectionMetrics {
+	if aws.StringValue(mode) != dynamodb.ReturnItemCollectionMetricsSize || len(t.lsis) == 0 {
+		return nil
+	}
+	return &dynamodb.ItemCollectionMetrics{
+		ItemCollectionKey:   item{t.keys.hash: copyValue(it[t.keys.hash])},
+		SizeEstimateRangeGB: []*float64{aws.Float64(0), aws.Float64(1)},
+	}
+}
+
+// returnValues returns the attributes for ReturnValues, which is one of the allowed modes.
+func returnValues(mode *string, allowed []string, old, new item, updated map[string]bool) (item, *apiError) {
+	m := aws.StringValue(mode)
+	if m == "" || m == dynamodb.ReturnValueNone {
+		return nil, nil
+	}
+	ok := false
+	for _, a := range allowed {
+		ok = ok || a == m
+	}
+	if !ok {
+		return nil, validationError("ReturnValues can only be %v for this operation", allowed)
+	}
+	var src item
+	switch m {
+	case dynamodb.ReturnValueAllOld, dynamodb.ReturnValueUpdatedOld:
+		src = old
+	default:
+		src = new
+	}
+	if src == nil {
+		return nil, nil
+	}
+	out := make(item)
+	for k, v := range src {
+		if m == dynamodb.ReturnValueAllOld || m == dynamodb.ReturnValueAllNew || updated[k] {
+			out[k] = copyValue(v)
+		}
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+func (db *DB) putItem(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, *apiError) {
+	if err := checkLegacy(map[string]bool{"Expected": in.Expected != nil, "ConditionalOperator": in.ConditionalOperator != nil}); err != nil {
+		return nil, err
+	}
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	ctx := newExprContext(in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	cond, err := ctx.condition("ConditionExpression", in.ConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.checkUnused(); err != nil {
+		return nil, err
+	}
+	if err := t.checkItem(in.Item); err != nil {
+		return nil, err
+	}
+	k := t.encodeKey(in.Item)
+	old := t.items[k]
+	if ok, err := cond.eval(old); err != nil || !ok {
+		if err == nil {
+			err = conditionalCheckFailed()
+		}
+		return nil, err
+	}
+	attrs, err := returnValues(in.ReturnValues, []string{dynamodb.ReturnValueAllOld}, old, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	it := copyItem(in.Item)
+	t.items[k] = it
+	c := newCapacity(in.ReturnConsumedCapacity)
+	c.write(t, old, it)
+	return &dynamodb.PutItemOutput{
+		Attributes:            attrs,
+		ConsumedCapacity:      c.consumed(t.name),
+		ItemCollectionMetrics: t.itemCollectionMetrics(in.ReturnItemCollectionMetrics, it),
+	}, nil
+}
+
+func (db *DB) getItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, *apiError) {
+	if err := checkLegacy(map[string]bool{"AttributesToGet": in.AttributesToGet != nil}); err != nil {
+		return nil, err
+	}
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	ctx := newExprContext(in.ExpressionAttributeNames, nil)
+	paths, err := ctx.projection(in.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.checkUnused(); err != nil {
+		return nil, err
+	}
+	if err := t.checkKey(in.Key); err != nil {
+		return nil, err
+	}
+	out := &dynamodb.GetItemOutput{}
+	it := t.items[t.encodeKey(in.Key)]
+	if it != nil {
+		out.Item = project(it, paths)
+	}
+	c := newCapacity(in.ReturnConsumedCapacity)
+	c.read(nil, readUnits(itemSize(it), aws.BoolValue(in.ConsistentRead)))
+	out.ConsumedCapacity = c.consumed(t.name)
+	return out, nil
+}
+
+func (db *DB) updateItem(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, *apiError) {
+	if err := checkLegacy(map[string]bool{"Expected": in.Expected != nil, "ConditionalOperator": in.ConditionalOperator != nil, "AttributeUpdates": in.AttributeUpdates != nil}); err != nil {
+		return nil, err
+	}
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	ctx := newExprContext(in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	actions, err := ctx.update(in.UpdateExpression)
+	if err != nil {
+		return nil, err
+	}
+	cond, err := ctx.condition("ConditionExpression", in.ConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.checkUnused(); err != nil {
+		return nil, err
+	}
+	if err := t.checkKey(in.Key); err != nil {
+		return nil, err
+	}
+	updated := make(map[string]bool)
+	for _, a := range actions {
+		name := a.path[0].name
+		if name == t.keys.hash || name == t.keys.rng {
+			return nil, validationError("One or more parameter values were invalid: Cannot update attribute %s. This attribute is part of the key", name)
+		}
+		updated[name] = true
+	}
+	k := t.encodeKey(in.Key)
+	old := t.items[k]
+	if ok, err := cond.eval(old); err != nil || !ok {
+		if err == nil {
+			err = conditionalCheckFailed()
+		}
+		return nil, err
+	}
+	base := old
+	if base == nil {
+		base = in.Key
+	}
+	it, err := applyUpdate(base, actions)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkItem(it); err != nil {
+		return nil, err
+	}
+	attrs, err := returnValues(in.ReturnValues, []string{
+		dynamodb.ReturnValueAllOld, dynamodb.ReturnValueUpdatedOld, dynamodb.ReturnValueAllNew, dynamodb.ReturnValueUpdatedNew,
+	}, old, it, updated)
+	if err != nil {
+		return nil, err
+	}
+	t.items[k] = it
+	c := newCapacity(in.ReturnConsumedCapacity)
+	c.write(t, old, it)
+	return &dynamodb.UpdateItemOutput{
+		Attributes:            attrs,
+		ConsumedCapacity:      c.consumed(t.name),
+		ItemCollectionMetrics: t.itemCollectionMetrics(in.ReturnItemCollectionMetrics, it),
+	}, nil
+}
+
+func (db *DB) deleteItem(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, *apiError) {
+	if err := checkLegacy(map[string]bool{"Expected": in.Expected != nil, "ConditionalOperator": in.ConditionalOperator != nil}); err != nil {
+		return nil, err
+	}
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	ctx := newExprContext(in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	cond, err := ctx.condition("ConditionExpression", in.ConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.checkUnused(); err != nil {
+		return nil, err
+	}
+	if err := t.checkKey(in.Key); err != nil {
+		return nil, err
+	}
+	k := t.encodeKey(in.Key)
+	old := t.items[k]
+	if ok, err := cond.eval(old); err != nil || !ok {
+		if err == nil {
+			err = conditionalCheckFailed()
+		}
+		return nil, err
+	}
+	attrs, err := returnValues(in.ReturnValues, []string{dynamodb.ReturnValueAllOld}, old, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	delete(t.items, k)
+	c := newCapacity(in.ReturnConsumedCapacity)
+	c.write(t, old, nil)
+	return &dynamodb.DeleteItemOutput{
+		Attributes:            attrs,
+		ConsumedCapacity:      c.consumed(t.name),
+		ItemCollectionMetrics: t.itemCollectionMetrics(in.ReturnItemCollectionMetrics, in.Key),
+	}, nil
+}
+
+func (db *DB) batchWriteItem(in *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, *apiError) {
+	type write struct {
+		t   *table
+		key string
+		put item
+	}
+	var writes []write
+	for name, reqs := range in.RequestItems {
+		t, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool, len(reqs))
+		for _, req := range reqs {
+			var w write
+			switch {
+			case req.PutRequest != nil && req.DeleteRequest == nil:
+				if err := t.checkItem(req.PutRequest.Item); err != nil {
+					return nil, err
+				}
+				w = write{t: t, key: t.encodeKey(req.PutRequest.Item), put: req.PutRequest.Item}
+			case req.DeleteRequest != nil && req.PutRequest == nil:
+				if err := t.checkKey(req.DeleteRequest.Key); err != nil {
+					return nil, err
+				}
+				w = write{t: t, key: t.encodeKey(req.DeleteRequest.Key)}
+			default:
+				return nil, validationError("Supplied AttributeValue has more than one datatypes set, must contain exactly one of the supported datatypes")
+			}
+			if seen[w.key] {
+				return nil, validationError("Provided list of item keys contains duplicates")
+			}
+			seen[w.key] = true
+			writes = append(writes, w)
+		}
+	}
+	if len(writes) == 0 || len(writes) > 25 {
+		return nil, validationError("1 validation error detected: Value at 'requestItems' failed to satisfy constraint: Map value must satisfy constraint: [Member must have length less than or equal to 25, Member must have length greater than or equal to 1]")
+	}
+
+	caps := make(map[*table]*capacity)
+	out := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]*dynamodb.WriteRequest{}}
+	for _, w := range writes {
+		c, ok := caps[w.t]
+		if !ok {
+			c = newCapacity(in.ReturnConsumedCapacity)
+			caps[w.t] = c
+		}
+		old := w.t.items[w.key]
+		if w.put != nil {
+			it := copyItem(w.put)
+			w.t.items[w.key] = it
+			c.write(w.t, old, it)
+		} else {
+			delete(w.t.items, w.key)
+			c.write(w.t, old, nil)
+		}
+		if m := w.t.itemCollectionMetrics(in.ReturnItemCollectionMetrics, w.t.key(firstItem(w.put, old))); m != nil {
+			if out.ItemCollectionMetrics == nil {
+				out.ItemCollectionMetrics = make(map[string][]*dynamodb.ItemCollectionMetrics)
+			}
+			out.ItemCollectionMetrics[w.t.name] = append(out.ItemCollectionMetrics[w.t.name], m)
+		}
+	}
+	for _, t := range sortedTables(caps) {
+		if cc := caps[t].consumed(t.name); cc != nil {
+			out.ConsumedCapacity = append(out.ConsumedCapacity, cc)
+		}
+	}
+	return out, nil
+}
+
+func firstItem(items ...item) item {
+	for _, it := range items {
+		if it != nil {
+			return it
+		}
+	}
+	return item{}
+}
+
+func (db *DB) batchGetItem(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, *apiError) {
+	n := 0
+	out := &dynamodb.BatchGetItemOutput{
+		Responses:       make(map[string][]map[string]*dynamodb.AttributeValue),
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{},
+	}
+	type get struct {
+		t          *table
+		keys       []item
+		paths      []docPath
+		consistent bool
+	}
+	var gets []get
+	for name, ka := range in.RequestItems {
+		if err := checkLegacy(map[string]bool{"AttributesToGet": ka.AttributesToGet != nil}); err != nil {
+			return nil, err
+		}
+		t, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+		ctx := newExprContext(ka.ExpressionAttributeNames, nil)
+		paths, err := ctx.projection(ka.ProjectionExpression)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.checkUnused(); err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool, len(ka.Keys))
+		for _, key := range ka.Keys {
+			if err := t.checkKey(key); err != nil {
+				return nil, err
+			}
+			k := t.encodeKey(key)
+			if seen[k] {
+				return nil, validationError("Provided list of item keys contains duplicates")
+			}
+			seen[k] = true
+		}
+		n += len(ka.Keys)
+		gets = append(gets, get{t: t, keys: ka.Keys, paths: paths, consistent: aws.BoolValue(ka.ConsistentRead)})
+	}
+	if n == 0 || n > 100 {
+		return nil, validationError("Too many items requested for the BatchGetItem call")
+	}
+	caps := make(map[*table]*capacity)
+	for _, g := range gets {
+		c := newCapacity(in.ReturnConsumedCapacity)
+		caps[g.t] = c
+		items := []map[string]*dynamodb.AttributeValue{}
+		for _, key := range g.keys {
+			it := g.t.items[g.t.encodeKey(key)]
+			c.read(nil, readUnits(itemSize(it), g.consistent))
+			if it != nil {
+				items = append(items, project(it, g.paths))
+			}
+		}
+		out.Responses[g.t.name] = items
+	}
+	for _, t := range sortedTables(caps) {
+		if cc := caps[t].consumed(t.name); cc != nil {
+			out.ConsumedCapacity = append(out.ConsumedCapacity, cc)
+		}
+	}
+	return out, nil
+}
+
+// sortedTables returns the tables of a capacity map ordered by name, so output is deterministic.
+func sortedTables(caps map[*table]*capacity) []*table {
+	names := make(map[string]string, len(caps))
+	byName := make(map[string]*table, len(caps))
+	for t := range caps {
+		names[t.name] = t.name
+		byName[t.name] = t
+	}
+	tables := make([]*table, 0, len(caps))
+	for _, name := range sortedNames(names) {
+		tables = append(tables, byName[name])
+	}
+	return tables
+}
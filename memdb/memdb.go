@@ -0,0 +1,180 @@
+// Package memdb is an in-memory implementation of the subset of dynamodb used by dynamodbx, for
+// unit tests which cannot reach a real dynamodb or DynamoDB Local.
+//
+// A DB serves requests made through the *dynamodb.DynamoDB returned by Client, so code under test
+// uses the aws-sdk-go client unchanged. A DB is also an http.Handler serving the dynamodb JSON
+// protocol, for code which is configured with an endpoint URL.
+//
+// The fake supports CreateTable, DescribeTable, UpdateTable, DeleteTable, ListTables,
+// DescribeTimeToLive, UpdateTimeToLive, PutItem, GetItem, UpdateItem, DeleteItem, BatchWriteItem,
+// BatchGetItem, Query and Scan, including global and local secondary indexes, expressions,
+// pagination and consumed capacity. Other operations fail with a ValidationException.
+//
+// Behaviour is deterministic: tables and indexes are active as soon as they are created, writes
+// are never throttled or left unprocessed, and Scan returns items ordered by key. Items are not
+// expired by time to live and reserved words are not rejected in expressions.
+package memdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// DB is an in-memory dynamodb. It is safe for concurrent use.
+type DB struct {
+	mu       sync.Mutex
+	tables   map[string]*table
+	requests int64
+	// Now returns the time used for table creation and update timestamps. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// New returns an empty DB.
+func New() *DB {
+	return &DB{
+		tables: make(map[string]*table),
+		Now:    time.Now,
+	}
+}
+
+// NewClient returns a client for a new empty DB.
+func NewClient(cfgs ...*aws.Config) *dynamodb.DynamoDB {
+	return New().Client(cfgs...)
+}
+
+// Client returns a dynamodb client whose requests are served by the DB instead of being sent over
+// HTTP. Requests are still validated, built and signed, and errors are returned as the same
+// awserr.RequestFailure values and retried in the same way as errors from dynamodb. Configs are
+// merged into the default config, which sets a region, endpoint and static credentials.
+func (db *DB) Client(cfgs ...*aws.Config) *dynamodb.DynamoDB {
+	cfg := aws.NewConfig().
+		WithRegion("us-east-1").
+		WithEndpoint("http://memdb").
+		WithCredentials(credentials.NewStaticCredentials("memdb", "memdb", "")).
+		WithDisableComputeChecksums(true)
+	cfg.MergeIn(cfgs...)
+	client := dynamodb.New(session.Must(session.NewSession(cfg)))
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBackNamed(request.NamedHandler{Name: "memdb.Send", Fn: db.send})
+	client.Handlers.UnmarshalMeta.Clear()
+	client.Handlers.ValidateResponse.Clear()
+	client.Handlers.Unmarshal.Clear()
+	client.Handlers.UnmarshalError.Clear()
+	return client
+}
+
+// send serves a request from the DB, setting its output or error and a matching HTTP response.
+func (db *DB) send(r *request.Request) {
+	if err := r.Context().Err(); err != nil {
+		r.Error = awserr.New(request.CanceledErrorCode, "request context canceled", err)
+		r.Retryable = aws.Bool(false)
+		return
+	}
+	out, err := db.do(r.Params)
+	requestID := db.requestID()
+	r.HTTPResponse = &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"X-Amzn-Requestid": []string{requestID}},
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}
+	r.RequestID = requestID
+	if err != nil {
+		r.HTTPResponse.StatusCode = err.status
+		r.Error = awserr.NewRequestFailure(awserr.New(err.code, err.message, nil), err.status, requestID)
+		return
+	}
+	reflect.ValueOf(r.Data).Elem().Set(reflect.ValueOf(out).Elem())
+}
+
+func (db *DB) requestID() string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.requests++
+	return fmt.Sprintf("MEMDB%015d", db.requests)
+}
+
+// do serves a typed request, returning the typed output.
+func (db *DB) do(input interface{}) (interface{}, *apiError) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	switch in := input.(type) {
+	case *dynamodb.CreateTableInput:
+		return db.createTable(in)
+	case *dynamodb.DescribeTableInput:
+		return db.describeTable(in)
+	case *dynamodb.UpdateTableInput:
+		return db.updateTable(in)
+	case *dynamodb.DeleteTableInput:
+		return db.deleteTable(in)
+	case *dynamodb.ListTablesInput:
+		return db.listTables(in)
+	case *dynamodb.DescribeTimeToLiveInput:
+		return db.describeTimeToLive(in)
+	case *dynamodb.UpdateTimeToLiveInput:
+		return db.updateTimeToLive(in)
+	case *dynamodb.PutItemInput:
+		return db.putItem(in)
+	case *dynamodb.GetItemInput:
+		return db.getItem(in)
+	case *dynamodb.UpdateItemInput:
+		return db.updateItem(in)
+	case *dynamodb.DeleteItemInput:
+		return db.deleteItem(in)
+	case *dynamodb.BatchWriteItemInput:
+		return db.batchWriteItem(in)
+	case *dynamodb.BatchGetItemInput:
+		return db.batchGetItem(in)
+	case *dynamodb.QueryInput:
+		return db.query(in)
+	case *dynamodb.ScanInput:
+		return db.scan(in)
+	}
+	return nil, validationError("memdb: operation %T is not supported", input)
+}
+
+// apiError is an error returned by the DB with the code and status dynamodb would return.
+type apiError struct {
+	code    string
+	message string
+	status  int
+}
+
+func validationError(format string, args ...interface{}) *apiError {
+	return &apiError{code: "ValidationException", message: fmt.Sprintf(format, args...), status: http.StatusBadRequest}
+}
+
+func resourceNotFound(table string) *apiError {
+	return &apiError{
+		code:    dynamodb.ErrCodeResourceNotFoundException,
+		message: "Requested resource not found: Table: " + table + " not found",
+		status:  http.StatusBadRequest,
+	}
+}
+
+func resourceInUse(table string) *apiError {
+	return &apiError{
+		code:    dynamodb.ErrCodeResourceInUseException,
+		message: "Table already exists: " + table,
+		status:  http.StatusBadRequest,
+	}
+}
+
+func conditionalCheckFailed() *apiError {
+	return &apiError{
+		code:    dynamodb.ErrCodeConditionalCheckFailedException,
+		message: "The conditional request failed",
+		status:  http.StatusBadRequest,
+	}
+}
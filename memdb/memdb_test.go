@@ -0,0 +1,462 @@
+package memdb_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+// newTable creates a table with a string hash key P, a number range key R and a global secondary
+// index on G.
+func newTable(t *testing.T, ddb *dynamodb.DynamoDB) {
+	t.Helper()
+	_, err := ddb.CreateTable(&dynamodb.CreateTableInput{
+		TableName:   aws.String("test"),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("P"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			{AttributeName: aws.String("R"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeN)},
+			{AttributeName: aws.String("G"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("P"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String("R"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName:  aws.String("byG"),
+				KeySchema:  []*dynamodb.KeySchemaElement{{AttributeName: aws.String("G"), KeyType: aws.String(dynamodb.KeyTypeHash)}},
+				Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly)},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func errCode(err error) string {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code()
+	}
+	return ""
+}
+
+func TestTables(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	newTable(t, ddb)
+
+	for _, tc := range []struct {
+		name  string
+		input *dynamodb.CreateTableInput
+		code  string
+	}{
+		{
+			name: "table exists",
+			input: &dynamodb.CreateTableInput{
+				TableName:            aws.String("test"),
+				BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("P"), AttributeType: aws.String("S")}},
+				KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("P"), KeyType: aws.String("HASH")}},
+			},
+			code: dynamodb.ErrCodeResourceInUseException,
+		},
+		{
+			name: "undefined key attribute",
+			input: &dynamodb.CreateTableInput{
+				TableName:            aws.String("undefined"),
+				BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("P"), AttributeType: aws.String("S")}},
+				KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("X"), KeyType: aws.String("HASH")}},
+			},
+			code: "ValidationException",
+		},
+		{
+			name: "missing throughput",
+			input: &dynamodb.CreateTableInput{
+				TableName:            aws.String("provisioned"),
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("P"), AttributeType: aws.String("S")}},
+				KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("P"), KeyType: aws.String("HASH")}},
+			},
+			code: "ValidationException",
+		},
+	} {
+		if _, err := ddb.CreateTable(tc.input); errCode(err) != tc.code {
+			t.Fatalf("%s: expected error code %s, got %v", tc.name, tc.code, err)
+		}
+	}
+
+	desc, err := ddb.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("test")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *desc.Table.TableStatus != dynamodb.TableStatusActive || *desc.Table.GlobalSecondaryIndexes[0].IndexStatus != dynamodb.IndexStatusActive {
+		t.Fatalf("expected the table and index to be active, got %v", desc.Table)
+	}
+	list, err := ddb.ListTables(&dynamodb.ListTablesInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(aws.StringValueSlice(list.TableNames), []string{"test"}) {
+		t.Fatalf("expected one table, got %v", aws.StringValueSlice(list.TableNames))
+	}
+	if _, err := ddb.UpdateTable(&dynamodb.UpdateTableInput{
+		TableName:           aws.String("test"),
+		StreamSpecification: &dynamodb.StreamSpecification{StreamEnabled: aws.Bool(true), StreamViewType: aws.String(dynamodb.StreamViewTypeKeysOnly)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ddb.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String("test")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ddb.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("test")}); errCode(err) != dynamodb.ErrCodeResourceNotFoundException {
+		t.Fatalf("expected the table to be deleted, got %v", err)
+	}
+}
+
+func TestItems(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	newTable(t, ddb)
+	key := map[string]*dynamodb.AttributeValue{"P": {S: aws.String("p")}, "R": {N: aws.String("1")}}
+
+	for _, tc := range []struct {
+		name   string
+		call   func() (interface{}, error)
+		code   string
+		expect interface{}
+	}{
+		{
+			name: "put",
+			call: func() (interface{}, error) {
+				return ddb.PutItem(&dynamodb.PutItemInput{
+					TableName:              aws.String("test"),
+					Item:                   map[string]*dynamodb.AttributeValue{"P": {S: aws.String("p")}, "R": {N: aws.String("1")}, "A": {N: aws.String("1")}},
+					ConditionExpression:    aws.String("attribute_not_exists(P)"),
+					ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+				})
+			},
+			expect: &dynamodb.PutItemOutput{ConsumedCapacity: &dynamodb.ConsumedCapacity{TableName: aws.String("test"), CapacityUnits: aws.Float64(1)}},
+		},
+		{
+			name: "conditional put fails",
+			call: func() (interface{}, error) {
+				return ddb.PutItem(&dynamodb.PutItemInput{
+					TableName:           aws.String("test"),
+					Item:                key,
+					ConditionExpression: aws.String("attribute_not_exists(P)"),
+				})
+			},
+			code: dynamodb.ErrCodeConditionalCheckFailedException,
+		},
+		{
+			name: "missing key",
+			call: func() (interface{}, error) {
+				return ddb.PutItem(&dynamodb.PutItemInput{
+					TableName: aws.String("test"),
+					Item:      map[string]*dynamodb.AttributeValue{"P": {S: aws.String("p")}},
+				})
+			},
+			code: "ValidationException",
+		},
+		{
+			name: "index key type mismatch",
+			call: func() (interface{}, error) {
+				return ddb.PutItem(&dynamodb.PutItemInput{
+					TableName: aws.String("test"),
+					Item:      map[string]*dynamodb.AttributeValue{"P": {S: aws.String("p")}, "R": {N: aws.String("2")}, "G": {N: aws.String("1")}},
+				})
+			},
+			code: "ValidationException",
+		},
+		{
+			name: "update",
+			call: func() (interface{}, error) {
+				return ddb.UpdateItem(&dynamodb.UpdateItemInput{
+					TableName:                aws.String("test"),
+					Key:                      key,
+					UpdateExpression:         aws.String("SET #a = #a + :one, L = list_append(if_not_exists(L, :empty), :l) ADD S :s"),
+					ConditionExpression:      aws.String("#a BETWEEN :one AND :two AND size(P) = :one"),
+					ExpressionAttributeNames: map[string]*string{"#a": aws.String("A")},
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+						":one":   {N: aws.String("1")},
+						":two":   {N: aws.String("2")},
+						":empty": {L: []*dynamodb.AttributeValue{}},
+						":l":     {L: []*dynamodb.AttributeValue{{S: aws.String("x")}}},
+						":s":     {SS: aws.StringSlice([]string{"a"})},
+					},
+					ReturnValues: aws.String(dynamodb.ReturnValueUpdatedNew),
+				})
+			},
+			expect: &dynamodb.UpdateItemOutput{Attributes: map[string]*dynamodb.AttributeValue{
+				"A": {N: aws.String("2")},
+				"L": {L: []*dynamodb.AttributeValue{{S: aws.String("x")}}},
+				"S": {SS: aws.StringSlice([]string{"a"})},
+			}},
+		},
+		{
+			name: "update key attribute",
+			call: func() (interface{}, error) {
+				return ddb.UpdateItem(&dynamodb.UpdateItemInput{
+					TableName:                 aws.String("test"),
+					Key:                       key,
+					UpdateExpression:          aws.String("SET R = :r"),
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":r": {N: aws.String("2")}},
+				})
+			},
+			code: "ValidationException",
+		},
+		{
+			name: "unused expression value",
+			call: func() (interface{}, error) {
+				return ddb.UpdateItem(&dynamodb.UpdateItemInput{
+					TableName:                 aws.String("test"),
+					Key:                       key,
+					UpdateExpression:          aws.String("REMOVE S"),
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":r": {N: aws.String("2")}},
+				})
+			},
+			code: "ValidationException",
+		},
+		{
+			name: "get with projection",
+			call: func() (interface{}, error) {
+				return ddb.GetItem(&dynamodb.GetItemInput{
+					TableName:            aws.String("test"),
+					Key:                  key,
+					ProjectionExpression: aws.String("A, L[0]"),
+				})
+			},
+			expect: &dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+				"A": {N: aws.String("2")},
+				"L": {L: []*dynamodb.AttributeValue{{S: aws.String("x")}}},
+			}},
+		},
+		{
+			name: "get with the wrong key",
+			call: func() (interface{}, error) {
+				return ddb.GetItem(&dynamodb.GetItemInput{
+					TableName: aws.String("test"),
+					Key:       map[string]*dynamodb.AttributeValue{"P": {S: aws.String("p")}},
+				})
+			},
+			code: "ValidationException",
+		},
+		{
+			name: "delete",
+			call: func() (interface{}, error) {
+				return ddb.DeleteItem(&dynamodb.DeleteItemInput{
+					TableName:    aws.String("test"),
+					Key:          key,
+					ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+				})
+			},
+			expect: &dynamodb.DeleteItemOutput{Attributes: map[string]*dynamodb.AttributeValue{
+				"P": {S: aws.String("p")},
+				"R": {N: aws.String("1")},
+				"A": {N: aws.String("2")},
+				"L": {L: []*dynamodb.AttributeValue{{S: aws.String("x")}}},
+				"S": {SS: aws.StringSlice([]string{"a"})},
+			}},
+		},
+	} {
+		// The cases run in order as each one relies on the item written by the previous ones
+		out, err := tc.call()
+		if errCode(err) != tc.code {
+			t.Fatalf("%s: expected error code %q, got %v", tc.name, tc.code, err)
+		}
+		if tc.expect != nil && !reflect.DeepEqual(out, tc.expect) {
+			t.Fatalf("%s: %s", tc.name, pretty.Compare(out, tc.expect))
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	newTable(t, ddb)
+	var writes []*dynamodb.WriteRequest
+	var keys []map[string]*dynamodb.AttributeValue
+	for i := 0; i < 25; i++ {
+		key := map[string]*dynamodb.AttributeValue{"P": {S: aws.String("p")}, "R": {N: aws.String(strconv.Itoa(i))}}
+		writes = append(writes, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: key}})
+		keys = append(keys, key)
+	}
+	out, err := ddb.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+		RequestItems:           map[string][]*dynamodb.WriteRequest{"test": writes},
+		ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.UnprocessedItems) != 0 || *out.ConsumedCapacity[0].CapacityUnits != 25 {
+		t.Fatalf("unexpected output: %v", out)
+	}
+
+	_, err = ddb.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{"test": append(writes, writes[0])},
+	})
+	if errCode(err) != "ValidationException" {
+		t.Fatalf("expected more than 25 writes to fail validation, got %v", err)
+	}
+	_, err = ddb.BatchWriteItem(&dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{"test": {writes[0], writes[0]}},
+	})
+	if errCode(err) != "ValidationException" {
+		t.Fatalf("expected duplicate keys to fail validation, got %v", err)
+	}
+
+	get, err := ddb.BatchGetItem(&dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{"test": {Keys: append(keys[:10:10], map[string]*dynamodb.AttributeValue{
+			"P": {S: aws.String("missing")}, "R": {N: aws.String("1")},
+		})}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(get.Responses["test"]) != 10 || len(get.UnprocessedKeys) != 0 {
+		t.Fatalf("expected 10 items, got %d", len(get.Responses["test"]))
+	}
+}
+
+func TestQueryAndScan(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	newTable(t, ddb)
+	for i := 0; i < 30; i++ {
+		it := map[string]*dynamodb.AttributeValue{
+			"P": {S: aws.String("p" + strconv.Itoa(i%3))},
+			"R": {N: aws.String(strconv.Itoa(i))},
+			"A": {N: aws.String(strconv.Itoa(i % 2))},
+		}
+		if i%5 == 0 {
+			it["G"] = &dynamodb.AttributeValue{S: aws.String("g")}
+		}
+		if _, err := ddb.PutItem(&dynamodb.PutItemInput{TableName: aws.String("test"), Item: it}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ranges := func(items []map[string]*dynamodb.AttributeValue) []string {
+		var out []string
+		for _, it := range items {
+			out = append(out, *it["R"].N)
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name   string
+		input  *dynamodb.QueryInput
+		code   string
+		expect []string
+		count  int64
+		more   bool
+	}{
+		{
+			name: "range condition",
+			input: &dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("P = :p AND R BETWEEN :lo AND :hi"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":p": {S: aws.String("p0")}, ":lo": {N: aws.String("3")}, ":hi": {N: aws.String("12")},
+				},
+			},
+			expect: []string{"3", "6", "9", "12"},
+			count:  4,
+		},
+		{
+			name: "descending with a filter and limit",
+			input: &dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("P = :p"),
+				FilterExpression:       aws.String("A = :a"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":p": {S: aws.String("p1")}, ":a": {N: aws.String("1")},
+				},
+				ScanIndexForward: aws.Bool(false),
+				Limit:            aws.Int64(4),
+			},
+			expect: []string{"25", "19"},
+			count:  2,
+			more:   true,
+		},
+		{
+			name: "index",
+			input: &dynamodb.QueryInput{
+				IndexName:                 aws.String("byG"),
+				KeyConditionExpression:    aws.String("G = :g"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":g": {S: aws.String("g")}},
+			},
+			expect: []string{"0", "15", "10", "25", "5", "20"},
+			count:  6,
+		},
+		{
+			name: "missing hash key",
+			input: &dynamodb.QueryInput{
+				KeyConditionExpression:    aws.String("R = :r"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":r": {N: aws.String("1")}},
+			},
+			code: "ValidationException",
+		},
+		{
+			name: "or in key condition",
+			input: &dynamodb.QueryInput{
+				KeyConditionExpression:    aws.String("P = :p OR P = :p"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":p": {S: aws.String("p0")}},
+			},
+			code: "ValidationException",
+		},
+	} {
+		tc.input.TableName = aws.String("test")
+		out, err := ddb.Query(tc.input)
+		if errCode(err) != tc.code {
+			t.Fatalf("%s: expected error code %q, got %v", tc.name, tc.code, err)
+		}
+		if err != nil {
+			continue
+		}
+		if got := ranges(out.Items); !reflect.DeepEqual(got, tc.expect) || *out.Count != tc.count {
+			t.Fatalf("%s: %s", tc.name, pretty.Compare(got, tc.expect))
+		}
+		if tc.more != (out.LastEvaluatedKey != nil) {
+			t.Fatalf("%s: expected more results to be %v, got LastEvaluatedKey %v", tc.name, tc.more, out.LastEvaluatedKey)
+		}
+	}
+
+	// Every item is returned by exactly one segment of a parallel scan
+	seen := make(map[string]int)
+	for segment := int64(0); segment < 4; segment++ {
+		var start map[string]*dynamodb.AttributeValue
+		for {
+			out, err := ddb.Scan(&dynamodb.ScanInput{
+				TableName:         aws.String("test"),
+				Segment:           aws.Int64(segment),
+				TotalSegments:     aws.Int64(4),
+				Limit:             aws.Int64(4),
+				ExclusiveStartKey: start,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range ranges(out.Items) {
+				seen[r]++
+			}
+			if start = out.LastEvaluatedKey; start == nil {
+				break
+			}
+		}
+	}
+	if len(seen) != 30 {
+		t.Fatalf("expected 30 items, got %d", len(seen))
+	}
+	for r, n := range seen {
+		if n != 1 {
+			t.Fatalf("expected item %s to be scanned once, got %d", r, n)
+		}
+	}
+}
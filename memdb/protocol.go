@@ -0,0 +1,67 @@
+package memdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// buildJSON encodes an output of the aws-sdk-go in the dynamodb JSON protocol. The field names of
+// dynamodb shapes are their JSON names, but unset fields are left out rather than written as null,
+// and timestamps are seconds since the epoch. Inputs need no such care, as encoding/json skips
+// nulls and no input served has a timestamp.
+func buildJSON(v interface{}) ([]byte, error) {
+	out, _ := protocolValue(reflect.ValueOf(v))
+	return json.Marshal(out)
+}
+
+// protocolValue returns the value encoding/json should write for v, or false if v is unset.
+func protocolValue(v reflect.Value) (interface{}, bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		return protocolValue(v.Elem())
+	case reflect.Struct:
+		if v.Type() == timeType {
+			t := v.Interface().(time.Time)
+			return float64(t.UnixNano()) / float64(time.Second), true
+		}
+		m := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if e, ok := protocolValue(v.Field(i)); ok {
+				m[v.Type().Field(i).Name] = e
+			}
+		}
+		return m, true
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, false
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes byte slices base64 encoded, as blobs are
+			return v.Bytes(), true
+		}
+		l := make([]interface{}, v.Len())
+		for i := range l {
+			l[i], _ = protocolValue(v.Index(i))
+		}
+		return l, true
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, false
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, k := range v.MapKeys() {
+			m[k.String()], _ = protocolValue(v.MapIndex(k))
+		}
+		return m, true
+	}
+	return v.Interface(), true
+}
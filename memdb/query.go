@@ -0,0 +1,337 @@
+package memdb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// maxPageSize is the most data a single Query or Scan reads before returning a page
+const maxPageSize = 1024 * 1024
+
+// readRequest holds the parameters shared by Query and Scan.
+type readRequest struct {
+	t          *table
+	idx        *index
+	keyCond    []*node
+	filter     *node
+	paths      []docPath
+	selectMode string
+	limit      int64
+	startKey   item
+	consistent bool
+	forward    bool
+	segment    int64
+	segments   int64
+	capacity   *string
+}
+
+// newReadRequest resolves the table and index and checks the Select mode and consistency.
+func (db *DB) newReadRequest(table, indexName, selectMode *string, hasProjection, consistent bool) (*readRequest, *apiError) {
+	t, err := db.table(table)
+	if err != nil {
+		return nil, err
+	}
+	r := &readRequest{t: t, consistent: consistent, forward: true}
+	if indexName != nil {
+		if r.idx = t.index(*indexName); r.idx == nil {
+			return nil, validationError("The table does not have the specified index: %s", *indexName)
+		}
+		if consistent && !r.idx.local {
+			return nil, validationError("Consistent reads are not supported on global secondary indexes")
+		}
+	}
+	r.selectMode = aws.StringValue(selectMode)
+	switch r.selectMode {
+	case "":
+		r.selectMode = dynamodb.SelectAllAttributes
+		if hasProjection {
+			r.selectMode = dynamodb.SelectSpecificAttributes
+		} else if r.idx != nil {
+			r.selectMode = dynamodb.SelectAllProjectedAttributes
+		}
+	case dynamodb.SelectAllProjectedAttributes:
+		if r.idx == nil {
+			return nil, validationError("ALL_PROJECTED_ATTRIBUTES can be used only when Querying using an IndexName")
+		}
+	case dynamodb.SelectAllAttributes:
+		if r.idx != nil && !r.idx.local && aws.StringValue(r.idx.projection.ProjectionType) != dynamodb.ProjectionTypeAll {
+			return nil, validationError("One or more parameter values were invalid: Select type ALL_ATTRIBUTES is not supported for global secondary index %s because its projection type is not ALL", r.idx.name)
+		}
+	case dynamodb.SelectSpecificAttributes, dynamodb.SelectCount:
+	}
+	if hasProjection && r.selectMode != dynamodb.SelectSpecificAttributes {
+		return nil, validationError("Cannot specify the ProjectionExpression when choosing to get %s", r.selectMode)
+	}
+	return r, nil
+}
+
+// setStartKey checks the ExclusiveStartKey has the key attributes of the table and index.
+func (r *readRequest) setStartKey(key item) *apiError {
+	if key == nil {
+		return nil
+	}
+	names := r.t.keys.names()
+	if r.idx != nil {
+		names = append(names, r.idx.keys.names()...)
+	}
+	for _, name := range names {
+		if v, ok := key[name]; !ok || valueType(v) != r.t.attrs[name] {
+			return validationError("The provided starting key is invalid: The provided key element does not match the schema")
+		}
+	}
+	r.startKey = key
+	return nil
+}
+
+// read walks the table or index in order, returning a page of results.
+func (r *readRequest) read(partition *dynamodb.AttributeValue) (items []item, count, scanned int64, lastKey item, cc *dynamodb.ConsumedCapacity, err *apiError) {
+	view := r.t.view(r.idx)
+	if !r.forward {
+		for i, j := 0, len(view)-1; i < j; i, j = i+1, j-1 {
+			view[i], view[j] = view[j], view[i]
+		}
+	}
+	size := 0
+	for _, it := range view {
+		if partition != nil && !equalValues(it[r.idx.hashName(r.t)], partition) {
+			continue
+		}
+		if r.segments > 0 && r.t.segment(it, r.segments) != r.segment {
+			continue
+		}
+		if r.startKey != nil {
+			c := r.t.compareItems(r.idx, it, r.startKey)
+			if r.forward && c <= 0 || !r.forward && c >= 0 {
+				continue
+			}
+		}
+		match := true
+		for _, cond := range r.keyCond {
+			ok, err := cond.eval(it)
+			if err != nil {
+				return nil, 0, 0, nil, nil, err
+			}
+			match = match && ok
+		}
+		if !match {
+			continue
+		}
+		scanned++
+		size += itemSize(it)
+		ok, err := r.filter.eval(it)
+		if err != nil {
+			return nil, 0, 0, nil, nil, err
+		}
+		if ok {
+			count++
+			if r.selectMode != dynamodb.SelectCount {
+				result := it
+				if r.selectMode == dynamodb.SelectAllAttributes && r.idx != nil {
+					result = r.t.items[r.t.encodeKey(it)]
+				}
+				items = append(items, project(result, r.paths))
+			}
+		}
+		if scanned == r.limit || size >= maxPageSize {
+			lastKey = r.t.lastKey(r.idx, it)
+			break
+		}
+	}
+	c := newCapacity(r.capacity)
+	c.read(r.idx, readUnits(size, r.consistent))
+	return items, count, scanned, lastKey, c.consumed(r.t.name), nil
+}
+
+// hashName returns the hash key attribute of the index, or the table if idx is nil.
+func (idx *index) hashName(t *table) string {
+	if idx == nil {
+		return t.keys.hash
+	}
+	return idx.keys.hash
+}
+
+func (idx *index) rangeName(t *table) string {
+	if idx == nil {
+		return t.keys.rng
+	}
+	return idx.keys.rng
+}
+
+func (db *DB) query(in *dynamodb.QueryInput) (*dynamodb.QueryOutput, *apiError) {
+	if err := checkLegacy(map[string]bool{
+		"AttributesToGet":     in.AttributesToGet != nil,
+		"KeyConditions":       in.KeyConditions != nil,
+		"QueryFilter":         in.QueryFilter != nil,
+		"ConditionalOperator": in.ConditionalOperator != nil,
+	}); err != nil {
+		return nil, err
+	}
+	if in.KeyConditionExpression == nil {
+		return nil, validationError("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+	r, err := db.newReadRequest(in.TableName, in.IndexName, in.Select, in.ProjectionExpression != nil, aws.BoolValue(in.ConsistentRead))
+	if err != nil {
+		return nil, err
+	}
+	ctx := newExprContext(in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	keyCond, err := ctx.condition("KeyConditionExpression", in.KeyConditionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if r.filter, err = ctx.condition("FilterExpression", in.FilterExpression); err != nil {
+		return nil, err
+	}
+	if r.paths, err = ctx.projection(in.ProjectionExpression); err != nil {
+		return nil, err
+	}
+	if err := ctx.checkUnused(); err != nil {
+		return nil, err
+	}
+	partition, err := r.parseKeyCondition(keyCond)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.setStartKey(in.ExclusiveStartKey); err != nil {
+		return nil, err
+	}
+	r.limit = aws.Int64Value(in.Limit)
+	r.forward = in.ScanIndexForward == nil || *in.ScanIndexForward
+	r.capacity = in.ReturnConsumedCapacity
+	items, count, scanned, lastKey, cc, err := r.read(partition)
+	if err != nil {
+		return nil, err
+	}
+	out := &dynamodb.QueryOutput{
+		Count:            aws.Int64(count),
+		ScannedCount:     aws.Int64(scanned),
+		LastEvaluatedKey: lastKey,
+		ConsumedCapacity: cc,
+	}
+	if r.selectMode != dynamodb.SelectCount {
+		out.Items = make([]map[string]*dynamodb.AttributeValue, len(items))
+		copy(out.Items, items)
+	}
+	return out, nil
+}
+
+// parseKeyCondition checks a key condition is an equality condition on the hash key, optionally
+// combined with AND with one condition on the range key. It returns the partition to query.
+func (r *readRequest) parseKeyCondition(cond *node) (*dynamodb.AttributeValue, *apiError) {
+	var conds []*node
+	var flatten func(n *node) *apiError
+	flatten = func(n *node) *apiError {
+		switch n.kind {
+		case nodeAnd:
+			if err := flatten(n.args[0]); err != nil {
+				return err
+			}
+			return flatten(n.args[1])
+		case nodeCompare, nodeBetween:
+			if n.name == "<>" {
+				return validationError("Invalid KeyConditionExpression: Invalid operator used in KeyConditionExpression: <>")
+			}
+		case nodeFunc:
+			if n.name != "begins_with" {
+				return validationError("Invalid KeyConditionExpression: Invalid operator used in KeyConditionExpression: %s", n.name)
+			}
+		default:
+			return validationError("Invalid KeyConditionExpression: Invalid operator used in KeyConditionExpression: %s", map[nodeKind]string{nodeOr: "OR", nodeNot: "NOT", nodeIn: "IN"}[n.kind])
+		}
+		conds = append(conds, n)
+		return nil
+	}
+	if err := flatten(cond); err != nil {
+		return nil, err
+	}
+	hash, rng := r.idx.hashName(r.t), r.idx.rangeName(r.t)
+	var partition *dynamodb.AttributeValue
+	seen := make(map[string]bool)
+	for _, c := range conds {
+		// The key attribute is on the left, except for comparisons written value first
+		attr, value := c.args[0], c.args[len(c.args)-1]
+		if c.kind == nodeCompare && attr.kind == nodeValue {
+			attr, value = value, attr
+		}
+		if attr.kind != nodePath || len(attr.path) != 1 || value.kind != nodeValue {
+			return nil, validationError("Invalid KeyConditionExpression: Key conditions must compare a key attribute with a value")
+		}
+		name := attr.path[0].name
+		if name != hash && name != rng {
+			return nil, validationError("Query condition missed key schema element: %s", hash)
+		}
+		if seen[name] {
+			return nil, validationError("KeyConditionExpressions must only contain one condition per key")
+		}
+		seen[name] = true
+		for _, arg := range c.args {
+			if arg.kind == nodeValue && valueType(arg.value) != r.t.attrs[name] {
+				return nil, validationError("One or more parameter values were invalid: Condition parameter type does not match schema type")
+			}
+		}
+		if name == hash {
+			if c.kind != nodeCompare || c.name != "=" {
+				return nil, validationError("Query key condition not supported")
+			}
+			partition = value.value
+		}
+	}
+	if partition == nil {
+		return nil, validationError("Query condition missed key schema element: %s", hash)
+	}
+	r.keyCond = conds
+	return partition, nil
+}
+
+func (db *DB) scan(in *dynamodb.ScanInput) (*dynamodb.ScanOutput, *apiError) {
+	if err := checkLegacy(map[string]bool{
+		"AttributesToGet":     in.AttributesToGet != nil,
+		"ScanFilter":          in.ScanFilter != nil,
+		"ConditionalOperator": in.ConditionalOperator != nil,
+	}); err != nil {
+		return nil, err
+	}
+	r, err := db.newReadRequest(in.TableName, in.IndexName, in.Select, in.ProjectionExpression != nil, aws.BoolValue(in.ConsistentRead))
+	if err != nil {
+		return nil, err
+	}
+	ctx := newExprContext(in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if r.filter, err = ctx.condition("FilterExpression", in.FilterExpression); err != nil {
+		return nil, err
+	}
+	if r.paths, err = ctx.projection(in.ProjectionExpression); err != nil {
+		return nil, err
+	}
+	if err := ctx.checkUnused(); err != nil {
+		return nil, err
+	}
+	switch {
+	case in.Segment != nil && in.TotalSegments == nil:
+		return nil, validationError("The TotalSegments parameter is required but was not present in the request when Segment parameter is present")
+	case in.Segment == nil && in.TotalSegments != nil:
+		return nil, validationError("The Segment parameter is required but was not present in the request when parameter TotalSegments is present")
+	case in.Segment != nil && *in.Segment >= *in.TotalSegments:
+		return nil, validationError("The Segment parameter is zero-based and must be less than parameter TotalSegments: Segment: %d is not less than TotalSegments: %d", *in.Segment, *in.TotalSegments)
+	case in.Segment != nil:
+		r.segment, r.segments = *in.Segment, *in.TotalSegments
+	}
+	if err := r.setStartKey(in.ExclusiveStartKey); err != nil {
+		return nil, err
+	}
+	r.limit = aws.Int64Value(in.Limit)
+	r.capacity = in.ReturnConsumedCapacity
+	items, count, scanned, lastKey, cc, err := r.read(nil)
+	if err != nil {
+		return nil, err
+	}
+	out := &dynamodb.ScanOutput{
+		Count:            aws.Int64(count),
+		ScannedCount:     aws.Int64(scanned),
+		LastEvaluatedKey: lastKey,
+		ConsumedCapacity: cc,
+	}
+	if r.selectMode != dynamodb.SelectCount {
+		out.Items = make([]map[string]*dynamodb.AttributeValue, len(items))
+		copy(out.Items, items)
+	}
+	return out, nil
+}
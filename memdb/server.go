@@ -0,0 +1,100 @@
+package memdb
+
+import (
+	"encoding/json"
+	"hash/crc32"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// targetPrefix prefixes the operation name in the X-Amz-Target header of dynamodb requests.
+const targetPrefix = "DynamoDB_20120810."
+
+// operations maps the operations served over HTTP to constructors of their inputs.
+var operations = map[string]func() interface{}{
+	"CreateTable":        func() interface{} { return &dynamodb.CreateTableInput{} },
+	"DescribeTable":      func() interface{} { return &dynamodb.DescribeTableInput{} },
+	"UpdateTable":        func() interface{} { return &dynamodb.UpdateTableInput{} },
+	"DeleteTable":        func() interface{} { return &dynamodb.DeleteTableInput{} },
+	"ListTables":         func() interface{} { return &dynamodb.ListTablesInput{} },
+	"DescribeTimeToLive": func() interface{} { return &dynamodb.DescribeTimeToLiveInput{} },
+	"UpdateTimeToLive":   func() interface{} { return &dynamodb.UpdateTimeToLiveInput{} },
+	"PutItem":            func() interface{} { return &dynamodb.PutItemInput{} },
+	"GetItem":            func() interface{} { return &dynamodb.GetItemInput{} },
+	"UpdateItem":         func() interface{} { return &dynamodb.UpdateItemInput{} },
+	"DeleteItem":         func() interface{} { return &dynamodb.DeleteItemInput{} },
+	"BatchWriteItem":     func() interface{} { return &dynamodb.BatchWriteItemInput{} },
+	"BatchGetItem":       func() interface{} { return &dynamodb.BatchGetItemInput{} },
+	"Query":              func() interface{} { return &dynamodb.QueryInput{} },
+	"Scan":               func() interface{} { return &dynamodb.ScanInput{} },
+}
+
+// ServeHTTP serves the DB over the dynamodb JSON 1.0 protocol, routing requests by their
+// X-Amz-Target header, so any dynamodb client can use it as its endpoint:
+//
+//	srv := httptest.NewServer(memdb.New())
+//	defer srv.Close()
+//	ddb := dynamodb.New(sess, aws.NewConfig().WithEndpoint(srv.URL))
+//
+// Requests are not authenticated, so any credentials and region are accepted.
+func (db *DB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestID := db.requestID()
+	w.Header().Set("X-Amzn-Requestid", requestID)
+	if r.Method != http.MethodPost {
+		writeError(w, &apiError{code: "UnknownOperationException", status: http.StatusBadRequest})
+		return
+	}
+	target := r.Header.Get("X-Amz-Target")
+	newInput, ok := operations[strings.TrimPrefix(target, targetPrefix)]
+	if !ok || !strings.HasPrefix(target, targetPrefix) {
+		writeError(w, &apiError{code: "UnknownOperationException", status: http.StatusBadRequest})
+		return
+	}
+	input := newInput()
+	if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+		writeError(w, &apiError{code: "SerializationException", message: err.Error(), status: http.StatusBadRequest})
+		return
+	}
+	if v, ok := input.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			msg := err.Error()
+			if aerr, ok := err.(awserr.Error); ok {
+				msg = aerr.Message()
+			}
+			writeError(w, validationError("%s", msg))
+			return
+		}
+	}
+	out, aerr := db.do(input)
+	if aerr != nil {
+		writeError(w, aerr)
+		return
+	}
+	body, err := buildJSON(out)
+	if err != nil {
+		writeError(w, &apiError{code: dynamodb.ErrCodeInternalServerError, message: err.Error(), status: http.StatusInternalServerError})
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// writeError writes an error in the format of dynamodb, with the code in __type.
+func writeError(w http.ResponseWriter, err *apiError) {
+	body, _ := json.Marshal(struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}{"com.amazonaws.dynamodb.v20120810#" + err.code, err.message})
+	writeJSON(w, err.status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("X-Amz-Crc32", strconv.FormatUint(uint64(crc32.ChecksumIEEE(body)), 10))
+	w.WriteHeader(status)
+	w.Write(body)
+}
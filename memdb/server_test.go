@@ -0,0 +1,183 @@
+package memdb_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(memdb.New())
+	defer srv.Close()
+	ddb := dynamodb.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("eu-west-1"),
+		Endpoint:    aws.String(srv.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})))
+	newTable(t, ddb)
+
+	item := map[string]*dynamodb.AttributeValue{
+		"P": {S: aws.String("a")},
+		"R": {N: aws.String("1")},
+		"B": {B: []byte{0, 1, 2}},
+		"L": {L: []*dynamodb.AttributeValue{{BOOL: aws.Bool(true)}, {NULL: aws.Bool(true)}}},
+		"S": {SS: []*string{aws.String("x"), aws.String("y")}},
+	}
+	if _, err := ddb.PutItem(&dynamodb.PutItemInput{TableName: aws.String("test"), Item: item}); err != nil {
+		t.Fatal(err)
+	}
+	get, err := ddb.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String("test"),
+		Key:       map[string]*dynamodb.AttributeValue{"P": {S: aws.String("a")}, "R": {N: aws.String("1")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(get.Item, item) {
+		t.Fatal(pretty.Compare(get.Item, item))
+	}
+	query, err := ddb.Query(&dynamodb.QueryInput{
+		TableName:                 aws.String("test"),
+		KeyConditionExpression:    aws.String("P = :p"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":p": {S: aws.String("a")}},
+		ReturnConsumedCapacity:    aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.Int64Value(query.Count) != 1 || query.ConsumedCapacity == nil {
+		t.Fatalf("expected 1 item and consumed capacity, got %v", query)
+	}
+	desc, err := ddb.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("test")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.Table.CreationDateTime == nil || aws.StringValue(desc.Table.TableStatus) != dynamodb.TableStatusActive {
+		t.Fatalf("expected an active table with a creation time, got %v", desc.Table)
+	}
+
+	for _, tc := range []struct {
+		name string
+		call func() error
+		code string
+	}{
+		{
+			name: "resource not found",
+			call: func() error {
+				_, err := ddb.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String("missing")})
+				return err
+			},
+			code: dynamodb.ErrCodeResourceNotFoundException,
+		},
+		{
+			name: "conditional check failed",
+			call: func() error {
+				_, err := ddb.PutItem(&dynamodb.PutItemInput{
+					TableName:           aws.String("test"),
+					Item:                item,
+					ConditionExpression: aws.String("attribute_not_exists(P)"),
+				})
+				return err
+			},
+			code: dynamodb.ErrCodeConditionalCheckFailedException,
+		},
+		{
+			name: "unsupported operation",
+			call: func() error {
+				_, err := ddb.DescribeLimits(&dynamodb.DescribeLimitsInput{})
+				return err
+			},
+			code: "UnknownOperationException",
+		},
+	} {
+		if code := errCode(tc.call()); code != tc.code {
+			t.Fatalf("%s: expected error code %s, got %s", tc.name, tc.code, code)
+		}
+	}
+}
+
+func TestServerRequests(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(memdb.New())
+	defer srv.Close()
+	for _, tc := range []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		expect string
+	}{
+		{
+			name:   "list tables",
+			method: http.MethodPost,
+			target: "DynamoDB_20120810.ListTables",
+			body:   "{}",
+			status: http.StatusOK,
+			expect: `{"TableNames":[]}`,
+		},
+		{
+			name:   "get",
+			method: http.MethodGet,
+			target: "DynamoDB_20120810.ListTables",
+			status: http.StatusBadRequest,
+			expect: "#UnknownOperationException",
+		},
+		{
+			name:   "unknown target",
+			method: http.MethodPost,
+			target: "ListTables",
+			body:   "{}",
+			status: http.StatusBadRequest,
+			expect: "#UnknownOperationException",
+		},
+		{
+			name:   "invalid json",
+			method: http.MethodPost,
+			target: "DynamoDB_20120810.ListTables",
+			body:   "{",
+			status: http.StatusBadRequest,
+			expect: "#SerializationException",
+		},
+		{
+			name:   "invalid input",
+			method: http.MethodPost,
+			target: "DynamoDB_20120810.DescribeTable",
+			body:   "{}",
+			status: http.StatusBadRequest,
+			expect: "#ValidationException",
+		},
+	} {
+		req, err := http.NewRequest(tc.method, srv.URL, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Amz-Target", tc.target)
+		req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body bytes.Buffer
+		_, err = body.ReadFrom(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.status || !strings.Contains(body.String(), tc.expect) {
+			t.Fatalf("%s: expected %d containing %s, got %d %s", tc.name, tc.status, tc.expect, resp.StatusCode, body.String())
+		}
+	}
+}
@@ -0,0 +1,619 @@
+package memdb
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// keySchema names the hash and optional range key attributes of a table or index.
+type keySchema struct {
+	hash string
+	rng  string
+}
+
+func (k keySchema) names() []string {
+	if k.rng == "" {
+		return []string{k.hash}
+	}
+	return []string{k.hash, k.rng}
+}
+
+func (k keySchema) elements() []*dynamodb.KeySchemaElement {
+	ks := []*dynamodb.KeySchemaElement{{AttributeName: aws.String(k.hash), KeyType: aws.String(dynamodb.KeyTypeHash)}}
+	if k.rng != "" {
+		ks = append(ks, &dynamodb.KeySchemaElement{AttributeName: aws.String(k.rng), KeyType: aws.String(dynamodb.KeyTypeRange)})
+	}
+	return ks
+}
+
+// index is a global or local secondary index.
+type index struct {
+	name       string
+	keys       keySchema
+	projection *dynamodb.Projection
+	local      bool
+	throughput *dynamodb.ProvisionedThroughput
+}
+
+// projects reports whether an attribute is stored in the index.
+func (idx *index) projects(t *table, name string) bool {
+	if name == t.keys.hash || name == t.keys.rng || name == idx.keys.hash || name == idx.keys.rng {
+		return true
+	}
+	switch aws.StringValue(idx.projection.ProjectionType) {
+	case dynamodb.ProjectionTypeAll:
+		return true
+	case dynamodb.ProjectionTypeInclude:
+		for _, a := range idx.projection.NonKeyAttributes {
+			if aws.StringValue(a) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type table struct {
+	name        string
+	created     time.Time
+	keys        keySchema
+	attrs       map[string]string
+	billingMode string
+	throughput  *dynamodb.ProvisionedThroughput
+	gsis        []*index
+	lsis        []*index
+	stream      *dynamodb.StreamSpecification
+	streamLabel string
+	sse         *dynamodb.SSESpecification
+	ttl         *dynamodb.TimeToLiveSpecification
+	items       map[string]item
+}
+
+func (t *table) arn() string {
+	return "arn:aws:dynamodb:memdb:000000000000:table/" + t.name
+}
+
+func (t *table) index(name string) *index {
+	for _, idx := range append(append([]*index{}, t.gsis...), t.lsis...) {
+		if idx.name == name {
+			return idx
+		}
+	}
+	return nil
+}
+
+// key returns the primary key attributes of an item.
+func (t *table) key(it item) item {
+	key := item{t.keys.hash: it[t.keys.hash]}
+	if t.keys.rng != "" {
+		key[t.keys.rng] = it[t.keys.rng]
+	}
+	return key
+}
+
+// encodeKey returns the map key an item is stored under.
+func (t *table) encodeKey(it item) string {
+	h := encodeScalar(it[t.keys.hash])
+	if t.keys.rng == "" {
+		return h
+	}
+	return fmt.Sprintf("%d:%s%s", len(h), h, encodeScalar(it[t.keys.rng]))
+}
+
+// view returns the items of the table, or of an index when idx is set, in key order. Items are
+// projected to the attributes stored in the index.
+func (t *table) view(idx *index) []item {
+	keys := t.keys
+	if idx != nil {
+		keys = idx.keys
+	}
+	items := make([]item, 0, len(t.items))
+	for _, it := range t.items {
+		if it[keys.hash] == nil || keys.rng != "" && it[keys.rng] == nil {
+			continue
+		}
+		if idx != nil {
+			view := make(item, len(it))
+			for k, v := range it {
+				if idx.projects(t, k) {
+					view[k] = v
+				}
+			}
+			it = view
+		}
+		items = append(items, it)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return t.compareItems(idx, items[i], items[j]) < 0
+	})
+	return items
+}
+
+// compareItems orders items by the index key, when idx is set, and then the table key.
+func (t *table) compareItems(idx *index, a, b item) int {
+	names := t.keys.names()
+	if idx != nil {
+		names = append(idx.keys.names(), names...)
+	}
+	for _, name := range names {
+		if c, _ := compareValues(a[name], b[name]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// lastKey returns the LastEvaluatedKey for an item read from the table or index.
+func (t *table) lastKey(idx *index, it item) item {
+	key := t.key(it)
+	if idx != nil {
+		for _, name := range idx.keys.names() {
+			key[name] = it[name]
+		}
+	}
+	return copyItem(key)
+}
+
+// segment returns the parallel scan segment of an item.
+func (t *table) segment(it item, total int64) int64 {
+	h := fnv.New32a()
+	h.Write([]byte(encodeScalar(it[t.keys.hash])))
+	return int64(h.Sum32()) % total
+}
+
+func (t *table) describe(status string) *dynamodb.TableDescription {
+	size := 0
+	for _, it := range t.items {
+		size += itemSize(it)
+	}
+	var attrs []*dynamodb.AttributeDefinition
+	for _, name := range sortedNames(t.attrs) {
+		attrs = append(attrs, &dynamodb.AttributeDefinition{AttributeName: aws.String(name), AttributeType: aws.String(t.attrs[name])})
+	}
+	desc := &dynamodb.TableDescription{
+		TableName:            aws.String(t.name),
+		TableArn:             aws.String(t.arn()),
+		TableId:              aws.String(fmt.Sprintf("%08x-0000-0000-0000-000000000000", fnv32(t.name))),
+		TableStatus:          aws.String(status),
+		CreationDateTime:     aws.Time(t.created),
+		KeySchema:            t.keys.elements(),
+		AttributeDefinitions: attrs,
+		ItemCount:            aws.Int64(int64(len(t.items))),
+		TableSizeBytes:       aws.Int64(int64(size)),
+		BillingModeSummary:   &dynamodb.BillingModeSummary{BillingMode: aws.String(t.billingMode)},
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:      aws.Int64(0),
+			WriteCapacityUnits:     aws.Int64(0),
+			NumberOfDecreasesToday: aws.Int64(0),
+		},
+	}
+	if t.throughput != nil {
+		desc.ProvisionedThroughput.ReadCapacityUnits = t.throughput.ReadCapacityUnits
+		desc.ProvisionedThroughput.WriteCapacityUnits = t.throughput.WriteCapacityUnits
+	}
+	for _, idx := range t.gsis {
+		count, size := t.indexSize(idx)
+		gsi := &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:      aws.String(idx.name),
+			IndexArn:       aws.String(t.arn() + "/index/" + idx.name),
+			IndexStatus:    aws.String(dynamodb.IndexStatusActive),
+			KeySchema:      idx.keys.elements(),
+			Projection:     idx.projection,
+			ItemCount:      aws.Int64(count),
+			IndexSizeBytes: aws.Int64(size),
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:      aws.Int64(0),
+				WriteCapacityUnits:     aws.Int64(0),
+				NumberOfDecreasesToday: aws.Int64(0),
+			},
+		}
+		if idx.throughput != nil {
+			gsi.ProvisionedThroughput.ReadCapacityUnits = idx.throughput.ReadCapacityUnits
+			gsi.ProvisionedThroughput.WriteCapacityUnits = idx.throughput.WriteCapacityUnits
+		}
+		desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, gsi)
+	}
+	for _, idx := range t.lsis {
+		count, size := t.indexSize(idx)
+		desc.LocalSecondaryIndexes = append(desc.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndexDescription{
+			IndexName:      aws.String(idx.name),
+			IndexArn:       aws.String(t.arn() + "/index/" + idx.name),
+			KeySchema:      idx.keys.elements(),
+			Projection:     idx.projection,
+			ItemCount:      aws.Int64(count),
+			IndexSizeBytes: aws.Int64(size),
+		})
+	}
+	if aws.BoolValue(t.stream.StreamEnabled) {
+		desc.StreamSpecification = t.stream
+		desc.LatestStreamLabel = aws.String(t.streamLabel)
+		desc.LatestStreamArn = aws.String(t.arn() + "/stream/" + t.streamLabel)
+	}
+	if aws.BoolValue(t.sse.Enabled) {
+		sseType := aws.StringValue(t.sse.SSEType)
+		if sseType == "" {
+			sseType = dynamodb.SSETypeKms
+		}
+		desc.SSEDescription = &dynamodb.SSEDescription{
+			Status:  aws.String(dynamodb.SSEStatusEnabled),
+			SSEType: aws.String(sseType),
+		}
+		if sseType == dynamodb.SSETypeKms {
+			desc.SSEDescription.KMSMasterKeyArn = aws.String("arn:aws:kms:memdb:000000000000:key/" + aws.StringValue(t.sse.KMSMasterKeyId))
+		}
+	}
+	return desc
+}
+
+func (t *table) indexSize(idx *index) (count, size int64) {
+	for _, it := range t.view(idx) {
+		count++
+		size += int64(itemSize(it))
+	}
+	return count, size
+}
+
+func fnv32(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+func sortedNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (db *DB) table(name *string) (*table, *apiError) {
+	t, ok := db.tables[aws.StringValue(name)]
+	if !ok {
+		return nil, resourceNotFound(aws.StringValue(name))
+	}
+	return t, nil
+}
+
+// parseKeySchema checks a key schema has a hash key and an optional range key, each defined in
+// the attribute definitions.
+func parseKeySchema(ks []*dynamodb.KeySchemaElement, attrs map[string]string) (keySchema, *apiError) {
+	var k keySchema
+	if len(ks) == 0 || len(ks) > 2 || aws.StringValue(ks[0].KeyType) != dynamodb.KeyTypeHash ||
+		len(ks) == 2 && aws.StringValue(ks[1].KeyType) != dynamodb.KeyTypeRange {
+		return k, validationError("1 validation error detected: Value '%v' at 'keySchema' failed to satisfy constraint: Member must have a HASH key and an optional RANGE key", ks)
+	}
+	k.hash = aws.StringValue(ks[0].AttributeName)
+	if len(ks) == 2 {
+		k.rng = aws.StringValue(ks[1].AttributeName)
+		if k.rng == k.hash {
+			return k, validationError("Both the Hash Key and the Range Key element in the KeySchema have the same name")
+		}
+	}
+	for _, name := range k.names() {
+		if _, ok := attrs[name]; !ok {
+			return k, validationError("One or more parameter values were invalid: Some index key attributes are not defined in AttributeDefinitions. Keys: [%s], AttributeDefinitions: %v", name, sortedNames(attrs))
+		}
+	}
+	return k, nil
+}
+
+func parseProjection(p *dynamodb.Projection) (*dynamodb.Projection, *apiError) {
+	if p == nil || p.ProjectionType == nil {
+		return nil, validationError("One or more parameter values were invalid: Unknown ProjectionType: null")
+	}
+	switch aws.StringValue(p.ProjectionType) {
+	case dynamodb.ProjectionTypeAll, dynamodb.ProjectionTypeKeysOnly:
+		if len(p.NonKeyAttributes) > 0 {
+			return nil, validationError("One or more parameter values were invalid: ProjectionType is %s, but NonKeyAttributes is specified", aws.StringValue(p.ProjectionType))
+		}
+	case dynamodb.ProjectionTypeInclude:
+		if len(p.NonKeyAttributes) == 0 {
+			return nil, validationError("One or more parameter values were invalid: ProjectionType is INCLUDE, but NonKeyAttributes is not specified")
+		}
+	default:
+		return nil, validationError("One or more parameter values were invalid: Unknown ProjectionType: %s", aws.StringValue(p.ProjectionType))
+	}
+	out := &dynamodb.Projection{ProjectionType: aws.String(*p.ProjectionType)}
+	if len(p.NonKeyAttributes) > 0 {
+		out.NonKeyAttributes = aws.StringSlice(aws.StringValueSlice(p.NonKeyAttributes))
+	}
+	return out, nil
+}
+
+// checkThroughput checks throughput is set only when the billing mode is PROVISIONED.
+func checkThroughput(billingMode string, p *dynamodb.ProvisionedThroughput) *apiError {
+	if billingMode == dynamodb.BillingModePayPerRequest && p != nil {
+		return validationError("One or more parameter values were invalid: Neither ReadCapacityUnits nor WriteCapacityUnits can be specified when BillingMode is PAY_PER_REQUEST")
+	}
+	if billingMode == dynamodb.BillingModeProvisioned && p == nil {
+		return validationError("One or more parameter values were invalid: ReadCapacityUnits and WriteCapacityUnits must both be specified when BillingMode is PROVISIONED")
+	}
+	return nil
+}
+
+func copyThroughput(p *dynamodb.ProvisionedThroughput) *dynamodb.ProvisionedThroughput {
+	if p == nil {
+		return nil
+	}
+	return &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(aws.Int64Value(p.ReadCapacityUnits)),
+		WriteCapacityUnits: aws.Int64(aws.Int64Value(p.WriteCapacityUnits)),
+	}
+}
+
+func (db *DB) createTable(in *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, *apiError) {
+	name := aws.StringValue(in.TableName)
+	if _, ok := db.tables[name]; ok {
+		return nil, resourceInUse(name)
+	}
+	t := &table{
+		name:        name,
+		created:     db.Now().UTC(),
+		attrs:       make(map[string]string),
+		billingMode: dynamodb.BillingModeProvisioned,
+		throughput:  copyThroughput(in.ProvisionedThroughput),
+		stream:      &dynamodb.StreamSpecification{StreamEnabled: aws.Bool(false)},
+		sse:         &dynamodb.SSESpecification{Enabled: aws.Bool(false)},
+		ttl:         &dynamodb.TimeToLiveSpecification{Enabled: aws.Bool(false)},
+		items:       make(map[string]item),
+	}
+	if in.BillingMode != nil {
+		t.billingMode = *in.BillingMode
+	}
+	if err := checkThroughput(t.billingMode, in.ProvisionedThroughput); err != nil {
+		return nil, err
+	}
+	for _, a := range in.AttributeDefinitions {
+		t.attrs[aws.StringValue(a.AttributeName)] = aws.StringValue(a.AttributeType)
+	}
+	var err *apiError
+	if t.keys, err = parseKeySchema(in.KeySchema, t.attrs); err != nil {
+		return nil, err
+	}
+	used := map[string]bool{t.keys.hash: true, t.keys.rng: true}
+	names := make(map[string]bool)
+	for _, g := range in.GlobalSecondaryIndexes {
+		idx, err := t.newIndex(g.IndexName, g.KeySchema, g.Projection, names)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkThroughput(t.billingMode, g.ProvisionedThroughput); err != nil {
+			return nil, err
+		}
+		idx.throughput = copyThroughput(g.ProvisionedThroughput)
+		used[idx.keys.hash], used[idx.keys.rng] = true, true
+		t.gsis = append(t.gsis, idx)
+	}
+	for _, l := range in.LocalSecondaryIndexes {
+		idx, err := t.newIndex(l.IndexName, l.KeySchema, l.Projection, names)
+		if err != nil {
+			return nil, err
+		}
+		if idx.keys.hash != t.keys.hash || idx.keys.rng == "" || t.keys.rng == "" {
+			return nil, validationError("One or more parameter values were invalid: Index KeySchema does not have the same leading hash key as table KeySchema for index: %s", idx.name)
+		}
+		idx.local = true
+		used[idx.keys.rng] = true
+		t.lsis = append(t.lsis, idx)
+	}
+	for name := range t.attrs {
+		if !used[name] {
+			return nil, validationError("One or more parameter values were invalid: Number of attributes in KeySchema does not exactly match number of attributes defined in AttributeDefinitions")
+		}
+	}
+	if in.StreamSpecification != nil && aws.BoolValue(in.StreamSpecification.StreamEnabled) {
+		if err := t.updateStream(in.StreamSpecification, t.created); err != nil {
+			return nil, err
+		}
+	}
+	if in.SSESpecification != nil {
+		t.sse = in.SSESpecification
+	}
+	db.tables[name] = t
+	return &dynamodb.CreateTableOutput{TableDescription: t.describe(dynamodb.TableStatusActive)}, nil
+}
+
+func (t *table) newIndex(name *string, ks []*dynamodb.KeySchemaElement, p *dynamodb.Projection, names map[string]bool) (*index, *apiError) {
+	idx := &index{name: aws.StringValue(name)}
+	if names[idx.name] || t.index(idx.name) != nil {
+		return nil, validationError("One or more parameter values were invalid: Duplicate index name: %s", idx.name)
+	}
+	names[idx.name] = true
+	var err *apiError
+	if idx.keys, err = parseKeySchema(ks, t.attrs); err != nil {
+		return nil, err
+	}
+	if idx.projection, err = parseProjection(p); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func (t *table) updateStream(s *dynamodb.StreamSpecification, now time.Time) *apiError {
+	enabled := aws.BoolValue(s.StreamEnabled)
+	switch {
+	case enabled && aws.BoolValue(t.stream.StreamEnabled):
+		return validationError("Table already has an enabled stream: TableName: %s", t.name)
+	case enabled && s.StreamViewType == nil:
+		return validationError("One or more parameter values were invalid: StreamViewType must be specified when StreamEnabled is true")
+	case !enabled && !aws.BoolValue(t.stream.StreamEnabled):
+		return validationError("Table already has a disabled stream: TableName: %s", t.name)
+	case !enabled && s.StreamViewType != nil:
+		return validationError("One or more parameter values were invalid: Stream view type cannot be specified when stream is disabled")
+	}
+	t.stream = &dynamodb.StreamSpecification{StreamEnabled: aws.Bool(enabled)}
+	if enabled {
+		t.stream.StreamViewType = aws.String(*s.StreamViewType)
+		t.streamLabel = now.UTC().Format("2006-01-02T15:04:05.000")
+	}
+	return nil
+}
+
+func (db *DB) describeTable(in *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, *apiError) {
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.DescribeTableOutput{Table: t.describe(dynamodb.TableStatusActive)}, nil
+}
+
+func (db *DB) deleteTable(in *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, *apiError) {
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	delete(db.tables, t.name)
+	return &dynamodb.DeleteTableOutput{TableDescription: t.describe(dynamodb.TableStatusDeleting)}, nil
+}
+
+func (db *DB) listTables(in *dynamodb.ListTablesInput) (*dynamodb.ListTablesOutput, *apiError) {
+	limit := int(aws.Int64Value(in.Limit))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	names := make([]string, 0, len(db.tables))
+	for name := range db.tables {
+		if name > aws.StringValue(in.ExclusiveStartTableName) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	out := &dynamodb.ListTablesOutput{TableNames: []*string{}}
+	if len(names) > limit {
+		names = names[:limit]
+		out.LastEvaluatedTableName = aws.String(names[limit-1])
+	}
+	out.TableNames = aws.StringSlice(names)
+	return out, nil
+}
+
+func (db *DB) updateTable(in *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, *apiError) {
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if in.BillingMode == nil && in.ProvisionedThroughput == nil && len(in.GlobalSecondaryIndexUpdates) == 0 &&
+		in.StreamSpecification == nil && in.SSESpecification == nil {
+		return nil, validationError("At least one of ProvisionedThroughput, BillingMode, UpdateStreamEnabled, GlobalSecondaryIndexUpdates or SSESpecification or ReplicaUpdates is required")
+	}
+	// Changes are made to a copy so a failed update leaves the table unchanged
+	u := *t
+	u.attrs = make(map[string]string, len(t.attrs))
+	for k, v := range t.attrs {
+		u.attrs[k] = v
+	}
+	u.gsis = append([]*index{}, t.gsis...)
+	for _, a := range in.AttributeDefinitions {
+		u.attrs[aws.StringValue(a.AttributeName)] = aws.StringValue(a.AttributeType)
+	}
+	if in.BillingMode != nil {
+		u.billingMode = *in.BillingMode
+		if u.billingMode == dynamodb.BillingModePayPerRequest {
+			u.throughput = nil
+			for i, idx := range u.gsis {
+				c := *idx
+				c.throughput = nil
+				u.gsis[i] = &c
+			}
+		}
+	}
+	if in.ProvisionedThroughput != nil || in.BillingMode != nil {
+		if in.ProvisionedThroughput != nil {
+			u.throughput = copyThroughput(in.ProvisionedThroughput)
+		}
+		if err := checkThroughput(u.billingMode, u.throughput); err != nil {
+			return nil, err
+		}
+	}
+	for _, g := range in.GlobalSecondaryIndexUpdates {
+		switch {
+		case g.Create != nil:
+			idx, err := u.newIndex(g.Create.IndexName, g.Create.KeySchema, g.Create.Projection, map[string]bool{})
+			if err != nil {
+				return nil, err
+			}
+			if err := checkThroughput(u.billingMode, g.Create.ProvisionedThroughput); err != nil {
+				return nil, err
+			}
+			idx.throughput = copyThroughput(g.Create.ProvisionedThroughput)
+			u.gsis = append(u.gsis, idx)
+		case g.Delete != nil:
+			found := false
+			for i, idx := range u.gsis {
+				if idx.name == aws.StringValue(g.Delete.IndexName) {
+					u.gsis = append(u.gsis[:i:i], u.gsis[i+1:]...)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, &apiError{code: dynamodb.ErrCodeResourceNotFoundException, message: "Requested resource not found: Index: " + aws.StringValue(g.Delete.IndexName) + " not found", status: 400}
+			}
+		case g.Update != nil:
+			found := false
+			for i, idx := range u.gsis {
+				if idx.name == aws.StringValue(g.Update.IndexName) {
+					if err := checkThroughput(u.billingMode, g.Update.ProvisionedThroughput); err != nil {
+						return nil, err
+					}
+					c := *idx
+					c.throughput = copyThroughput(g.Update.ProvisionedThroughput)
+					u.gsis[i] = &c
+					found = true
+				}
+			}
+			if !found {
+				return nil, &apiError{code: dynamodb.ErrCodeResourceNotFoundException, message: "Requested resource not found: Index: " + aws.StringValue(g.Update.IndexName) + " not found", status: 400}
+			}
+		}
+	}
+	if in.StreamSpecification != nil {
+		if err := u.updateStream(in.StreamSpecification, db.Now()); err != nil {
+			return nil, err
+		}
+	}
+	if in.SSESpecification != nil {
+		u.sse = in.SSESpecification
+	}
+	*t = u
+	return &dynamodb.UpdateTableOutput{TableDescription: t.describe(dynamodb.TableStatusActive)}, nil
+}
+
+func (db *DB) describeTimeToLive(in *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, *apiError) {
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	desc := &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled)}
+	if aws.BoolValue(t.ttl.Enabled) {
+		desc.TimeToLiveStatus = aws.String(dynamodb.TimeToLiveStatusEnabled)
+		desc.AttributeName = t.ttl.AttributeName
+	}
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: desc}, nil
+}
+
+func (db *DB) updateTimeToLive(in *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, *apiError) {
+	t, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	enabled := aws.BoolValue(in.TimeToLiveSpecification.Enabled)
+	if enabled == aws.BoolValue(t.ttl.Enabled) {
+		if enabled {
+			return nil, validationError("TimeToLive is already enabled")
+		}
+		return nil, validationError("TimeToLive is already disabled")
+	}
+	t.ttl = &dynamodb.TimeToLiveSpecification{
+		Enabled:       aws.Bool(enabled),
+		AttributeName: aws.String(aws.StringValue(in.TimeToLiveSpecification.AttributeName)),
+	}
+	return &dynamodb.UpdateTimeToLiveOutput{TimeToLiveSpecification: t.ttl}, nil
+}
@@ -0,0 +1,328 @@
+package memdb
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// item is a stored item. Stored items are never modified, writes replace them with a copy.
+type item = map[string]*dynamodb.AttributeValue
+
+func copyItem(it item) item {
+	if it == nil {
+		return nil
+	}
+	out := make(item, len(it))
+	for k, v := range it {
+		out[k] = copyValue(v)
+	}
+	return out
+}
+
+func copyValue(v *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if v == nil {
+		return nil
+	}
+	out := &dynamodb.AttributeValue{}
+	switch {
+	case v.S != nil:
+		out.S = aws.String(*v.S)
+	case v.N != nil:
+		out.N = aws.String(*v.N)
+	case v.B != nil:
+		out.B = append([]byte{}, v.B...)
+	case v.BOOL != nil:
+		out.BOOL = aws.Bool(*v.BOOL)
+	case v.NULL != nil:
+		out.NULL = aws.Bool(*v.NULL)
+	case v.SS != nil:
+		out.SS = aws.StringSlice(aws.StringValueSlice(v.SS))
+	case v.NS != nil:
+		out.NS = aws.StringSlice(aws.StringValueSlice(v.NS))
+	case v.BS != nil:
+		out.BS = make([][]byte, len(v.BS))
+		for i, b := range v.BS {
+			out.BS[i] = append([]byte{}, b...)
+		}
+	case v.L != nil:
+		out.L = make([]*dynamodb.AttributeValue, len(v.L))
+		for i, e := range v.L {
+			out.L[i] = copyValue(e)
+		}
+	case v.M != nil:
+		out.M = copyItem(v.M)
+	}
+	return out
+}
+
+// valueType returns the dynamodb type of a value, or "" if it has no value set.
+func valueType(v *dynamodb.AttributeValue) string {
+	switch {
+	case v == nil:
+		return ""
+	case v.S != nil:
+		return "S"
+	case v.N != nil:
+		return "N"
+	case v.B != nil:
+		return "B"
+	case v.BOOL != nil:
+		return "BOOL"
+	case v.NULL != nil:
+		return "NULL"
+	case v.SS != nil:
+		return "SS"
+	case v.NS != nil:
+		return "NS"
+	case v.BS != nil:
+		return "BS"
+	case v.L != nil:
+		return "L"
+	case v.M != nil:
+		return "M"
+	}
+	return ""
+}
+
+// validateValue checks a value has exactly one type set, numbers are valid and sets are not empty
+// and have no duplicates.
+func validateValue(v *dynamodb.AttributeValue) *apiError {
+	if v == nil {
+		return validationError("Supplied AttributeValue is empty, must contain exactly one of the supported datatypes")
+	}
+	set := 0
+	for _, ok := range []bool{v.S != nil, v.N != nil, v.B != nil, v.BOOL != nil, v.NULL != nil, v.SS != nil, v.NS != nil, v.BS != nil, v.L != nil, v.M != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return validationError("Supplied AttributeValue has more than one datatypes set, must contain exactly one of the supported datatypes")
+	}
+	switch valueType(v) {
+	case "N":
+		if _, ok := parseNumber(*v.N); !ok {
+			return validationError("A value provided cannot be converted into a number")
+		}
+	case "NULL":
+		if !*v.NULL {
+			return validationError("One or more parameter values were invalid: Null attribute value types must have the value of true")
+		}
+	case "SS", "NS", "BS":
+		n := len(v.SS) + len(v.NS) + len(v.BS)
+		if n == 0 {
+			return validationError("One or more parameter values were invalid: An %s set may not be empty", setTypeNames[valueType(v)])
+		}
+		seen := make(map[string]bool, n)
+		for _, e := range setElements(v) {
+			if e.N != nil {
+				if _, ok := parseNumber(*e.N); !ok {
+					return validationError("A value provided cannot be converted into a number")
+				}
+			}
+			k := encodeScalar(e)
+			if seen[k] {
+				return validationError("One or more parameter values were invalid: Input collection contains duplicates")
+			}
+			seen[k] = true
+		}
+	case "L":
+		for _, e := range v.L {
+			if err := validateValue(e); err != nil {
+				return err
+			}
+		}
+	case "M":
+		for _, e := range v.M {
+			if err := validateValue(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var setTypeNames = map[string]string{"SS": "string", "NS": "number", "BS": "binary"}
+
+func parseNumber(n string) (*big.Rat, bool) {
+	return new(big.Rat).SetString(strings.TrimSpace(n))
+}
+
+// formatNumber writes a number as an integer or the shortest exact decimal.
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	ten := big.NewInt(10)
+	pow := big.NewInt(1)
+	mod := new(big.Int)
+	for digits := 1; digits <= 40; digits++ {
+		pow.Mul(pow, ten)
+		if mod.Mod(pow, r.Denom()).Sign() == 0 {
+			return r.FloatString(digits)
+		}
+	}
+	return r.FloatString(38)
+}
+
+// setElements returns the elements of a set as scalar values.
+func setElements(v *dynamodb.AttributeValue) []*dynamodb.AttributeValue {
+	var out []*dynamodb.AttributeValue
+	for _, s := range v.SS {
+		out = append(out, &dynamodb.AttributeValue{S: s})
+	}
+	for _, n := range v.NS {
+		out = append(out, &dynamodb.AttributeValue{N: n})
+	}
+	for _, b := range v.BS {
+		out = append(out, &dynamodb.AttributeValue{B: b})
+	}
+	return out
+}
+
+// encodeScalar returns a string which is equal for equal S, N or B values, so "1" and "1.0" are
+// the same number.
+func encodeScalar(v *dynamodb.AttributeValue) string {
+	switch {
+	case v.S != nil:
+		return "S" + *v.S
+	case v.N != nil:
+		if r, ok := parseNumber(*v.N); ok {
+			return "N" + r.RatString()
+		}
+		return "N" + *v.N
+	case v.B != nil:
+		return "B" + string(v.B)
+	}
+	return ""
+}
+
+// equalValues reports whether two values are equal as dynamodb compares them. Numbers are compared
+// by value and sets ignore order.
+func equalValues(a, b *dynamodb.AttributeValue) bool {
+	t := valueType(a)
+	if t != valueType(b) {
+		return false
+	}
+	switch t {
+	case "S", "N", "B":
+		return encodeScalar(a) == encodeScalar(b)
+	case "BOOL":
+		return *a.BOOL == *b.BOOL
+	case "NULL":
+		return true
+	case "SS", "NS", "BS":
+		ae, be := setElements(a), setElements(b)
+		if len(ae) != len(be) {
+			return false
+		}
+		seen := make(map[string]bool, len(ae))
+		for _, e := range ae {
+			seen[encodeScalar(e)] = true
+		}
+		for _, e := range be {
+			if !seen[encodeScalar(e)] {
+				return false
+			}
+		}
+		return true
+	case "L":
+		if len(a.L) != len(b.L) {
+			return false
+		}
+		for i := range a.L {
+			if !equalValues(a.L[i], b.L[i]) {
+				return false
+			}
+		}
+		return true
+	case "M":
+		if len(a.M) != len(b.M) {
+			return false
+		}
+		for k, v := range a.M {
+			if !equalValues(v, b.M[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compareValues orders two S, N or B values of the same type. ok is false if they cannot be ordered.
+func compareValues(a, b *dynamodb.AttributeValue) (c int, ok bool) {
+	t := valueType(a)
+	if t != valueType(b) {
+		return 0, false
+	}
+	switch t {
+	case "S":
+		return strings.Compare(*a.S, *b.S), true
+	case "N":
+		ar, aok := parseNumber(*a.N)
+		br, bok := parseNumber(*b.N)
+		if !aok || !bok {
+			return 0, false
+		}
+		return ar.Cmp(br), true
+	case "B":
+		return bytes.Compare(a.B, b.B), true
+	}
+	return 0, false
+}
+
+// itemSize is the size of an item as dynamodb counts it, see dynamodbx.ItemSize.
+func itemSize(it item) int {
+	size := 0
+	for k, v := range it {
+		size += len(k) + valueSize(v)
+	}
+	return size
+}
+
+func valueSize(v *dynamodb.AttributeValue) int {
+	switch valueType(v) {
+	case "S":
+		return len(*v.S)
+	case "N":
+		return numberSize(*v.N)
+	case "B":
+		return len(v.B)
+	case "BOOL", "NULL":
+		return 1
+	case "SS", "NS", "BS":
+		size := 0
+		for _, e := range setElements(v) {
+			size += valueSize(e)
+		}
+		return size
+	case "L":
+		size := 3
+		for _, e := range v.L {
+			size += 1 + valueSize(e)
+		}
+		return size
+	case "M":
+		size := 3
+		for k, e := range v.M {
+			size += 1 + len(k) + valueSize(e)
+		}
+		return size
+	}
+	return 0
+}
+
+func numberSize(n string) int {
+	digits := 0
+	for _, r := range strings.TrimLeft(strings.TrimLeft(n, "-+"), "0.") {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return (digits+1)/2 + 1
+}
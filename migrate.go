@@ -0,0 +1,360 @@
+package dynamodbx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrMigrationTable   = errors.New("dynamodbx/Migrate: metadata table name cannot be empty")
+	ErrMigrationVersion = errors.New("dynamodbx/Migrate: migration versions must be positive and strictly increasing")
+	ErrMigrationStep    = errors.New("dynamodbx/Migrate: migration step cannot be nil")
+)
+
+// Attribute names of the metadata item which records the applied migration version.
+const (
+	migrationKeyAttr     = "ID"
+	migrationVersionAttr = "Version"
+	migrationPendingAttr = "Pending"
+)
+
+// MigrationStep is a single schema change. Apply must block until the change has completed so that
+// the next step sees the table in a usable state.
+type MigrationStep interface {
+	Apply(ctx context.Context, client *dynamodb.DynamoDB, opts ...request.Option) error
+}
+
+// Migration is a versioned MigrationStep. Versions must be positive and strictly increasing within a
+// MigrateInput, they do not need to be contiguous.
+type Migration struct {
+	Version     int64
+	Description string
+	Step        MigrationStep
+}
+
+// MigrateInput describes a set of migrations and where their progress is recorded.
+type MigrateInput struct {
+	// MetadataTable is the table holding the applied version. It is created with on demand billing
+	// if it does not exist yet.
+	MetadataTable string
+	// ID is the key of the metadata item, allowing several migration sets to share a metadata
+	// table. Defaults to "default".
+	ID string
+	// Migrations are applied in order, skipping any with a version already recorded.
+	Migrations []Migration
+	// PollInterval is how often a deployer waiting on another deployer re-reads the metadata item.
+	// Defaults to one second.
+	PollInterval time.Duration
+}
+
+// MigrateOutput reports the versions before and after a call to Migrate.
+type MigrateOutput struct {
+	PreviousVersion int64
+	Version         int64
+	// Applied lists the versions applied by this call, versions applied concurrently by other
+	// deployers are not included.
+	Applied []int64
+}
+
+// Migrate applies every migration with a version greater than the one recorded in the metadata table.
+//
+// Before a step runs it is claimed by conditionally writing its version to the Pending attribute of
+// the metadata item, so concurrent deployers never apply the same step twice. A deployer which finds
+// a step claimed waits for it to complete and then carries on from the new version. If a step fails
+// the claim is released and the error is returned. If a deployer dies mid step the Pending attribute
+// remains and must be removed by hand once the step has been checked.
+func Migrate(client *dynamodb.DynamoDB, input *MigrateInput) (*MigrateOutput, error) {
+	return MigrateWithContext(context.Background(), client, input)
+}
+
+// MigrateWithContext is the same as Migrate.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func MigrateWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *MigrateInput, opts ...request.Option) (*MigrateOutput, error) {
+	if input.MetadataTable == "" {
+		return nil, ErrMigrationTable
+	}
+	var last int64
+	for _, m := range input.Migrations {
+		if m.Version <= last {
+			return nil, ErrMigrationVersion
+		}
+		if m.Step == nil {
+			return nil, ErrMigrationStep
+		}
+		last = m.Version
+	}
+	id := input.ID
+	if id == "" {
+		id = "default"
+	}
+	poll := input.PollInterval
+	if poll <= 0 {
+		poll = time.Second
+	}
+
+	if err := ensureMigrationTable(ctx, client, input.MetadataTable, opts...); err != nil {
+		return nil, err
+	}
+	version, _, err := migrationState(ctx, client, input.MetadataTable, id, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out := &MigrateOutput{PreviousVersion: version, Version: version}
+
+	for _, m := range input.Migrations {
+		for {
+			version, pending, err := migrationState(ctx, client, input.MetadataTable, id, opts...)
+			if err != nil {
+				return out, err
+			}
+			out.Version = version
+			if version >= m.Version {
+				break
+			}
+			if pending {
+				if err := aws.SleepWithContext(ctx, poll); err != nil {
+					return out, err
+				}
+				continue
+			}
+			claimed, err := claimMigration(ctx, client, input.MetadataTable, id, version, m.Version, opts...)
+			if err != nil {
+				return out, err
+			}
+			if !claimed {
+				continue
+			}
+			if err := m.Step.Apply(ctx, client, opts...); err != nil {
+				// The step error is more useful than any error releasing the claim
+				releaseMigration(ctx, client, input.MetadataTable, id, m.Version, false, opts...)
+				return out, err
+			}
+			if err := releaseMigration(ctx, client, input.MetadataTable, id, m.Version, true, opts...); err != nil {
+				return out, err
+			}
+			out.Version = m.Version
+			out.Applied = append(out.Applied, m.Version)
+			break
+		}
+	}
+	return out, nil
+}
+
+// ensureMigrationTable creates the metadata table if it does not exist and waits for it to be active.
+func ensureMigrationTable(ctx context.Context, client *dynamodb.DynamoDB, table string, opts ...request.Option) error {
+	_, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)}, opts...)
+	if err == nil {
+		return waitTableActive(ctx, client, table, opts...)
+	}
+	if !isErrCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+		return err
+	}
+	_, err = client.CreateTableWithContext(ctx, &dynamodb.CreateTableInput{
+		TableName:   aws.String(table),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{
+				AttributeName: aws.String(migrationKeyAttr),
+				AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+			},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{
+				AttributeName: aws.String(migrationKeyAttr),
+				KeyType:       aws.String(dynamodb.KeyTypeHash),
+			},
+		},
+	}, opts...)
+	// Another deployer may have created the table between the describe and create calls
+	if err != nil && !isErrCode(err, dynamodb.ErrCodeResourceInUseException) {
+		return err
+	}
+	return waitTableActive(ctx, client, table, opts...)
+}
+
+// migrationState reads the applied version and whether a step is currently claimed.
+func migrationState(ctx context.Context, client *dynamodb.DynamoDB, table, id string, opts ...request.Option) (int64, bool, error) {
+	out, err := client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(table),
+		ConsistentRead: aws.Bool(true),
+		Key: map[string]*dynamodb.AttributeValue{
+			migrationKeyAttr: {S: aws.String(id)},
+		},
+	}, opts...)
+	if err != nil {
+		return 0, false, err
+	}
+	var version int64
+	if v, ok := out.Item[migrationVersionAttr]; ok && v.N != nil {
+		version, err = strconv.ParseInt(*v.N, 10, 64)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	_, pending := out.Item[migrationPendingAttr]
+	return version, pending, nil
+}
+
+// claimMigration marks version next as pending provided the applied version is still current and no
+// other step is pending. It reports false if another deployer got there first.
+func claimMigration(ctx context.Context, client *dynamodb.DynamoDB, table, id string, current, next int64, opts ...request.Option) (bool, error) {
+	cond := "attribute_not_exists(#p) AND #v = :cur"
+	values := map[string]*dynamodb.AttributeValue{
+		":cur":  {N: aws.String(strconv.FormatInt(current, 10))},
+		":next": {N: aws.String(strconv.FormatInt(next, 10))},
+	}
+	if current == 0 {
+		cond = "attribute_not_exists(#p) AND attribute_not_exists(#v)"
+		delete(values, ":cur")
+	}
+	_, err := client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key: map[string]*dynamodb.AttributeValue{
+			migrationKeyAttr: {S: aws.String(id)},
+		},
+		UpdateExpression:    aws.String("SET #p = :next"),
+		ConditionExpression: aws.String(cond),
+		ExpressionAttributeNames: map[string]*string{
+			"#p": aws.String(migrationPendingAttr),
+			"#v": aws.String(migrationVersionAttr),
+		},
+		ExpressionAttributeValues: values,
+	}, opts...)
+	if isErrCode(err, dynamodb.ErrCodeConditionalCheckFailedException) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// releaseMigration removes the claim on version. If applied is true the version is recorded as applied.
+func releaseMigration(ctx context.Context, client *dynamodb.DynamoDB, table, id string, version int64, applied bool, opts ...request.Option) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key: map[string]*dynamodb.AttributeValue{
+			migrationKeyAttr: {S: aws.String(id)},
+		},
+		UpdateExpression:    aws.String("REMOVE #p"),
+		ConditionExpression: aws.String("#p = :v"),
+		ExpressionAttributeNames: map[string]*string{
+			"#p": aws.String(migrationPendingAttr),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":v": {N: aws.String(strconv.FormatInt(version, 10))},
+		},
+	}
+	if applied {
+		input.UpdateExpression = aws.String("SET #v = :v REMOVE #p")
+		input.ExpressionAttributeNames["#v"] = aws.String(migrationVersionAttr)
+	}
+	_, err := client.UpdateItemWithContext(ctx, input, opts...)
+	return err
+}
+
+// isErrCode reports whether err is an aws error with the given code.
+func isErrCode(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
+
+// CreateTableStep creates a table and waits for it to become active.
+type CreateTableStep struct {
+	Input *dynamodb.CreateTableInput
+}
+
+// Apply implements MigrationStep.
+func (s CreateTableStep) Apply(ctx context.Context, client *dynamodb.DynamoDB, opts ...request.Option) error {
+	_, err := CreateTableSyncWithContext(ctx, client, s.Input, opts...)
+	return err
+}
+
+// AddGlobalSecondaryIndexStep adds a global secondary index and waits for the backfill to finish.
+// AttributeDefinitions must include the key attributes of the new index.
+type AddGlobalSecondaryIndexStep struct {
+	TableName            string
+	AttributeDefinitions []*dynamodb.AttributeDefinition
+	Index                *dynamodb.CreateGlobalSecondaryIndexAction
+}
+
+// Apply implements MigrationStep.
+func (s AddGlobalSecondaryIndexStep) Apply(ctx context.Context, client *dynamodb.DynamoDB, opts ...request.Option) error {
+	_, err := UpdateTableSyncWithContext(ctx, client, &dynamodb.UpdateTableInput{
+		TableName:            aws.String(s.TableName),
+		AttributeDefinitions: s.AttributeDefinitions,
+		GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+			{Create: s.Index},
+		},
+	}, opts...)
+	return err
+}
+
+// DeleteGlobalSecondaryIndexStep deletes a global secondary index and waits until it has gone.
+type DeleteGlobalSecondaryIndexStep struct {
+	TableName string
+	IndexName string
+}
+
+// Apply implements MigrationStep.
+func (s DeleteGlobalSecondaryIndexStep) Apply(ctx context.Context, client *dynamodb.DynamoDB, opts ...request.Option) error {
+	_, err := UpdateTableSyncWithContext(ctx, client, &dynamodb.UpdateTableInput{
+		TableName: aws.String(s.TableName),
+		GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+			{Delete: &dynamodb.DeleteGlobalSecondaryIndexAction{IndexName: aws.String(s.IndexName)}},
+		},
+	}, opts...)
+	return err
+}
+
+// EnableTTLStep enables time to live on AttributeName and waits until it is enabled.
+type EnableTTLStep struct {
+	TableName     string
+	AttributeName string
+}
+
+// Apply implements MigrationStep.
+func (s EnableTTLStep) Apply(ctx context.Context, client *dynamodb.DynamoDB, opts ...request.Option) error {
+	_, err := EnableTTLSyncWithContext(ctx, client, s.TableName, s.AttributeName, opts...)
+	return err
+}
+
+// EnableStreamStep enables a stream with the given StreamViewType and waits for the table to be active.
+type EnableStreamStep struct {
+	TableName      string
+	StreamViewType string
+}
+
+// Apply implements MigrationStep.
+func (s EnableStreamStep) Apply(ctx context.Context, client *dynamodb.DynamoDB, opts ...request.Option) error {
+	_, err := UpdateTableSyncWithContext(ctx, client, &dynamodb.UpdateTableInput{
+		TableName: aws.String(s.TableName),
+		StreamSpecification: &dynamodb.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: aws.String(s.StreamViewType),
+		},
+	}, opts...)
+	return err
+}
+
+// ChangeBillingModeStep switches the table billing mode. ProvisionedThroughput is required when
+// switching to PROVISIONED and must be nil when switching to PAY_PER_REQUEST.
+type ChangeBillingModeStep struct {
+	TableName             string
+	BillingMode           string
+	ProvisionedThroughput *dynamodb.ProvisionedThroughput
+}
+
+// Apply implements MigrationStep.
+func (s ChangeBillingModeStep) Apply(ctx context.Context, client *dynamodb.DynamoDB, opts ...request.Option) error {
+	_, err := UpdateTableSyncWithContext(ctx, client, &dynamodb.UpdateTableInput{
+		TableName:             aws.String(s.TableName),
+		BillingMode:           aws.String(s.BillingMode),
+		ProvisionedThroughput: s.ProvisionedThroughput,
+	}, opts...)
+	return err
+}
@@ -0,0 +1,142 @@
+package dynamodbx_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+type failStep struct{}
+
+func (failStep) Apply(ctx context.Context, client *dynamodb.DynamoDB, opts ...request.Option) error {
+	return errors.New("step failed")
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+	createStep := func(table string) dynamodbx.MigrationStep {
+		return dynamodbx.CreateTableStep{Input: &dynamodb.CreateTableInput{
+			TableName:   aws.String(table),
+			BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				{
+					AttributeName: aws.String("S"),
+					AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+				},
+			},
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{
+					AttributeName: aws.String("S"),
+					KeyType:       aws.String(dynamodb.KeyTypeHash),
+				},
+			},
+		}}
+	}
+	for _, tc := range []struct {
+		name    string
+		input   *dynamodbx.MigrateInput
+		err     error
+		errText string
+		expect  *dynamodbx.MigrateOutput
+		// rerun applies the same input a second time and expects nothing to be applied
+		rerun bool
+	}{
+		{
+			name:  "empty metadata table",
+			input: &dynamodbx.MigrateInput{},
+			err:   dynamodbx.ErrMigrationTable,
+		},
+		{
+			name: "versions out of order",
+			input: &dynamodbx.MigrateInput{
+				MetadataTable: "migrations",
+				Migrations: []dynamodbx.Migration{
+					{Version: 2, Step: failStep{}},
+					{Version: 1, Step: failStep{}},
+				},
+			},
+			err: dynamodbx.ErrMigrationVersion,
+		},
+		{
+			name: "nil step",
+			input: &dynamodbx.MigrateInput{
+				MetadataTable: "migrations",
+				Migrations:    []dynamodbx.Migration{{Version: 1}},
+			},
+			err: dynamodbx.ErrMigrationStep,
+		},
+		{
+			name: "apply migrations once",
+			input: &dynamodbx.MigrateInput{
+				MetadataTable: "migrationsApply",
+				Migrations: []dynamodbx.Migration{
+					{Version: 1, Step: createStep("migrateA")},
+					{Version: 3, Step: dynamodbx.EnableStreamStep{
+						TableName:      "migrateA",
+						StreamViewType: dynamodb.StreamViewTypeNewImage,
+					}},
+				},
+			},
+			expect: &dynamodbx.MigrateOutput{Version: 3, Applied: []int64{1, 3}},
+			rerun:  true,
+		},
+		{
+			name: "failed step is not recorded",
+			input: &dynamodbx.MigrateInput{
+				MetadataTable: "migrationsFail",
+				Migrations: []dynamodbx.Migration{
+					{Version: 1, Step: createStep("migrateB")},
+					{Version: 2, Step: failStep{}},
+				},
+			},
+			errText: "step failed",
+			expect:  &dynamodbx.MigrateOutput{Version: 1, Applied: []int64{1}},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ddb := memdb.NewClient()
+			if tc.err == nil {
+				defer ddb.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(tc.input.MetadataTable)})
+			}
+			for _, m := range tc.input.Migrations {
+				if s, ok := m.Step.(dynamodbx.CreateTableStep); ok {
+					defer ddb.DeleteTable(&dynamodb.DeleteTableInput{TableName: s.Input.TableName})
+				}
+			}
+			out, err := dynamodbx.Migrate(ddb, tc.input)
+			if tc.err != nil && tc.err != err {
+				t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+			}
+			if tc.errText != "" && (err == nil || tc.errText != err.Error()) {
+				t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.errText)
+			}
+			if tc.err == nil && tc.errText == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expect != nil && !reflect.DeepEqual(out, tc.expect) {
+				t.Fatal(pretty.Compare(out, tc.expect))
+			}
+			if !tc.rerun {
+				return
+			}
+			out, err = dynamodbx.Migrate(ddb, tc.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expect := &dynamodbx.MigrateOutput{PreviousVersion: tc.expect.Version, Version: tc.expect.Version}
+			if !reflect.DeepEqual(out, expect) {
+				t.Fatal(pretty.Compare(out, expect))
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package dynamodbx
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// QueryIterator pages through the results of a Query one item at a time. Use it as
+//
+//	it := dynamodbx.NewQueryIterator(ddb, input, 0)
+//	for it.Next() {
+//		var v Stuff
+//		if err := it.Item(&v); err != nil {
+//			return err
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type QueryIterator struct {
+	ctx      context.Context
+	client   *dynamodb.DynamoDB
+	input    dynamodb.QueryInput
+	opts     []request.Option
+	maxItems int
+
+	page    []map[string]*dynamodb.AttributeValue
+	idx     int
+	item    map[string]*dynamodb.AttributeValue
+	lastKey map[string]*dynamodb.AttributeValue
+	started bool
+	err     error
+
+	count        int64
+	scannedCount int64
+	caps         []*dynamodb.ConsumedCapacity
+}
+
+// NewQueryIterator returns an iterator over the items matching input. If maxItems is greater than
+// zero iteration stops after that many items, otherwise it continues until the last page. The input
+// is not modified.
+func NewQueryIterator(client *dynamodb.DynamoDB, input *dynamodb.QueryInput, maxItems int) *QueryIterator {
+	return NewQueryIteratorWithContext(context.Background(), client, input, maxItems)
+}
+
+// NewQueryIteratorWithContext is the same as NewQueryIterator.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func NewQueryIteratorWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.QueryInput, maxItems int, opts ...request.Option) *QueryIterator {
+	return &QueryIterator{
+		ctx:      ctx,
+		client:   client,
+		input:    *input,
+		opts:     opts,
+		maxItems: maxItems,
+		lastKey:  input.ExclusiveStartKey,
+	}
+}
+
+// Next advances to the next item, fetching the next page when needed. It returns false when there
+// are no more items, the item limit has been reached or an error occurred.
+func (it *QueryIterator) Next() bool {
+	it.item = nil
+	if it.err != nil || (it.maxItems > 0 && it.count >= int64(it.maxItems)) {
+		return false
+	}
+	for it.idx >= len(it.page) {
+		if it.started && len(it.lastKey) == 0 {
+			return false
+		}
+		it.started = true
+		page := it.input
+		page.ExclusiveStartKey = it.lastKey
+		// Never read past the item limit so LastEvaluatedKey is exactly where iteration stopped
+		if it.maxItems > 0 {
+			remaining := int64(it.maxItems) - it.count
+			if page.Limit == nil || *page.Limit > remaining {
+				page.Limit = aws.Int64(remaining)
+			}
+		}
+		out, err := it.client.QueryWithContext(it.ctx, &page, it.opts...)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page, it.idx = out.Items, 0
+		it.lastKey = out.LastEvaluatedKey
+		it.scannedCount += aws.Int64Value(out.ScannedCount)
+		if out.ConsumedCapacity != nil {
+			it.caps = append(it.caps, out.ConsumedCapacity)
+		}
+	}
+	it.item = it.page[it.idx]
+	it.idx++
+	it.count++
+	return true
+}
+
+// Item unmarshals the current item into v using dynamodbattribute.UnmarshalMap.
+func (it *QueryIterator) Item(v interface{}) error {
+	return dynamodbattribute.UnmarshalMap(it.item, v)
+}
+
+// AttributeValues returns the current item without unmarshalling it.
+func (it *QueryIterator) AttributeValues() map[string]*dynamodb.AttributeValue {
+	return it.item
+}
+
+// Err returns the error which stopped iteration, if any.
+func (it *QueryIterator) Err() error {
+	return it.err
+}
+
+// LastEvaluatedKey returns the ExclusiveStartKey needed to resume the query after Next has returned
+// false. It is nil when the query has been read to the end.
+func (it *QueryIterator) LastEvaluatedKey() map[string]*dynamodb.AttributeValue {
+	return it.lastKey
+}
+
+// ConsumedCapacity returns the capacity used by every page read so far summed up, or nil if the
+// input did not ask for consumed capacity to be returned.
+func (it *QueryIterator) ConsumedCapacity() *dynamodb.ConsumedCapacity {
+	sum := sumConsumedCapacity(it.caps)
+	if len(sum) == 0 {
+		return nil
+	}
+	return sum[0]
+}
+
+// QueryAll runs a query following every page, or until maxItems items have been read if maxItems is
+// greater than zero, and unmarshals the items into out which must be a pointer to a slice.
+//
+// The returned output holds the total Count and ScannedCount, the summed ConsumedCapacity and the
+// LastEvaluatedKey to resume from when the item limit was reached. Its Items are left empty as they
+// have already been unmarshalled into out.
+func QueryAll(client *dynamodb.DynamoDB, input *dynamodb.QueryInput, maxItems int, out interface{}) (*dynamodb.QueryOutput, error) {
+	return QueryAllWithContext(context.Background(), client, input, maxItems, out)
+}
+
+// QueryAllWithContext is the same as QueryAll.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func QueryAllWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.QueryInput, maxItems int, out interface{}, opts ...request.Option) (*dynamodb.QueryOutput, error) {
+	it := NewQueryIteratorWithContext(ctx, client, input, maxItems, opts...)
+	var items []map[string]*dynamodb.AttributeValue
+	for it.Next() {
+		items = append(items, it.AttributeValues())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, out); err != nil {
+		return nil, err
+	}
+	return &dynamodb.QueryOutput{
+		Count:            aws.Int64(it.count),
+		ScannedCount:     aws.Int64(it.scannedCount),
+		ConsumedCapacity: it.ConsumedCapacity(),
+		LastEvaluatedKey: it.LastEvaluatedKey(),
+	}, nil
+}
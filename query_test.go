@@ -0,0 +1,145 @@
+package dynamodbx_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func TestQueryAll(t *testing.T) {
+	t.Parallel()
+	type TestData struct {
+		P string
+		S string
+	}
+	testDataSet := func(count int) []*TestData {
+		data := make([]*TestData, count)
+		for i := 0; i < count; i++ {
+			data[i] = &TestData{
+				P: "p",
+				S: fmt.Sprintf("%04d", i),
+			}
+		}
+		return data
+	}
+	for _, tc := range []struct {
+		name     string
+		table    string
+		count    int
+		pageSize int64
+		maxItems int
+		expect   int
+		// more is true when the query should stop with a LastEvaluatedKey
+		more bool
+	}{
+		{
+			name:   "empty table",
+			table:  "testQueryEmpty",
+			expect: 0,
+		},
+		{
+			name:     "all pages",
+			table:    "testQueryAll",
+			count:    120,
+			pageSize: 25,
+			expect:   120,
+		},
+		{
+			name:     "item limit",
+			table:    "testQueryLimit",
+			count:    120,
+			pageSize: 25,
+			maxItems: 60,
+			expect:   60,
+			more:     true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ddb := memdb.NewClient()
+			_, err := dynamodbx.CreateTableSync(ddb, &dynamodb.CreateTableInput{
+				TableName:   aws.String(tc.table),
+				BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{
+					{
+						AttributeName: aws.String("P"),
+						AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+					},
+					{
+						AttributeName: aws.String("S"),
+						AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+					},
+				},
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{
+						AttributeName: aws.String("P"),
+						KeyType:       aws.String(dynamodb.KeyTypeHash),
+					},
+					{
+						AttributeName: aws.String("S"),
+						KeyType:       aws.String(dynamodb.KeyTypeRange),
+					},
+				},
+			})
+			defer ddb.DeleteTable(&dynamodb.DeleteTableInput{
+				TableName: aws.String(tc.table),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.count > 0 {
+				req, err := dynamodbx.BatchPutRequest(tc.table, testDataSet(tc.count))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := dynamodbx.BatchWriteItem(ddb, &dynamodb.BatchWriteItemInput{RequestItems: req}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			input := &dynamodb.QueryInput{
+				TableName:              aws.String(tc.table),
+				KeyConditionExpression: aws.String("P = :p"),
+				ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+					":p": {S: aws.String("p")},
+				},
+				ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+			}
+			if tc.pageSize > 0 {
+				input.Limit = aws.Int64(tc.pageSize)
+			}
+			var items []*TestData
+			out, err := dynamodbx.QueryAll(ddb, input, tc.maxItems, &items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != tc.expect || aws.Int64Value(out.Count) != int64(tc.expect) {
+				t.Fatalf("expected %d items, got %d with a count of %d", tc.expect, len(items), aws.Int64Value(out.Count))
+			}
+			for i, v := range items {
+				if want := fmt.Sprintf("%04d", i); v.S != want {
+					t.Fatalf("expected item %d to be %s, got %s", i, want, v.S)
+				}
+			}
+			if tc.more != (out.LastEvaluatedKey != nil) {
+				t.Fatalf("expected more results to be %v, got LastEvaluatedKey %v", tc.more, out.LastEvaluatedKey)
+			}
+			if !tc.more {
+				return
+			}
+			// Resuming from the LastEvaluatedKey returns the rest of the items
+			input.ExclusiveStartKey = out.LastEvaluatedKey
+			var rest []*TestData
+			if _, err := dynamodbx.QueryAll(ddb, input, 0, &rest); err != nil {
+				t.Fatal(err)
+			}
+			if len(rest) != tc.count-tc.expect || rest[0].S != fmt.Sprintf("%04d", tc.expect) {
+				t.Fatalf("expected %d remaining items starting at %d, got %d", tc.count-tc.expect, tc.expect, len(rest))
+			}
+		})
+	}
+}
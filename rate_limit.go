@@ -0,0 +1,42 @@
+package dynamodbx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// rateLimiter spaces out work so that on average no more than a fixed number of units are used per
+// second. It is safe for concurrent use and a nil *rateLimiter never blocks.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter returns a limiter allowing perSecond units a second, or nil if perSecond is not
+// positive.
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until n units may be used or the context is done.
+func (l *rateLimiter) wait(ctx context.Context, n float64) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	at := l.next
+	l.next = l.next.Add(time.Duration(n * float64(l.interval)))
+	l.mu.Unlock()
+	return aws.SleepWithContext(ctx, time.Until(at))
+}
@@ -0,0 +1,285 @@
+// Package replay records the requests a dynamodb client makes and the responses it receives to
+// golden files, and replays them later without a server, so integration tests recorded against
+// DynamoDB Local run deterministically anywhere.
+//
+// Interactions are stored one per file, named by their position and operation, such as
+// 0001-CreateTable.json. Replayed requests must arrive in the recorded order with the same
+// operation and an equal JSON body, otherwise the request fails with a ReplayMismatch error which
+// is not retried. Signatures, dates and other headers are not compared.
+//
+//	var record = flag.Bool("record", false, "record golden files against DynamoDB Local")
+//
+//	var tr *replay.Transport
+//	if *record {
+//		tr = replay.NewRecorder("testdata/put", nil)
+//	} else {
+//		tr, err = replay.NewReplayer("testdata/put")
+//	}
+//	ddb := dynamodb.New(sess, aws.NewConfig().WithHTTPClient(&http.Client{Transport: tr}))
+//	...
+//	if err := tr.Close(); err != nil {
+//		t.Fatal(err)
+//	}
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MismatchCode is the error code of requests which do not match the recording.
+const MismatchCode = "ReplayMismatch"
+
+// Errors returned by the replay package.
+var (
+	ErrNoInteractions = errors.New("dynamodbx/replay: no recorded interactions found")
+	ErrUnreplayed     = errors.New("dynamodbx/replay: recorded interactions were not replayed")
+)
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Operation string          `json:"operation"`
+	Request   json.RawMessage `json:"request"`
+	Status    int             `json:"status"`
+	Response  json.RawMessage `json:"response"`
+}
+
+// Transport is an http.RoundTripper which records or replays interactions. It is safe for
+// concurrent use, but concurrent requests are only replayed reliably if they are made in the
+// recorded order.
+type Transport struct {
+	dir       string
+	transport http.RoundTripper // nil when replaying
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	next         int
+	err          error
+}
+
+// NewRecorder returns a Transport which sends requests with transport, or http.DefaultTransport
+// if it is nil, and records each interaction to dir. Previously recorded interactions in dir are
+// removed by the first request.
+func NewRecorder(dir string, transport http.RoundTripper) *Transport {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Transport{dir: dir, transport: transport}
+}
+
+// NewReplayer returns a Transport which replays the interactions recorded in dir.
+func NewReplayer(dir string) (*Transport, error) {
+	names, err := interactionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, ErrNoInteractions
+	}
+	t := &Transport{dir: dir}
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		in := &Interaction{}
+		if err := json.Unmarshal(data, in); err != nil {
+			return nil, fmt.Errorf("dynamodbx/replay: %s: %v", name, err)
+		}
+		t.interactions = append(t.interactions, in)
+	}
+	return t, nil
+}
+
+// Recording reports whether the Transport records interactions rather than replaying them.
+func (t *Transport) Recording() bool {
+	return t.transport != nil
+}
+
+// Close returns the first mismatch or recording error. When replaying it returns ErrUnreplayed if
+// any recorded interactions were not requested.
+func (t *Transport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return t.err
+	}
+	if !t.Recording() && t.next < len(t.interactions) {
+		return ErrUnreplayed
+	}
+	return nil
+}
+
+// RoundTrip records or replays a request.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation := req.Header.Get("X-Amz-Target")
+	if i := strings.LastIndex(operation, "."); i >= 0 {
+		operation = operation[i+1:]
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	if t.Recording() {
+		return t.record(req, operation, body)
+	}
+	return t.replay(req, operation, body), nil
+}
+
+func (t *Transport) record(req *http.Request, operation string, body []byte) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.save(&Interaction{
+		Operation: operation,
+		Request:   compact(body),
+		Status:    resp.StatusCode,
+		Response:  compact(respBody),
+	}); err != nil && t.err == nil {
+		t.err = err
+	}
+	return resp, nil
+}
+
+// save writes an interaction to the next file in dir. t.mu must be held.
+func (t *Transport) save(in *Interaction) error {
+	if t.next == 0 {
+		if err := os.MkdirAll(t.dir, 0755); err != nil {
+			return err
+		}
+		names, err := interactionFiles(t.dir)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := os.Remove(filepath.Join(t.dir, name)); err != nil {
+				return err
+			}
+		}
+	}
+	t.next++
+	data, err := json.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%04d-%s.json", t.next, in.Operation)
+	return ioutil.WriteFile(filepath.Join(t.dir, name), append(data, '\n'), 0644)
+}
+
+func (t *Transport) replay(req *http.Request, operation string, body []byte) *http.Response {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.next >= len(t.interactions) {
+		return t.mismatch(req, fmt.Sprintf("unexpected %s request after %d recorded interactions", operation, len(t.interactions)))
+	}
+	in := t.interactions[t.next]
+	if in.Operation != operation {
+		return t.mismatch(req, fmt.Sprintf("interaction %d: expected %s request, got %s", t.next+1, in.Operation, operation))
+	}
+	if !jsonEqual(in.Request, body) {
+		return t.mismatch(req, fmt.Sprintf("interaction %d: %s request differs from the recording:\ngot:  %s\nwant: %s", t.next+1, operation, compact(body), in.Request))
+	}
+	t.next++
+	body = in.Response
+	var s string
+	if json.Unmarshal(body, &s) == nil {
+		// The response was not JSON and was recorded as a string
+		body = []byte(s)
+	}
+	return response(req, in.Status, body)
+}
+
+// mismatch records the first mismatch and returns a response the sdk will not retry. t.mu must
+// be held.
+func (t *Transport) mismatch(req *http.Request, message string) *http.Response {
+	if t.err == nil {
+		t.err = errors.New("dynamodbx/replay: " + message)
+	}
+	body, _ := json.Marshal(struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}{MismatchCode, message})
+	return response(req, http.StatusBadRequest, body)
+}
+
+func response(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode: status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Content-Type":   []string{"application/x-amz-json-1.0"},
+			"Content-Length": []string{strconv.Itoa(len(body))},
+			"X-Amz-Crc32":    []string{strconv.FormatUint(uint64(crc32.ChecksumIEEE(body)), 10)},
+		},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// interactionFiles returns the names of the recorded interactions in dir in order.
+func interactionFiles(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		name := f.Name()
+		if i := strings.Index(name, "-"); i == 4 && strings.HasSuffix(name, ".json") {
+			if _, err := strconv.Atoi(name[:i]); err == nil {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// compact returns data compacted if it is JSON, or as a JSON string otherwise.
+func compact(data []byte) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err == nil && buf.Len() > 0 {
+		return buf.Bytes()
+	}
+	s, _ := json.Marshal(string(data))
+	return s
+}
+
+func jsonEqual(a, b []byte) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(compact(b), &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
@@ -0,0 +1,163 @@
+package replay_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx/memdb"
+	"github.com/kynrai/dynamodbx/replay"
+)
+
+func newClient(endpoint string, tr http.RoundTripper) *dynamodb.DynamoDB {
+	return dynamodb.New(session.Must(session.NewSession()), &aws.Config{
+		Region:      aws.String("eu-west-1"),
+		Endpoint:    aws.String(endpoint),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		HTTPClient:  &http.Client{Transport: tr},
+	})
+}
+
+// interact makes the requests recorded and replayed by the tests. n is the key of the item put.
+func interact(ddb *dynamodb.DynamoDB, n string) (*dynamodb.GetItemOutput, error) {
+	_, err := ddb.CreateTable(&dynamodb.CreateTableInput{
+		TableName:            aws.String("test"),
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("P"), AttributeType: aws.String("S")}},
+		KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("P"), KeyType: aws.String("HASH")}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	key := map[string]*dynamodb.AttributeValue{"P": {S: aws.String(n)}}
+	if _, err := ddb.PutItem(&dynamodb.PutItemInput{TableName: aws.String("test"), Item: key}); err != nil {
+		return nil, err
+	}
+	return ddb.GetItem(&dynamodb.GetItemInput{TableName: aws.String("test"), Key: key})
+}
+
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// A stale recording is replaced
+	if err := ioutil.WriteFile(dir+"/0009-Scan.json", []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(memdb.New())
+	rec := replay.NewRecorder(dir, nil)
+	recorded, err := interact(newClient(srv.URL, rec), "a")
+	srv.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if expect := []string{"0001-CreateTable.json", "0002-PutItem.json", "0003-GetItem.json"}; !reflect.DeepEqual(names, expect) {
+		t.Fatal(pretty.Compare(names, expect))
+	}
+
+	for _, tc := range []struct {
+		name string
+		key  string
+		code string
+	}{
+		{
+			name: "match",
+			key:  "a",
+		},
+		{
+			name: "request differs",
+			key:  "b",
+			code: replay.MismatchCode,
+		},
+	} {
+		tr, err := replay.NewReplayer(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The endpoint is never contacted
+		out, err := interact(newClient("http://replay.invalid", tr), tc.key)
+		if tc.code != "" {
+			if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != tc.code {
+				t.Fatalf("%s: expected error code %s, got %v", tc.name, tc.code, err)
+			}
+			if tr.Close() == nil {
+				t.Fatalf("%s: expected Close to return the mismatch", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(out, recorded) {
+			t.Fatal(pretty.Compare(out, recorded))
+		}
+		if err := tr.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReplayErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := replay.NewReplayer(dir); err != replay.ErrNoInteractions {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, replay.ErrNoInteractions)
+	}
+
+	srv := httptest.NewServer(memdb.New())
+	defer srv.Close()
+	rec := replay.NewRecorder(dir, nil)
+	if _, err := interact(newClient(srv.URL, rec), "a"); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := replay.NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newClient("http://replay.invalid", tr).ListTables(&dynamodb.ListTablesInput{}); err == nil {
+		t.Fatal("expected an error for an unexpected operation")
+	}
+
+	tr, err = replay.NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ddb := newClient("http://replay.invalid", tr)
+	if _, err := ddb.CreateTable(&dynamodb.CreateTableInput{
+		TableName:            aws.String("test"),
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("P"), AttributeType: aws.String("S")}},
+		KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("P"), KeyType: aws.String("HASH")}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Close(); err != replay.ErrUnreplayed {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, replay.ErrUnreplayed)
+	}
+}
@@ -0,0 +1,260 @@
+package dynamodbx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrScanSegments = errors.New("dynamodbx/ParallelScan: segments must be between 1 and 1000000")
+	ErrScanProgress = errors.New("dynamodbx/ParallelScan: progress was saved for a different number of segments")
+)
+
+// maxScanSegments is the largest TotalSegments dynamodb accepts
+const maxScanSegments = 1000000
+
+// ScanFunc is called by ParallelScan for every item. It is called concurrently from several
+// segments so must be safe for concurrent use. Returning an error stops the scan.
+type ScanFunc func(item map[string]*dynamodb.AttributeValue) error
+
+// ScanCheckpoint persists the progress of each segment of a parallel scan so that an interrupted
+// scan can be resumed. Implementations must be safe for concurrent use.
+type ScanCheckpoint interface {
+	// Load returns the key to resume segment from, nil to start from the beginning, and whether the
+	// segment has already been completed.
+	Load(segment, totalSegments int) (key map[string]*dynamodb.AttributeValue, done bool, err error)
+	// Save is called after every item in a page has been processed with the LastEvaluatedKey of the
+	// page, which is nil once the segment is complete.
+	Save(segment, totalSegments int, key map[string]*dynamodb.AttributeValue) error
+}
+
+// ParallelScanner scans a table with several segments at once.
+type ParallelScanner struct {
+	// Segments is the TotalSegments the table is split into.
+	Segments int
+	// Concurrency bounds how many segments are scanned at once. Defaults to Segments.
+	Concurrency int
+	// ReadCapacityPerSecond limits the read capacity used across all segments. The consumed
+	// capacity of every page is returned and the next page is delayed to stay under the limit.
+	// Zero is unlimited.
+	ReadCapacityPerSecond float64
+	// Checkpoint, if set, is used to resume segments and record their progress.
+	Checkpoint ScanCheckpoint
+}
+
+// ParallelScan scans the table in input split into segments which are scanned concurrently,
+// calling fn for every item. The first error from a scan or from fn stops every segment and is
+// returned. Use a ParallelScanner to bound concurrency, limit read capacity or resume a scan.
+func ParallelScan(client *dynamodb.DynamoDB, input *dynamodb.ScanInput, segments int, fn ScanFunc) error {
+	return ParallelScanWithContext(context.Background(), client, input, segments, fn)
+}
+
+// ParallelScanWithContext is the same as ParallelScan.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func ParallelScanWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.ScanInput, segments int, fn ScanFunc, opts ...request.Option) error {
+	s := &ParallelScanner{Segments: segments}
+	return s.ScanWithContext(ctx, client, input, fn, opts...)
+}
+
+// Scan scans the table in input calling fn for every item.
+func (s *ParallelScanner) Scan(client *dynamodb.DynamoDB, input *dynamodb.ScanInput, fn ScanFunc) error {
+	return s.ScanWithContext(context.Background(), client, input, fn)
+}
+
+// ScanWithContext scans the table in input calling fn for every item.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func (s *ParallelScanner) ScanWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.ScanInput, fn ScanFunc, opts ...request.Option) error {
+	if s.Segments < 1 || s.Segments > maxScanSegments {
+		return ErrScanSegments
+	}
+	return s.scanPages(ctx, client, input, func(_ int, page *dynamodb.ScanOutput) error {
+		for _, item := range page.Items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, opts...)
+}
+
+// scanPages runs the parallel scan calling fn with every page, so fn must be safe for concurrent
+// use. The first error from a scan or from fn cancels the remaining workers and is returned.
+func (s *ParallelScanner) scanPages(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.ScanInput, fn func(segment int, page *dynamodb.ScanOutput) error, opts ...request.Option) error {
+	segments := s.Segments
+	if segments < 1 {
+		segments = 1
+	}
+	workers := s.Concurrency
+	if workers < 1 || workers > segments {
+		workers = segments
+	}
+	limiter := newRateLimiter(s.ReadCapacityPerSecond)
+	base := *input
+	if limiter != nil {
+		base.ReturnConsumedCapacity = aws.String(dynamodb.ReturnConsumedCapacityTotal)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	scanSegment := func(segment int) error {
+		page := base
+		if segments > 1 {
+			page.Segment = aws.Int64(int64(segment))
+			page.TotalSegments = aws.Int64(int64(segments))
+		}
+		if s.Checkpoint != nil {
+			key, done, err := s.Checkpoint.Load(segment, segments)
+			if err != nil || done {
+				return err
+			}
+			page.ExclusiveStartKey = key
+		}
+		for {
+			out, err := client.ScanWithContext(ctx, &page, opts...)
+			if err != nil {
+				return err
+			}
+			if err := fn(segment, out); err != nil {
+				return err
+			}
+			if s.Checkpoint != nil {
+				if err := s.Checkpoint.Save(segment, segments, out.LastEvaluatedKey); err != nil {
+					return err
+				}
+			}
+			if len(out.LastEvaluatedKey) == 0 {
+				return nil
+			}
+			page.ExclusiveStartKey = out.LastEvaluatedKey
+			if out.ConsumedCapacity != nil {
+				if err := limiter.wait(ctx, aws.Float64Value(out.ConsumedCapacity.CapacityUnits)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	queue := make(chan int)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for segment := range queue {
+				if err := scanSegment(segment); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+feed:
+	for segment := 0; segment < segments; segment++ {
+		select {
+		case queue <- segment:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	if firstErr == nil && ctx.Err() != nil {
+		// The parent context was cancelled before every segment was started
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+// scanSegments scans the table in input using segments parallel workers calling fn with every page.
+func scanSegments(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.ScanInput, segments int, fn func(segment int, page *dynamodb.ScanOutput) error, opts ...request.Option) error {
+	s := &ParallelScanner{Segments: segments}
+	return s.scanPages(ctx, client, input, fn, opts...)
+}
+
+// ScanProgress is an in memory ScanCheckpoint. It can be marshalled to JSON at any time, including
+// while a scan is running, and unmarshalled later to resume the scan.
+type ScanProgress struct {
+	mu            sync.Mutex
+	TotalSegments int                      `json:"totalSegments"`
+	Segments      map[int]*SegmentProgress `json:"segments"`
+}
+
+// SegmentProgress is the saved state of a single segment.
+type SegmentProgress struct {
+	LastEvaluatedKey map[string]*dynamodb.AttributeValue `json:"lastEvaluatedKey,omitempty"`
+	Done             bool                                `json:"done"`
+}
+
+// Load implements ScanCheckpoint.
+func (p *ScanProgress) Load(segment, totalSegments int) (map[string]*dynamodb.AttributeValue, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.TotalSegments == 0 {
+		p.TotalSegments = totalSegments
+	}
+	if p.TotalSegments != totalSegments {
+		return nil, false, ErrScanProgress
+	}
+	v, ok := p.Segments[segment]
+	if !ok {
+		return nil, false, nil
+	}
+	return v.LastEvaluatedKey, v.Done, nil
+}
+
+// Save implements ScanCheckpoint.
+func (p *ScanProgress) Save(segment, totalSegments int, key map[string]*dynamodb.AttributeValue) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.TotalSegments != totalSegments {
+		return ErrScanProgress
+	}
+	if p.Segments == nil {
+		p.Segments = make(map[int]*SegmentProgress)
+	}
+	p.Segments[segment] = &SegmentProgress{LastEvaluatedKey: key, Done: len(key) == 0}
+	return nil
+}
+
+// Done reports whether every segment has been completed.
+func (p *ScanProgress) Done() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.TotalSegments == 0 {
+		return false
+	}
+	for i := 0; i < p.TotalSegments; i++ {
+		if v, ok := p.Segments[i]; !ok || !v.Done {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON implements json.Marshaler, holding the lock so progress can be saved mid scan.
+func (p *ScanProgress) MarshalJSON() ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return json.Marshal(&struct {
+		TotalSegments int                      `json:"totalSegments"`
+		Segments      map[int]*SegmentProgress `json:"segments"`
+	}{p.TotalSegments, p.Segments})
+}
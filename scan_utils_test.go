@@ -0,0 +1,179 @@
+package dynamodbx_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func TestParallelScan(t *testing.T) {
+	t.Parallel()
+	type TestData struct {
+		S string
+	}
+	testDataSet := func(count int) []*TestData {
+		data := make([]*TestData, count)
+		for i := 0; i < count; i++ {
+			data[i] = &TestData{
+				S: strconv.Itoa(i),
+			}
+		}
+		return data
+	}
+	for _, tc := range []struct {
+		name    string
+		table   string
+		count   int
+		scanner *dynamodbx.ParallelScanner
+		err     error
+	}{
+		{
+			name:    "invalid segments",
+			scanner: &dynamodbx.ParallelScanner{},
+			err:     dynamodbx.ErrScanSegments,
+		},
+		{
+			name:    "single segment",
+			table:   "testScanSingle",
+			count:   120,
+			scanner: &dynamodbx.ParallelScanner{Segments: 1},
+		},
+		{
+			name:  "bounded concurrency with progress",
+			table: "testScanProgress",
+			count: 300,
+			scanner: &dynamodbx.ParallelScanner{
+				Segments:              8,
+				Concurrency:           2,
+				ReadCapacityPerSecond: 10000,
+				Checkpoint:            &dynamodbx.ScanProgress{},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ddb := memdb.NewClient()
+			input := &dynamodb.ScanInput{TableName: aws.String(tc.table), Limit: aws.Int64(20)}
+			if tc.err != nil {
+				if err := tc.scanner.Scan(ddb, input, nil); err != tc.err {
+					t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			_, err := dynamodbx.CreateTableSync(ddb, &dynamodb.CreateTableInput{
+				TableName:   aws.String(tc.table),
+				BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{
+					{
+						AttributeName: aws.String("S"),
+						AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+					},
+				},
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{
+						AttributeName: aws.String("S"),
+						KeyType:       aws.String(dynamodb.KeyTypeHash),
+					},
+				},
+			})
+			defer ddb.DeleteTable(&dynamodb.DeleteTableInput{
+				TableName: aws.String(tc.table),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, err := dynamodbx.BatchPutRequest(tc.table, testDataSet(tc.count))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := dynamodbx.BatchWriteItem(ddb, &dynamodb.BatchWriteItemInput{RequestItems: req}); err != nil {
+				t.Fatal(err)
+			}
+
+			var mu sync.Mutex
+			seen := make(map[string]int)
+			err = tc.scanner.Scan(ddb, input, func(item map[string]*dynamodb.AttributeValue) error {
+				mu.Lock()
+				seen[*item["S"].S]++
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(seen) != tc.count {
+				t.Fatalf("expected %d distinct items, got %d", tc.count, len(seen))
+			}
+			for k, v := range seen {
+				if v != 1 {
+					t.Fatalf("expected item %s once, got it %d times", k, v)
+				}
+			}
+			if p, ok := tc.scanner.Checkpoint.(*dynamodbx.ScanProgress); ok && !p.Done() {
+				t.Fatal("expected every segment to be done")
+			}
+
+			// A completed scan resumed from its progress reads nothing
+			if tc.scanner.Checkpoint == nil {
+				return
+			}
+			err = tc.scanner.Scan(ddb, input, func(item map[string]*dynamodb.AttributeValue) error {
+				return errors.New("unexpected item")
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestScanProgress(t *testing.T) {
+	t.Parallel()
+	key := map[string]*dynamodb.AttributeValue{"S": {S: aws.String("10")}}
+
+	p := &dynamodbx.ScanProgress{}
+	if _, _, err := p.Load(0, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Save(0, 2, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Save(1, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if p.Done() {
+		t.Fatal("expected segment 0 to be incomplete")
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed := &dynamodbx.ScanProgress{}
+	if err := json.Unmarshal(data, resumed); err != nil {
+		t.Fatal(err)
+	}
+	got, done, err := resumed.Load(0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done || !reflect.DeepEqual(got, key) {
+		t.Fatal(pretty.Compare(got, key))
+	}
+	if _, done, _ := resumed.Load(1, 2); !done {
+		t.Fatal("expected segment 1 to be done")
+	}
+	if _, _, err := resumed.Load(0, 4); err != dynamodbx.ErrScanProgress {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrScanProgress)
+	}
+}
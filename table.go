@@ -0,0 +1,555 @@
+package dynamodbx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/kynrai/dynamodbx/expr"
+)
+
+var (
+	ErrTableName        = errors.New("dynamodbx/Table: table name and key schema cannot be empty")
+	ErrTableItemType    = errors.New("dynamodbx/Table: the value does not have the item type of the table")
+	ErrTableKey         = errors.New("dynamodbx/Table: the key is missing a key attribute")
+	ErrTableNotFound    = errors.New("dynamodbx/Table: item not found")
+	ErrTableUnprocessed = errors.New("dynamodbx/Table: some items could not be processed")
+)
+
+// batchGetSize is the maximum number of keys dynamodb accepts in a single BatchGetItem call
+const batchGetSize = 100
+
+// Table is a table bound to a client, its key schema and the go struct type of its items, so that
+// items are read and written as go values, marshalled with dynamodbattribute. It is safe for
+// concurrent use.
+//
+// Keys are given as a struct or map holding at least the key attributes, such as the item itself.
+// Items are given as values of, or pointers to, the item type, and are read into pointers to the
+// item type or to slices of it or of pointers to it. Reads only fetch the attributes of the item
+// type, using the projection of expr.ProjectionFor.
+//
+// If the item type has a version field, tagged `dynamodbx:"version"`, items are locked
+// optimistically: Put, Update and BatchPut only write an item if its stored version is the version
+// of the item given, and increment the version. ErrVersionConflict is returned if the stored version
+// differs, meaning the item was modified since it was read.
+//
+// If the item type has timestamp fields, tagged `dynamodbx:"created"` and `dynamodbx:"updated"`,
+// writes stamp them with the time of the clock of the table: the updated time is always set, and
+// the created time is set by Put and BatchPut if the item does not have one, and by Update if the
+// stored item does not have one.
+//
+// If the item type has a time to live field, tagged `dynamodbx:"ttl"`, Put and BatchPut write it as
+// BatchPutRequest does, so each item expires at its own time.
+type Table struct {
+	client     *dynamodb.DynamoDB
+	name       string
+	keys       []string
+	itemType   reflect.Type
+	projection expr.ProjectionBuilder
+	version    *version
+	timestamps *timestamps
+	ttl        *ttl
+	clock      func() time.Time
+}
+
+// NewTable returns the Table with the name and key schema, holding items of the type of item, which
+// must be a struct or a pointer to one. item is only used for its type.
+func NewTable(client *dynamodb.DynamoDB, name string, keySchema []*dynamodb.KeySchemaElement, item interface{}) (*Table, error) {
+	if name == "" || len(keySchema) == 0 {
+		return nil, ErrTableName
+	}
+	t := reflect.TypeOf(item)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, ErrTableItemType
+	}
+	keys := make([]string, len(keySchema))
+	for i, k := range keySchema {
+		keys[i] = aws.StringValue(k.AttributeName)
+	}
+	v, err := itemVersion(t)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := itemTimestamps(t)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := itemTTL(t)
+	if err != nil {
+		return nil, err
+	}
+	tbl := &Table{client: client, name: name, keys: keys, itemType: t, version: v, timestamps: ts, ttl: exp}
+	// Items which unmarshal themselves may use any attribute, so they are read whole
+	if !reflect.PtrTo(t).Implements(unmarshalerType) {
+		tbl.projection = expr.ProjectionFor(item)
+	}
+	return tbl, nil
+}
+
+var unmarshalerType = reflect.TypeOf((*dynamodbattribute.Unmarshaler)(nil)).Elem()
+
+// Name returns the name of the table.
+func (t *Table) Name() string {
+	return t.name
+}
+
+// WithClock returns a copy of the table which stamps timestamps with the time of clock rather than
+// of time.Now.
+func (t *Table) WithClock(clock func() time.Time) *Table {
+	tbl := *t
+	tbl.clock = clock
+	return &tbl
+}
+
+// now returns the time to stamp timestamps with.
+func (t *Table) now() time.Time {
+	if t.clock != nil {
+		return t.clock()
+	}
+	return time.Now()
+}
+
+// TableQueryInput describes a Query of a Table.
+type TableQueryInput struct {
+	// IndexName, if set, queries a secondary index.
+	IndexName string
+	// KeyCondition selects the items to read.
+	KeyCondition expr.KeyConditionBuilder
+	// Filter, if set, is applied to the items after they are read.
+	Filter expr.ConditionBuilder
+	// Descending reads items in descending sort key order.
+	Descending bool
+	// ConsistentRead makes a strongly consistent read.
+	ConsistentRead bool
+	// MaxItems, if greater than zero, limits the number of items read.
+	MaxItems int
+}
+
+// TableScanInput describes a Scan of a Table.
+type TableScanInput struct {
+	// IndexName, if set, scans a secondary index.
+	IndexName string
+	// Filter, if set, is applied to the items after they are read.
+	Filter expr.ConditionBuilder
+	// ConsistentRead makes a strongly consistent read.
+	ConsistentRead bool
+	// Segments, if greater than one, scans the table in parallel with ParallelScan. Items are then
+	// in no particular order.
+	Segments int
+}
+
+// Get reads the item with the key into out, a pointer to the item type. ErrTableNotFound is
+// returned if there is no such item.
+func (t *Table) Get(key, out interface{}) error {
+	return t.GetWithContext(context.Background(), key, out)
+}
+
+// GetWithContext is the same as Get.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func (t *Table) GetWithContext(ctx context.Context, key, out interface{}, opts ...request.Option) error {
+	if err := t.checkItem(out, false); err != nil {
+		return err
+	}
+	k, err := t.key(key)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.GetItemInput{TableName: aws.String(t.name), Key: k}
+	if t.projection.IsSet() {
+		e, err := expr.NewBuilder().WithProjection(t.projection).Build()
+		if err != nil {
+			return err
+		}
+		input.ProjectionExpression = e.Projection()
+		input.ExpressionAttributeNames = e.Names()
+	}
+	res, err := t.client.GetItemWithContext(ctx, input, opts...)
+	if err != nil {
+		return err
+	}
+	if res.Item == nil {
+		return ErrTableNotFound
+	}
+	return dynamodbattribute.UnmarshalMap(res.Item, out)
+}
+
+// Put writes the item, replacing any item with the same key. cond, if set, must hold for the item to
+// be written. The version and timestamps of the item are updated if item is a pointer.
+func (t *Table) Put(item interface{}, cond expr.ConditionBuilder) error {
+	return t.PutWithContext(context.Background(), item, cond)
+}
+
+// PutWithContext is the same as Put.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func (t *Table) PutWithContext(ctx context.Context, item interface{}, cond expr.ConditionBuilder, opts ...request.Option) error {
+	if err := t.checkItem(item, true); err != nil {
+		return err
+	}
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.PutItemInput{TableName: aws.String(t.name), Item: av}
+	if t.version != nil {
+		cond = t.version.put(reflect.ValueOf(item), av, cond)
+	}
+	now := t.now()
+	if t.timestamps != nil {
+		t.timestamps.put(reflect.ValueOf(item), av, now)
+	}
+	if t.ttl != nil {
+		t.ttl.put(reflect.ValueOf(item), av, now)
+	}
+	if cond.IsSet() {
+		e, err := expr.NewBuilder().WithCondition(cond).Build()
+		if err != nil {
+			return err
+		}
+		input.ConditionExpression = e.Condition()
+		input.ExpressionAttributeNames = e.Names()
+		input.ExpressionAttributeValues = e.Values()
+	}
+	if _, err := t.client.PutItemWithContext(ctx, input, opts...); err != nil {
+		if t.version != nil {
+			return versionConflict(err)
+		}
+		return err
+	}
+	if t.version != nil {
+		t.version.increment(reflect.ValueOf(item))
+	}
+	if t.timestamps != nil {
+		t.timestamps.written(reflect.ValueOf(item), now)
+	}
+	return nil
+}
+
+// Delete deletes the item with the key. cond, if set, must hold for the item to be deleted.
+func (t *Table) Delete(key interface{}, cond expr.ConditionBuilder) error {
+	return t.DeleteWithContext(context.Background(), key, cond)
+}
+
+// DeleteWithContext is the same as Delete.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func (t *Table) DeleteWithContext(ctx context.Context, key interface{}, cond expr.ConditionBuilder, opts ...request.Option) error {
+	k, err := t.key(key)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.DeleteItemInput{TableName: aws.String(t.name), Key: k}
+	if cond.IsSet() {
+		e, err := expr.NewBuilder().WithCondition(cond).Build()
+		if err != nil {
+			return err
+		}
+		input.ConditionExpression = e.Condition()
+		input.ExpressionAttributeNames = e.Names()
+		input.ExpressionAttributeValues = e.Values()
+	}
+	_, err = t.client.DeleteItemWithContext(ctx, input, opts...)
+	return err
+}
+
+// Update applies the update to the item with the key, creating it if it does not exist. cond, if
+// set, must hold for the item to be updated. out, if not nil, is a pointer to the item type which
+// the updated item is read into.
+//
+// The update increments the version of a versioned item. If key is a value of the item type, the
+// item is only updated if its stored version is the version of key, which is incremented if key is
+// a pointer. Otherwise the item is updated whatever its version.
+func (t *Table) Update(key interface{}, update expr.UpdateBuilder, cond expr.ConditionBuilder, out interface{}) error {
+	return t.UpdateWithContext(context.Background(), key, update, cond, out)
+}
+
+// UpdateWithContext is the same as Update.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func (t *Table) UpdateWithContext(ctx context.Context, key interface{}, update expr.UpdateBuilder, cond expr.ConditionBuilder, out interface{}, opts ...request.Option) error {
+	if out != nil {
+		if err := t.checkItem(out, false); err != nil {
+			return err
+		}
+	}
+	k, err := t.key(key)
+	if err != nil {
+		return err
+	}
+	locked := t.version != nil && key != nil && t.isItemType(reflect.TypeOf(key))
+	if t.version != nil {
+		update = update.Add(expr.AttributeName(t.version.name), expr.Value(1))
+	}
+	if t.timestamps != nil {
+		update = t.timestamps.update(update, t.now())
+	}
+	if locked {
+		cond = t.version.and(t.version.get(reflect.ValueOf(key)), cond)
+	}
+	input, err := UpdateItemRequest(t.name, k, update, cond)
+	if err != nil {
+		return err
+	}
+	if out != nil {
+		input.ReturnValues = aws.String(dynamodb.ReturnValueAllNew)
+	}
+	res, err := t.client.UpdateItemWithContext(ctx, input, opts...)
+	if err != nil {
+		if locked {
+			return versionConflict(err)
+		}
+		return err
+	}
+	if locked {
+		t.version.increment(reflect.ValueOf(key))
+	}
+	if out == nil {
+		return nil
+	}
+	return dynamodbattribute.UnmarshalMap(res.Attributes, out)
+}
+
+// Query reads the items selected by the query into out, a pointer to a slice of the item type.
+func (t *Table) Query(input *TableQueryInput, out interface{}) error {
+	return t.QueryWithContext(context.Background(), input, out)
+}
+
+// QueryWithContext is the same as Query.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func (t *Table) QueryWithContext(ctx context.Context, input *TableQueryInput, out interface{}, opts ...request.Option) error {
+	if err := t.checkSlice(out); err != nil {
+		return err
+	}
+	b := expr.NewBuilder().WithKeyCondition(input.KeyCondition)
+	if input.Filter.IsSet() {
+		b = b.WithFilter(input.Filter)
+	}
+	if t.projection.IsSet() {
+		b = b.WithProjection(t.projection)
+	}
+	e, err := b.Build()
+	if err != nil {
+		return err
+	}
+	q := &dynamodb.QueryInput{
+		TableName:                 aws.String(t.name),
+		KeyConditionExpression:    e.KeyCondition(),
+		FilterExpression:          e.Filter(),
+		ProjectionExpression:      e.Projection(),
+		ExpressionAttributeNames:  e.Names(),
+		ExpressionAttributeValues: e.Values(),
+		ScanIndexForward:          aws.Bool(!input.Descending),
+		ConsistentRead:            aws.Bool(input.ConsistentRead),
+	}
+	if input.IndexName != "" {
+		q.IndexName = aws.String(input.IndexName)
+	}
+	_, err = QueryAllWithContext(ctx, t.client, q, input.MaxItems, out, opts...)
+	return err
+}
+
+// Scan reads every item of the table, or of those which pass the filter, into out, a pointer to a
+// slice of the item type. A nil input scans the whole table.
+func (t *Table) Scan(input *TableScanInput, out interface{}) error {
+	return t.ScanWithContext(context.Background(), input, out)
+}
+
+// ScanWithContext is the same as Scan.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func (t *Table) ScanWithContext(ctx context.Context, input *TableScanInput, out interface{}, opts ...request.Option) error {
+	if err := t.checkSlice(out); err != nil {
+		return err
+	}
+	if input == nil {
+		input = &TableScanInput{}
+	}
+	s := &dynamodb.ScanInput{
+		TableName:      aws.String(t.name),
+		ConsistentRead: aws.Bool(input.ConsistentRead),
+	}
+	if input.IndexName != "" {
+		s.IndexName = aws.String(input.IndexName)
+	}
+	b := expr.NewBuilder()
+	if input.Filter.IsSet() {
+		b = b.WithFilter(input.Filter)
+	}
+	if t.projection.IsSet() {
+		b = b.WithProjection(t.projection)
+	}
+	if e, err := b.Build(); err == nil {
+		s.FilterExpression = e.Filter()
+		s.ProjectionExpression = e.Projection()
+		s.ExpressionAttributeNames = e.Names()
+		s.ExpressionAttributeValues = e.Values()
+	} else if err != expr.ErrEmptyBuilder {
+		return err
+	}
+	segments := input.Segments
+	if segments < 1 {
+		segments = 1
+	}
+	var (
+		mu    sync.Mutex
+		items []map[string]*dynamodb.AttributeValue
+	)
+	err := ParallelScanWithContext(ctx, t.client, s, segments, func(item map[string]*dynamodb.AttributeValue) error {
+		mu.Lock()
+		items = append(items, item)
+		mu.Unlock()
+		return nil
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	return dynamodbattribute.UnmarshalListOfMaps(items, out)
+}
+
+// BatchGet reads the items with the keys, a slice, into out, a pointer to a slice of the item type.
+// Keys without an item are skipped, and items are in no particular order. Unprocessed keys are
+// retried with an exponential backoff, and ErrTableUnprocessed is returned if some are still
+// unprocessed after several attempts.
+func (t *Table) BatchGet(keys, out interface{}) error {
+	return t.BatchGetWithContext(context.Background(), keys, out)
+}
+
+// BatchGetWithContext is the same as BatchGet.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func (t *Table) BatchGetWithContext(ctx context.Context, keys, out interface{}, opts ...request.Option) error {
+	if err := t.checkSlice(out); err != nil {
+		return err
+	}
+	if keys == nil {
+		return ErrInterfaceNil
+	}
+	kv := reflect.ValueOf(keys)
+	if kv.Kind() != reflect.Slice {
+		return ErrInterfaceSlice
+	}
+	pending := make([]map[string]*dynamodb.AttributeValue, 0, kv.Len())
+	for i := 0; i < kv.Len(); i++ {
+		k, err := t.key(kv.Index(i).Interface())
+		if err != nil {
+			return err
+		}
+		pending = append(pending, k)
+	}
+	ka := &dynamodb.KeysAndAttributes{}
+	if t.projection.IsSet() {
+		e, err := expr.NewBuilder().WithProjection(t.projection).Build()
+		if err != nil {
+			return err
+		}
+		ka.ProjectionExpression = e.Projection()
+		ka.ExpressionAttributeNames = e.Names()
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	attempts := 0
+	for len(pending) > 0 {
+		end := batchGetSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := *ka
+		batch.Keys = pending[:end]
+		pending = pending[end:]
+		res, err := t.client.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]*dynamodb.KeysAndAttributes{t.name: &batch},
+		}, opts...)
+		if err != nil {
+			return err
+		}
+		items = append(items, res.Responses[t.name]...)
+
+		unprocessed := res.UnprocessedKeys[t.name]
+		if unprocessed == nil || len(unprocessed.Keys) == 0 {
+			attempts = 0
+			continue
+		}
+		pending = append(unprocessed.Keys, pending...)
+		attempts++
+		if attempts > batchWriteRetries {
+			return ErrTableUnprocessed
+		}
+		if err := aws.SleepWithContext(ctx, batchWriteBackoff(attempts)); err != nil {
+			return err
+		}
+	}
+	return dynamodbattribute.UnmarshalListOfMaps(items, out)
+}
+
+// BatchPut writes the items, a slice of the item type, with BatchWriteItem. ErrTableUnprocessed is
+// returned if some items are still unprocessed after several attempts.
+//
+// BatchWriteItem cannot check versions, so versioned items are written with ConditionalBatchPut
+// instead. The versions of the items written are incremented if they are pointers, and
+// ErrVersionConflict is returned after the other items are written if some items were modified
+// since they were read. The timestamps of the items written are updated if they are pointers.
+func (t *Table) BatchPut(items interface{}) error {
+	return t.BatchPutWithContext(context.Background(), items)
+}
+
+// BatchPutWithContext is the same as BatchPut.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func (t *Table) BatchPutWithContext(ctx context.Context, items interface{}, opts ...request.Option) error {
+	if items != nil {
+		if it := reflect.TypeOf(items); it.Kind() == reflect.Slice && !t.isItemType(it.Elem()) {
+			return ErrTableItemType
+		}
+	}
+	err := batchPut(ctx, t.client, t.name, items, t.version, t.timestamps, t.now(), opts...)
+	if err == ErrBatchPutUnprocessed {
+		return ErrTableUnprocessed
+	}
+	return err
+}
+
+// key returns the key attributes of v.
+func (t *Table) key(v interface{}) (map[string]*dynamodb.AttributeValue, error) {
+	av, ok := v.(map[string]*dynamodb.AttributeValue)
+	if !ok {
+		var err error
+		if av, err = dynamodbattribute.MarshalMap(v); err != nil {
+			return nil, err
+		}
+	}
+	key := make(map[string]*dynamodb.AttributeValue, len(t.keys))
+	for _, name := range t.keys {
+		k := av[name]
+		if k == nil || k.NULL != nil {
+			return nil, ErrTableKey
+		}
+		key[name] = k
+	}
+	return key, nil
+}
+
+// isItemType reports whether t is the item type or a pointer to it.
+func (t *Table) isItemType(typ reflect.Type) bool {
+	return typ == t.itemType || typ.Kind() == reflect.Ptr && typ.Elem() == t.itemType
+}
+
+// checkItem checks that v is a pointer to the item type, or also the item type if value is true.
+func (t *Table) checkItem(v interface{}, value bool) error {
+	typ := reflect.TypeOf(v)
+	if typ == nil || !t.isItemType(typ) || !value && typ.Kind() != reflect.Ptr {
+		return ErrTableItemType
+	}
+	return nil
+}
+
+// checkSlice checks that v is a pointer to a slice of the item type.
+func (t *Table) checkSlice(v interface{}) error {
+	typ := reflect.TypeOf(v)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Slice || !t.isItemType(typ.Elem().Elem()) {
+		return ErrTableItemType
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package dynamodbx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// DiffKind identifies which part of a table a TableDifference refers to.
+type DiffKind string
+
+// The kinds of difference reported by Diff.
+const (
+	DiffKeySchema             DiffKind = "KeySchema"
+	DiffMissingIndex          DiffKind = "MissingIndex"
+	DiffExtraIndex            DiffKind = "ExtraIndex"
+	DiffProjection            DiffKind = "Projection"
+	DiffBillingMode           DiffKind = "BillingMode"
+	DiffProvisionedThroughput DiffKind = "ProvisionedThroughput"
+	DiffStream                DiffKind = "Stream"
+	DiffSSE                   DiffKind = "SSE"
+)
+
+// TableDifference is a single mismatch between the desired and live table. Index is empty for table
+// level differences. Want and Got are human-readable renderings of the desired and live settings.
+type TableDifference struct {
+	Kind  DiffKind
+	Index string
+	Want  string
+	Got   string
+}
+
+// TableDiff is the result of comparing a live table to the CreateTableInput it should match.
+type TableDiff struct {
+	TableName   string
+	Differences []TableDifference
+}
+
+// Equal reports whether the live table matched the desired table.
+func (d *TableDiff) Equal() bool {
+	return len(d.Differences) == 0
+}
+
+// String renders the diff one difference per line, or a single line stating the table is up to date.
+func (d *TableDiff) String() string {
+	if d.Equal() {
+		return fmt.Sprintf("table %s: up to date\n", d.TableName)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "table %s: %d difference(s)\n", d.TableName, len(d.Differences))
+	for _, v := range d.Differences {
+		where := "table"
+		if v.Index != "" {
+			where = "index " + v.Index
+		}
+		switch v.Kind {
+		case DiffMissingIndex:
+			fmt.Fprintf(&b, "  + %s: missing, want %s\n", where, v.Want)
+		case DiffExtraIndex:
+			fmt.Fprintf(&b, "  - %s: not in desired schema, got %s\n", where, v.Got)
+		default:
+			fmt.Fprintf(&b, "  ~ %s %s: want %s, got %s\n", where, v.Kind, v.Want, v.Got)
+		}
+	}
+	return b.String()
+}
+
+// Diff describes the live table named in input and reports how it differs from input. Key schemas,
+// global and local secondary indexes and their projections, billing mode, provisioned throughput,
+// streams and server side encryption are compared.
+func Diff(client *dynamodb.DynamoDB, input *dynamodb.CreateTableInput) (*TableDiff, error) {
+	return DiffWithContext(context.Background(), client, input)
+}
+
+// DiffWithContext is the same as Diff.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func DiffWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.CreateTableInput, opts ...request.Option) (*TableDiff, error) {
+	out, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: input.TableName}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return DiffTable(input, out.Table), nil
+}
+
+// DiffTable compares a table description, as returned by DescribeTable, to the desired input without
+// making any calls to dynamodb.
+func DiffTable(want *dynamodb.CreateTableInput, got *dynamodb.TableDescription) *TableDiff {
+	d := &TableDiff{TableName: aws.StringValue(want.TableName)}
+	add := func(kind DiffKind, index, w, g string) {
+		if w != g {
+			d.Differences = append(d.Differences, TableDifference{Kind: kind, Index: index, Want: w, Got: g})
+		}
+	}
+
+	add(DiffKeySchema, "", formatKeySchema(want.KeySchema), formatKeySchema(got.KeySchema))
+
+	wantBilling := aws.StringValue(want.BillingMode)
+	if wantBilling == "" {
+		wantBilling = dynamodb.BillingModeProvisioned
+	}
+	gotBilling := dynamodb.BillingModeProvisioned
+	if got.BillingModeSummary != nil && got.BillingModeSummary.BillingMode != nil {
+		gotBilling = *got.BillingModeSummary.BillingMode
+	}
+	add(DiffBillingMode, "", wantBilling, gotBilling)
+	provisioned := wantBilling == dynamodb.BillingModeProvisioned && gotBilling == dynamodb.BillingModeProvisioned
+	if provisioned {
+		add(DiffProvisionedThroughput, "", formatThroughput(want.ProvisionedThroughput), formatThroughputDescription(got.ProvisionedThroughput))
+	}
+
+	add(DiffStream, "", formatStream(want.StreamSpecification), formatStream(got.StreamSpecification))
+	wantSSE, gotSSE := formatSSESpecification(want.SSESpecification), formatSSEDescription(got.SSEDescription)
+	// Without an explicit SSEType only whether encryption is enabled is compared
+	if wantSSE == "enabled" && strings.HasPrefix(gotSSE, "enabled") {
+		gotSSE = wantSSE
+	}
+	add(DiffSSE, "", wantSSE, gotSSE)
+
+	gotGSIs := make(map[string]*dynamodb.GlobalSecondaryIndexDescription, len(got.GlobalSecondaryIndexes))
+	for _, v := range got.GlobalSecondaryIndexes {
+		gotGSIs[aws.StringValue(v.IndexName)] = v
+	}
+	for _, w := range want.GlobalSecondaryIndexes {
+		name := aws.StringValue(w.IndexName)
+		g, ok := gotGSIs[name]
+		if !ok {
+			add(DiffMissingIndex, name, formatIndex(w.KeySchema, w.Projection), "")
+			continue
+		}
+		delete(gotGSIs, name)
+		add(DiffKeySchema, name, formatKeySchema(w.KeySchema), formatKeySchema(g.KeySchema))
+		add(DiffProjection, name, formatProjection(w.Projection), formatProjection(g.Projection))
+		if provisioned {
+			add(DiffProvisionedThroughput, name, formatThroughput(w.ProvisionedThroughput), formatThroughputDescription(g.ProvisionedThroughput))
+		}
+	}
+	for _, g := range got.GlobalSecondaryIndexes {
+		if _, ok := gotGSIs[aws.StringValue(g.IndexName)]; ok {
+			add(DiffExtraIndex, aws.StringValue(g.IndexName), "", formatIndex(g.KeySchema, g.Projection))
+		}
+	}
+
+	gotLSIs := make(map[string]*dynamodb.LocalSecondaryIndexDescription, len(got.LocalSecondaryIndexes))
+	for _, v := range got.LocalSecondaryIndexes {
+		gotLSIs[aws.StringValue(v.IndexName)] = v
+	}
+	for _, w := range want.LocalSecondaryIndexes {
+		name := aws.StringValue(w.IndexName)
+		g, ok := gotLSIs[name]
+		if !ok {
+			add(DiffMissingIndex, name, formatIndex(w.KeySchema, w.Projection), "")
+			continue
+		}
+		delete(gotLSIs, name)
+		add(DiffKeySchema, name, formatKeySchema(w.KeySchema), formatKeySchema(g.KeySchema))
+		add(DiffProjection, name, formatProjection(w.Projection), formatProjection(g.Projection))
+	}
+	for _, g := range got.LocalSecondaryIndexes {
+		if _, ok := gotLSIs[aws.StringValue(g.IndexName)]; ok {
+			add(DiffExtraIndex, aws.StringValue(g.IndexName), "", formatIndex(g.KeySchema, g.Projection))
+		}
+	}
+	return d
+}
+
+func formatKeySchema(ks []*dynamodb.KeySchemaElement) string {
+	parts := make([]string, 0, len(ks))
+	for _, k := range ks {
+		parts = append(parts, aws.StringValue(k.AttributeName)+" "+aws.StringValue(k.KeyType))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatProjection(p *dynamodb.Projection) string {
+	if p == nil || p.ProjectionType == nil {
+		return dynamodb.ProjectionTypeKeysOnly
+	}
+	if len(p.NonKeyAttributes) == 0 {
+		return *p.ProjectionType
+	}
+	attrs := aws.StringValueSlice(p.NonKeyAttributes)
+	sort.Strings(attrs)
+	return *p.ProjectionType + " [" + strings.Join(attrs, ", ") + "]"
+}
+
+func formatIndex(ks []*dynamodb.KeySchemaElement, p *dynamodb.Projection) string {
+	return formatKeySchema(ks) + " " + formatProjection(p)
+}
+
+func formatThroughput(p *dynamodb.ProvisionedThroughput) string {
+	if p == nil {
+		return "none"
+	}
+	return fmt.Sprintf("read=%d write=%d", aws.Int64Value(p.ReadCapacityUnits), aws.Int64Value(p.WriteCapacityUnits))
+}
+
+func formatThroughputDescription(p *dynamodb.ProvisionedThroughputDescription) string {
+	if p == nil {
+		return "none"
+	}
+	return fmt.Sprintf("read=%d write=%d", aws.Int64Value(p.ReadCapacityUnits), aws.Int64Value(p.WriteCapacityUnits))
+}
+
+func formatStream(s *dynamodb.StreamSpecification) string {
+	if s == nil || !aws.BoolValue(s.StreamEnabled) {
+		return "disabled"
+	}
+	return aws.StringValue(s.StreamViewType)
+}
+
+func formatSSESpecification(s *dynamodb.SSESpecification) string {
+	if s == nil || !aws.BoolValue(s.Enabled) {
+		return "disabled"
+	}
+	if s.SSEType == nil {
+		return "enabled"
+	}
+	return "enabled " + *s.SSEType
+}
+
+func formatSSEDescription(s *dynamodb.SSEDescription) string {
+	if s == nil {
+		return "disabled"
+	}
+	switch aws.StringValue(s.Status) {
+	case dynamodb.SSEStatusEnabled, dynamodb.SSEStatusEnabling, dynamodb.SSEStatusUpdating:
+	default:
+		return "disabled"
+	}
+	if s.SSEType == nil {
+		return "enabled"
+	}
+	return "enabled " + *s.SSEType
+}
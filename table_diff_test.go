@@ -0,0 +1,174 @@
+package dynamodbx_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+)
+
+func TestDiffTable(t *testing.T) {
+	t.Parallel()
+	hash := func(name string) []*dynamodb.KeySchemaElement {
+		return []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(name), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		}
+	}
+	desired := &dynamodb.CreateTableInput{
+		TableName:   aws.String("test"),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		KeySchema:   hash("S"),
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{
+			{
+				IndexName: aws.String("byFoo"),
+				KeySchema: hash("Foo"),
+				Projection: &dynamodb.Projection{
+					ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
+					NonKeyAttributes: aws.StringSlice([]string{"B", "A"}),
+				},
+			},
+			{
+				IndexName:  aws.String("byBar"),
+				KeySchema:  hash("Bar"),
+				Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+			},
+		},
+		StreamSpecification: &dynamodb.StreamSpecification{
+			StreamEnabled:  aws.Bool(true),
+			StreamViewType: aws.String(dynamodb.StreamViewTypeNewImage),
+		},
+		SSESpecification: &dynamodb.SSESpecification{Enabled: aws.Bool(true)},
+	}
+	for _, tc := range []struct {
+		name  string
+		input *dynamodb.CreateTableInput
+		table *dynamodb.TableDescription
+		diffs []dynamodbx.TableDifference
+	}{
+		{
+			name:  "up to date",
+			input: desired,
+			table: &dynamodb.TableDescription{
+				TableName:          aws.String("test"),
+				KeySchema:          hash("S"),
+				BillingModeSummary: &dynamodb.BillingModeSummary{BillingMode: aws.String(dynamodb.BillingModePayPerRequest)},
+				GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+					{
+						IndexName:  aws.String("byBar"),
+						KeySchema:  hash("Bar"),
+						Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+					},
+					{
+						IndexName: aws.String("byFoo"),
+						KeySchema: hash("Foo"),
+						Projection: &dynamodb.Projection{
+							ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
+							NonKeyAttributes: aws.StringSlice([]string{"A", "B"}),
+						},
+					},
+				},
+				StreamSpecification: &dynamodb.StreamSpecification{
+					StreamEnabled:  aws.Bool(true),
+					StreamViewType: aws.String(dynamodb.StreamViewTypeNewImage),
+				},
+				SSEDescription: &dynamodb.SSEDescription{
+					Status:  aws.String(dynamodb.SSEStatusEnabled),
+					SSEType: aws.String(dynamodb.SSETypeKms),
+				},
+			},
+		},
+		{
+			name:  "everything differs",
+			input: desired,
+			table: &dynamodb.TableDescription{
+				TableName: aws.String("test"),
+				KeySchema: hash("P"),
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+					ReadCapacityUnits:  aws.Int64(5),
+					WriteCapacityUnits: aws.Int64(5),
+				},
+				GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+					{
+						IndexName:  aws.String("byFoo"),
+						KeySchema:  hash("Foo"),
+						Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+					},
+					{
+						IndexName:  aws.String("byBaz"),
+						KeySchema:  hash("Baz"),
+						Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly)},
+					},
+				},
+			},
+			diffs: []dynamodbx.TableDifference{
+				{Kind: dynamodbx.DiffKeySchema, Want: "[S HASH]", Got: "[P HASH]"},
+				{Kind: dynamodbx.DiffBillingMode, Want: "PAY_PER_REQUEST", Got: "PROVISIONED"},
+				{Kind: dynamodbx.DiffStream, Want: "NEW_IMAGE", Got: "disabled"},
+				{Kind: dynamodbx.DiffSSE, Want: "enabled", Got: "disabled"},
+				{Kind: dynamodbx.DiffProjection, Index: "byFoo", Want: "INCLUDE [A, B]", Got: "ALL"},
+				{Kind: dynamodbx.DiffMissingIndex, Index: "byBar", Want: "[Bar HASH] ALL"},
+				{Kind: dynamodbx.DiffExtraIndex, Index: "byBaz", Got: "[Baz HASH] KEYS_ONLY"},
+			},
+		},
+		{
+			name: "provisioned throughput",
+			input: &dynamodb.CreateTableInput{
+				TableName: aws.String("test"),
+				KeySchema: hash("S"),
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(10),
+					WriteCapacityUnits: aws.Int64(5),
+				},
+			},
+			table: &dynamodb.TableDescription{
+				TableName: aws.String("test"),
+				KeySchema: hash("S"),
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+					ReadCapacityUnits:  aws.Int64(5),
+					WriteCapacityUnits: aws.Int64(5),
+				},
+			},
+			diffs: []dynamodbx.TableDifference{
+				{Kind: dynamodbx.DiffProvisionedThroughput, Want: "read=10 write=5", Got: "read=5 write=5"},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			diff := dynamodbx.DiffTable(tc.input, tc.table)
+			if diff.Equal() != (len(tc.diffs) == 0) {
+				t.Fatalf("expected equal to be %v:\n%s", len(tc.diffs) == 0, diff)
+			}
+			if !reflect.DeepEqual(diff.Differences, tc.diffs) {
+				t.Fatal(pretty.Compare(diff.Differences, tc.diffs))
+			}
+		})
+	}
+}
+
+func TestTableDiffString(t *testing.T) {
+	t.Parallel()
+	diff := &dynamodbx.TableDiff{
+		TableName: "test",
+		Differences: []dynamodbx.TableDifference{
+			{Kind: dynamodbx.DiffBillingMode, Want: "PAY_PER_REQUEST", Got: "PROVISIONED"},
+			{Kind: dynamodbx.DiffMissingIndex, Index: "byBar", Want: "[Bar HASH] ALL"},
+			{Kind: dynamodbx.DiffExtraIndex, Index: "byBaz", Got: "[Baz HASH] KEYS_ONLY"},
+		},
+	}
+	expect := `table test: 3 difference(s)
+  ~ table BillingMode: want PAY_PER_REQUEST, got PROVISIONED
+  + index byBar: missing, want [Bar HASH] ALL
+  - index byBaz: not in desired schema, got [Baz HASH] KEYS_ONLY
+`
+	if got := diff.String(); got != expect {
+		t.Fatal(pretty.Compare(got, expect))
+	}
+	if got := (&dynamodbx.TableDiff{TableName: "test"}).String(); got != "table test: up to date\n" {
+		t.Fatalf("unexpected rendering of empty diff: %q", got)
+	}
+}
@@ -0,0 +1,211 @@
+package dynamodbx_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/dynamodbxtest"
+	"github.com/kynrai/dynamodbx/expr"
+	"github.com/kynrai/dynamodbx/faultdb"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+type order struct {
+	Customer string
+	ID       int
+	Status   string
+	Total    float64
+	Note     string `dynamodbav:",omitempty"`
+}
+
+// orderKey is the key of an order.
+type orderKey struct {
+	Customer string
+	ID       int
+}
+
+var ordersSpec = &dynamodb.CreateTableInput{
+	TableName:   aws.String("testTable"),
+	BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+	AttributeDefinitions: []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String("Customer"), AttributeType: aws.String("S")},
+		{AttributeName: aws.String("ID"), AttributeType: aws.String("N")},
+	},
+	KeySchema: []*dynamodb.KeySchemaElement{
+		{AttributeName: aws.String("Customer"), KeyType: aws.String("HASH")},
+		{AttributeName: aws.String("ID"), KeyType: aws.String("RANGE")},
+	},
+}
+
+// newOrders returns the Table of a new orders table holding the fixtures.
+func newOrders(t *testing.T, ddb *dynamodb.DynamoDB, fixtures ...interface{}) (*dynamodbx.Table, *dynamodbxtest.Table) {
+	t.Helper()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, ordersSpec, fixtures...)
+	orders, err := dynamodbx.NewTable(ddb, tbl.Name, ordersSpec.KeySchema, order{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return orders, tbl
+}
+
+func TestNewTable(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name string
+		item interface{}
+		err  error
+	}{
+		{name: "", item: order{}, err: dynamodbx.ErrTableName},
+		{name: "test", item: nil, err: dynamodbx.ErrTableItemType},
+		{name: "test", item: []order{}, err: dynamodbx.ErrTableItemType},
+		{name: "test", item: &order{}},
+	} {
+		if _, err := dynamodbx.NewTable(memdb.NewClient(), tc.name, ordersSpec.KeySchema, tc.item); err != tc.err {
+			t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	orders, tbl := newOrders(t, ddb, []order{
+		{"a", 1, "paid", 10, ""},
+		{"a", 2, "shipped", 20, "fragile"},
+		{"a", 3, "paid", 30, ""},
+		{"b", 1, "paid", 5, ""},
+	})
+
+	var got order
+	if err := orders.Get(orderKey{"a", 2}, &got); err != nil {
+		t.Fatal(err)
+	}
+	if expect := (order{"a", 2, "shipped", 20, "fragile"}); got != expect {
+		t.Fatal(pretty.Compare(got, expect))
+	}
+	if err := orders.Get(orderKey{"a", 9}, &got); err != dynamodbx.ErrTableNotFound {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrTableNotFound)
+	}
+
+	if err := orders.Put(&order{"c", 1, "new", 1, ""}, expr.Name("Customer").AttributeNotExists()); err != nil {
+		t.Fatal(err)
+	}
+	err := orders.Put(order{"c", 1, "dup", 1, ""}, expr.Name("Customer").AttributeNotExists())
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodb.ErrCodeConditionalCheckFailedException {
+		t.Fatalf("expected a conditional check failure, got %v", err)
+	}
+
+	var updated order
+	if err := orders.Update(order{Customer: "b", ID: 1}, expr.Set(expr.Name("Status"), expr.Value("refunded")),
+		expr.Name("Status").Equal(expr.Value("paid")), &updated); err != nil {
+		t.Fatal(err)
+	}
+	if expect := (order{"b", 1, "refunded", 5, ""}); updated != expect {
+		t.Fatal(pretty.Compare(updated, expect))
+	}
+	if err := orders.Update(orderKey{"b", 1}, expr.Remove(expr.Name("Note")), expr.ConditionBuilder{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := orders.Delete(orderKey{"c", 1}, expr.Name("Status").Equal(expr.Value("new"))); err != nil {
+		t.Fatal(err)
+	}
+
+	var paid []*order
+	if err := orders.Query(&dynamodbx.TableQueryInput{
+		KeyCondition: expr.Key("Customer").Equal(expr.Value("a")),
+		Filter:       expr.Name("Status").Equal(expr.Value("paid")),
+		Descending:   true,
+	}, &paid); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []*order{{"a", 3, "paid", 30, ""}, {"a", 1, "paid", 10, ""}}; !reflect.DeepEqual(paid, expect) {
+		t.Fatal(pretty.Compare(paid, expect))
+	}
+
+	var all []order
+	if err := orders.Scan(&dynamodbx.TableScanInput{Filter: expr.Name("Total").GreaterThan(expr.Value(5)), Segments: 2}, &all); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	if expect := []order{{"a", 1, "paid", 10, ""}, {"a", 2, "shipped", 20, "fragile"}, {"a", 3, "paid", 30, ""}}; !reflect.DeepEqual(all, expect) {
+		t.Fatal(pretty.Compare(all, expect))
+	}
+
+	tbl.AssertItems([]order{
+		{"a", 1, "paid", 10, ""},
+		{"a", 2, "shipped", 20, "fragile"},
+		{"a", 3, "paid", 30, ""},
+		{"b", 1, "refunded", 5, ""},
+	})
+}
+
+func TestTableBatch(t *testing.T) {
+	t.Parallel()
+	mem := memdb.NewClient()
+	_, tbl := newOrders(t, mem)
+	faults := faultdb.New(faultdb.Config{Seed: 1, UnprocessedRate: 0.02})
+	orders, err := dynamodbx.NewTable(faults.Wrap(mem), tbl.Name, ordersSpec.KeySchema, &order{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := make([]*order, 150)
+	keys := make([]orderKey, 0, len(items)+1)
+	for i := range items {
+		items[i] = &order{Customer: "a", ID: i, Status: "paid", Total: float64(i)}
+		keys = append(keys, orderKey{"a", i})
+	}
+	if err := orders.BatchPut(items); err != nil {
+		t.Fatal(err)
+	}
+	tbl.AssertItems(items)
+
+	var got []order
+	if err := orders.BatchGet(append(keys, orderKey{"b", 1}), &got); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].ID < got[j].ID })
+	if len(got) != len(items) {
+		t.Fatalf("expected %d items, got %d", len(items), len(got))
+	}
+	for i, it := range got {
+		if it != *items[i] {
+			t.Fatal(pretty.Compare(it, items[i]))
+		}
+	}
+	if stats := faults.Stats(); stats.UnprocessedItems == 0 || stats.UnprocessedKeys == 0 {
+		t.Fatalf("expected some items and keys to be unprocessed, got %+v", stats)
+	}
+}
+
+func TestTableErrors(t *testing.T) {
+	t.Parallel()
+	orders, _ := newOrders(t, memdb.NewClient())
+	var o order
+	var os []order
+	for i, tc := range []struct {
+		err    error
+		expect error
+	}{
+		{orders.Get(orderKey{"a", 1}, o), dynamodbx.ErrTableItemType},
+		{orders.Get(orderKey{"a", 1}, &orderKey{}), dynamodbx.ErrTableItemType},
+		{orders.Get(struct{ Customer string }{"a"}, &o), dynamodbx.ErrTableKey},
+		{orders.Get(orderKey{"", 1}, &o), dynamodbx.ErrTableKey},
+		{orders.Put(orderKey{"a", 1}, expr.ConditionBuilder{}), dynamodbx.ErrTableItemType},
+		{orders.Update(orderKey{"a", 1}, expr.UpdateBuilder{}, expr.ConditionBuilder{}, nil), expr.ErrEmptyUpdate},
+		{orders.Query(&dynamodbx.TableQueryInput{KeyCondition: expr.Key("Customer").Equal(expr.Value("a"))}, &o), dynamodbx.ErrTableItemType},
+		{orders.Scan(nil, os), dynamodbx.ErrTableItemType},
+		{orders.BatchGet(orderKey{"a", 1}, &os), dynamodbx.ErrInterfaceSlice},
+		{orders.BatchPut([]orderKey{{"a", 1}}), dynamodbx.ErrTableItemType},
+		{orders.BatchPut(nil), dynamodbx.ErrInterfaceNil},
+	} {
+		if tc.err != tc.expect {
+			t.Fatalf("%d: expected error mismatch: got: %v, want: %v", i, tc.err, tc.expect)
+		}
+	}
+}
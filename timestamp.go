@@ -0,0 +1,229 @@
+package dynamodbx
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/kynrai/dynamodbx/expr"
+)
+
+var ErrTimestampType = errors.New("dynamodbx/Timestamp: timestamp fields must be a time.Time, a string or a signed integer, or a pointer to one")
+
+// ItemOption configures how BatchPutRequest, ConditionalPutRequest and UpdateItemDiff write items.
+type ItemOption func(*itemOptions)
+
+type itemOptions struct {
+	clock func() time.Time
+}
+
+// WithClock sets the clock the created and updated timestamps of items are stamped with, for
+// example a fixed time in tests. Defaults to time.Now. Table.WithClock sets the clock of a Table.
+func WithClock(clock func() time.Time) ItemOption {
+	return func(o *itemOptions) {
+		o.clock = clock
+	}
+}
+
+// clockTime returns the time of the clock set by the options.
+func clockTime(opts []ItemOption) time.Time {
+	o := itemOptions{clock: time.Now}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o.clock()
+}
+
+// Tags marking the timestamp fields of an item type, as in
+//
+//	Created time.Time `dynamodbx:"created"`
+//	Updated int64     `dynamodbx:"updated"`
+const (
+	tagCreated = "created"
+	tagUpdated = "updated"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	unixTimeType = reflect.TypeOf(dynamodbattribute.UnixTime{})
+)
+
+// timestamp is a created or updated field of an item type. Its encoding follows its type:
+// time.Time is an RFC3339 string with nanoseconds, as dynamodbattribute encodes it, or a number of
+// seconds since the epoch if it is tagged `dynamodbav:",unixtime"` or is a
+// dynamodbattribute.UnixTime. Strings are RFC3339 strings in UTC and integers are seconds since the
+// epoch. The field may also be a pointer to any of these, which is unset while it is nil.
+type timestamp struct {
+	index []int
+	name  string
+	typ   reflect.Type
+	epoch bool
+	ptr   bool
+}
+
+// timestamps are the timestamp fields of an item type, either of which may be nil.
+type timestamps struct {
+	created, updated *timestamp
+}
+
+// itemTimestamps returns the timestamp fields of the struct type t, or nil if it has none.
+func itemTimestamps(t reflect.Type) (*timestamps, error) {
+	var ts timestamps
+	for _, f := range []struct {
+		tag string
+		ts  **timestamp
+	}{{tagCreated, &ts.created}, {tagUpdated, &ts.updated}} {
+		sf, ok := taggedField(t, f.tag)
+		if !ok {
+			continue
+		}
+		stamp := &timestamp{index: sf.Index, name: attributeName(sf), typ: sf.Type}
+		if stamp.typ.Kind() == reflect.Ptr {
+			stamp.typ = stamp.typ.Elem()
+			stamp.ptr = true
+		}
+		switch {
+		case stamp.typ == unixTimeType:
+			stamp.epoch = true
+		case stamp.typ.ConvertibleTo(timeType):
+			stamp.epoch = hasOption(sf.Tag.Get("dynamodbav"), "unixtime")
+		case stamp.typ.Kind() == reflect.String:
+		case stamp.typ.Kind() >= reflect.Int && stamp.typ.Kind() <= reflect.Int64:
+			stamp.epoch = true
+		default:
+			return nil, ErrTimestampType
+		}
+		*f.ts = stamp
+	}
+	if ts.created == nil && ts.updated == nil {
+		return nil, nil
+	}
+	return &ts, nil
+}
+
+// value returns the encoded time.
+func (s *timestamp) value(now time.Time) *dynamodb.AttributeValue {
+	switch {
+	case s.epoch:
+		return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(now.Unix(), 10))}
+	case s.typ.Kind() == reflect.String:
+		return &dynamodb.AttributeValue{S: aws.String(now.UTC().Format(time.RFC3339))}
+	}
+	return &dynamodb.AttributeValue{S: aws.String(now.UTC().Format(time.RFC3339Nano))}
+}
+
+// set sets the field of the item, a pointer to a struct, to the time as it is stored.
+func (s *timestamp) set(item reflect.Value, now time.Time) {
+	f := item.Elem().FieldByIndex(s.index)
+	if s.ptr {
+		f.Set(reflect.New(s.typ))
+		f = f.Elem()
+	}
+	switch {
+	case s.typ.ConvertibleTo(timeType) && s.epoch:
+		f.Set(reflect.ValueOf(time.Unix(now.Unix(), 0)).Convert(s.typ))
+	case s.typ.ConvertibleTo(timeType):
+		f.Set(reflect.ValueOf(now.UTC()).Convert(s.typ))
+	case s.typ.Kind() == reflect.String:
+		f.SetString(now.UTC().Format(time.RFC3339))
+	default:
+		f.SetInt(now.Unix())
+	}
+}
+
+// isZero reports whether the field of the item, a struct or a pointer to one, is unset.
+func (s *timestamp) isZero(item reflect.Value) bool {
+	if item.Kind() == reflect.Ptr && item.IsNil() {
+		return true
+	}
+	f := reflect.Indirect(item).FieldByIndex(s.index)
+	if s.ptr {
+		if f.IsNil() {
+			return true
+		}
+		f = f.Elem()
+	}
+	if f.Type().ConvertibleTo(timeType) {
+		return f.Convert(timeType).Interface().(time.Time).IsZero()
+	}
+	return reflect.DeepEqual(f.Interface(), reflect.Zero(f.Type()).Interface())
+}
+
+// put stamps the marshalled item: the updated time is always set, and the created time is set if
+// the item does not have one, as it has not been written before.
+func (ts *timestamps) put(item reflect.Value, av map[string]*dynamodb.AttributeValue, now time.Time) {
+	if ts.created != nil && ts.created.isZero(item) {
+		av[ts.created.name] = ts.created.value(now)
+	}
+	if ts.updated != nil {
+		av[ts.updated.name] = ts.updated.value(now)
+	}
+}
+
+// written sets the timestamps of the item as put stamped them, if it is a pointer to a struct,
+// after it has been written.
+func (ts *timestamps) written(item reflect.Value, now time.Time) {
+	if item.Kind() != reflect.Ptr || item.IsNil() {
+		return
+	}
+	if ts.created != nil && ts.created.isZero(item) {
+		ts.created.set(item, now)
+	}
+	if ts.updated != nil {
+		ts.updated.set(item, now)
+	}
+}
+
+// update returns the update which also sets the updated time, and the created time if the item
+// does not exist yet.
+func (ts *timestamps) update(u expr.UpdateBuilder, now time.Time) expr.UpdateBuilder {
+	if ts.created != nil {
+		name := expr.AttributeName(ts.created.name)
+		u = u.Set(name, name.IfNotExists(expr.Value(ts.created.value(now))))
+	}
+	if ts.updated != nil {
+		u = u.Set(expr.AttributeName(ts.updated.name), expr.Value(ts.updated.value(now)))
+	}
+	return u
+}
+
+// names returns the attribute names of the timestamps.
+func (ts *timestamps) names() []string {
+	var names []string
+	for _, s := range []*timestamp{ts.created, ts.updated} {
+		if s != nil {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+// hasOption reports whether the struct tag value has the option.
+func hasOption(tag, option string) bool {
+	for _, opt := range strings.Split(tag, ",")[1:] {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// structType returns the struct type of v, a struct, a pointer to one or a slice of either, or nil.
+func structType(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
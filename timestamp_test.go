@@ -0,0 +1,315 @@
+package dynamodbx_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/dynamodbxtest"
+	"github.com/kynrai/dynamodbx/expr"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+type stamped struct {
+	S       string
+	Value   string
+	Created time.Time `dynamodbx:"created"`
+	Updated int64     `dynamodbav:"u" dynamodbx:"updated"`
+}
+
+// fixedClock returns a clock which returns the times in turn, and the last time once they run out.
+func fixedClock(times ...time.Time) func() time.Time {
+	return func() time.Time {
+		now := times[0]
+		if len(times) > 1 {
+			times = times[1:]
+		}
+		return now
+	}
+}
+
+func TestTimestampTable(t *testing.T) {
+	t.Parallel()
+	first := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	third := second.Add(time.Hour)
+	ddb := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec)
+	items, err := dynamodbx.NewTable(ddb, tbl.Name, batchWriteSpec.KeySchema, stamped{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items = items.WithClock(fixedClock(first, second, third))
+
+	a := &stamped{S: "a", Value: "1"}
+	if err := items.Put(a, expr.ConditionBuilder{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (stamped{"a", "1", first, first.Unix()}); *a != want {
+		t.Fatalf("unexpected put item: got: %+v, want: %+v", *a, want)
+	}
+
+	// A second put keeps the created time of the item
+	a.Value = "2"
+	if err := items.Put(a, expr.ConditionBuilder{}); err != nil {
+		t.Fatal(err)
+	}
+	tbl.AssertItems(stamped{"a", "2", first, second.Unix()})
+
+	// Updates set the created time only if the item does not have one
+	var out stamped
+	if err := items.Update(&stamped{S: "a"}, expr.Set(expr.Name("Value"), expr.Value("3")), expr.ConditionBuilder{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := (stamped{"a", "3", first, third.Unix()}); out != want {
+		t.Fatalf("unexpected updated item: got: %+v, want: %+v", out, want)
+	}
+	if err := items.Update(&stamped{S: "b"}, expr.Set(expr.Name("Value"), expr.Value("1")), expr.ConditionBuilder{}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := []*stamped{{S: "c"}, {S: "d", Created: first}}
+	if err := items.BatchPut(batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch[0].Created != third || batch[0].Updated != third.Unix() || batch[1].Created != first {
+		t.Fatalf("unexpected batch items: %+v and %+v", *batch[0], *batch[1])
+	}
+	tbl.AssertItems([]stamped{
+		{"a", "3", first, third.Unix()},
+		{"b", "1", third, third.Unix()},
+		{"c", "", third, third.Unix()},
+		{"d", "", first, third.Unix()},
+	})
+}
+
+func TestTimestampEncoding(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2019, 3, 1, 12, 0, 0, 500, time.FixedZone("", 3600))
+	epoch := &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(now.Unix(), 10))}
+
+	for _, tc := range []struct {
+		name   string
+		item   interface{}
+		expect *dynamodb.AttributeValue
+	}{
+		{
+			name: "time",
+			item: &struct {
+				S       string
+				Updated time.Time `dynamodbx:"updated"`
+			}{S: "a"},
+			expect: &dynamodb.AttributeValue{S: aws.String("2019-03-01T11:00:00.0000005Z")},
+		},
+		{
+			name: "unix time tag",
+			item: &struct {
+				S       string
+				Updated time.Time `dynamodbav:",unixtime" dynamodbx:"updated"`
+			}{S: "a"},
+			expect: epoch,
+		},
+		{
+			name: "unix time",
+			item: &struct {
+				S       string
+				Updated dynamodbattribute.UnixTime `dynamodbx:"updated"`
+			}{S: "a"},
+			expect: epoch,
+		},
+		{
+			name: "string",
+			item: &struct {
+				S       string
+				Updated string `dynamodbx:"updated"`
+			}{S: "a"},
+			expect: &dynamodb.AttributeValue{S: aws.String("2019-03-01T11:00:00Z")},
+		},
+		{
+			name: "integer",
+			item: &struct {
+				S       string
+				Updated int32 `dynamodbx:"updated"`
+			}{S: "a"},
+			expect: epoch,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ddb := memdb.NewClient()
+			tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec)
+			items, err := dynamodbx.NewTable(ddb, tbl.Name, batchWriteSpec.KeySchema, tc.item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			items = items.WithClock(fixedClock(now))
+			if err := items.Put(tc.item, expr.ConditionBuilder{}); err != nil {
+				t.Fatal(err)
+			}
+			out, err := ddb.GetItem(&dynamodb.GetItemInput{
+				TableName: aws.String(tbl.Name),
+				Key:       map[string]*dynamodb.AttributeValue{"S": {S: aws.String("a")}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := pretty.Compare(out.Item["Updated"], tc.expect); diff != "" {
+				t.Fatalf("unexpected timestamp: %s", diff)
+			}
+			// The item is stamped with the time as it is stored
+			got := out.Item["Updated"]
+			av, err := dynamodbattribute.MarshalMap(tc.item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := pretty.Compare(av["Updated"], got); diff != "" {
+				t.Fatalf("unexpected item timestamp: %s", diff)
+			}
+		})
+	}
+}
+
+func TestTimestampRequests(t *testing.T) {
+	t.Parallel()
+	type item struct {
+		S       string
+		Value   string
+		Created string `dynamodbx:"created"`
+		Updated string `dynamodbx:"updated"`
+	}
+	now := time.Date(2019, 3, 2, 12, 0, 0, 0, time.UTC)
+	clock := dynamodbx.WithClock(func() time.Time { return now })
+	items := []item{{S: "a"}, {S: "b", Created: "2019-03-01T12:00:00Z"}}
+	expect := []item{
+		{S: "a", Created: "2019-03-02T12:00:00Z", Updated: "2019-03-02T12:00:00Z"},
+		{S: "b", Created: "2019-03-01T12:00:00Z", Updated: "2019-03-02T12:00:00Z"},
+	}
+
+	req, err := dynamodbx.BatchPutRequest("test", items, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var batch []item
+	for _, w := range req["test"] {
+		var it item
+		if err := dynamodbattribute.UnmarshalMap(w.PutRequest.Item, &it); err != nil {
+			t.Fatal(err)
+		}
+		batch = append(batch, it)
+	}
+	if diff := pretty.Compare(batch, expect); diff != "" {
+		t.Fatalf("unexpected batch put items: %s", diff)
+	}
+
+	puts, err := dynamodbx.ConditionalPutRequest(items, expr.ConditionBuilder{}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conditional []item
+	for _, put := range puts {
+		var it item
+		if err := dynamodbattribute.UnmarshalMap(put.Item, &it); err != nil {
+			t.Fatal(err)
+		}
+		conditional = append(conditional, it)
+	}
+	if diff := pretty.Compare(conditional, expect); diff != "" {
+		t.Fatalf("unexpected conditional put items: %s", diff)
+	}
+
+	input, err := dynamodbx.UpdateItemDiff("test", []string{"S"}, item{S: "a", Value: "1"}, item{S: "a", Value: "2"}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SET #n0 = :v0, #n1 = if_not_exists(#n1, :v1), #n2 = :v2"; aws.StringValue(input.UpdateExpression) != want {
+		t.Fatalf("unexpected update expression: got: %q, want: %q", aws.StringValue(input.UpdateExpression), want)
+	}
+	names := map[string]*string{"#n0": aws.String("Value"), "#n1": aws.String("Created"), "#n2": aws.String("Updated")}
+	if diff := pretty.Compare(input.ExpressionAttributeNames, names); diff != "" {
+		t.Fatalf("unexpected expression attribute names: %s", diff)
+	}
+	values := map[string]*dynamodb.AttributeValue{
+		":v0": {S: aws.String("2")},
+		":v1": {S: aws.String("2019-03-02T12:00:00Z")},
+		":v2": {S: aws.String("2019-03-02T12:00:00Z")},
+	}
+	if diff := pretty.Compare(input.ExpressionAttributeValues, values); diff != "" {
+		t.Fatalf("unexpected expression attribute values: %s", diff)
+	}
+}
+
+func TestTimestampPointer(t *testing.T) {
+	t.Parallel()
+	type item struct {
+		S       string
+		Created *time.Time `dynamodbx:"created"`
+		Updated *int64     `dynamodbx:"updated"`
+	}
+	first := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	ddb := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec)
+	items, err := dynamodbx.NewTable(ddb, tbl.Name, batchWriteSpec.KeySchema, item{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items = items.WithClock(fixedClock(first, second))
+
+	// Nil fields are unset, so both are stamped
+	a := &item{S: "a"}
+	if err := items.Put(a, expr.ConditionBuilder{}); err != nil {
+		t.Fatal(err)
+	}
+	if a.Created == nil || !a.Created.Equal(first) || a.Updated == nil || *a.Updated != first.Unix() {
+		t.Fatalf("unexpected put item: %+v", a)
+	}
+	// A set created time is kept
+	b := &item{S: "b", Created: &first}
+	if err := items.Put(b, expr.ConditionBuilder{}); err != nil {
+		t.Fatal(err)
+	}
+	if b.Created != &first || *b.Updated != second.Unix() {
+		t.Fatalf("unexpected put item: %+v", b)
+	}
+	var out item
+	if err := items.Get(&item{S: "a"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Created == nil || !out.Created.Equal(first) || out.Updated == nil || *out.Updated != first.Unix() {
+		t.Fatalf("unexpected stored item: %+v", out)
+	}
+
+	req, err := dynamodbx.BatchPutRequest("test", []item{{S: "c"}}, dynamodbx.WithClock(fixedClock(second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stamped := req["test"][0].PutRequest.Item
+	expect := map[string]*dynamodb.AttributeValue{
+		"S":       {S: aws.String("c")},
+		"Created": {S: aws.String("2019-03-01T13:00:00Z")},
+		"Updated": {N: aws.String(strconv.FormatInt(second.Unix(), 10))},
+	}
+	if diff := pretty.Compare(stamped, expect); diff != "" {
+		t.Fatalf("unexpected batch put item: %s", diff)
+	}
+}
+
+func TestTimestampType(t *testing.T) {
+	t.Parallel()
+	type badTimestamp struct {
+		S       string
+		Created float64 `dynamodbx:"created"`
+	}
+	if _, err := dynamodbx.NewTable(memdb.NewClient(), "test", batchWriteSpec.KeySchema, badTimestamp{}); err != dynamodbx.ErrTimestampType {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrTimestampType)
+	}
+	if _, err := dynamodbx.BatchPutRequest("test", []badTimestamp{{S: "a"}}); err != dynamodbx.ErrTimestampType {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrTimestampType)
+	}
+}
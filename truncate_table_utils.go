@@ -0,0 +1,104 @@
+package dynamodbx
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrTruncateTableName   = errors.New("dynamodbx/TruncateTable: table name cannot be empty")
+	ErrTruncateUnprocessed = errors.New("dynamodbx/TruncateTable: some items could not be deleted")
+)
+
+// truncateSegments is the number of parallel scan segments used by TruncateTable
+const truncateSegments = 4
+
+// TruncateTableOutput reports the work done by TruncateTable. ConsumedCapacity holds the combined
+// read capacity of the scan and write capacity of the deletes.
+type TruncateTableOutput struct {
+	Deleted          int64
+	ConsumedCapacity []*dynamodb.ConsumedCapacity
+}
+
+// TruncateTable deletes every item in a table without dropping it, keeping its indexes, alarms and
+// stream ARNs. The key schema is read with DescribeTable, the table is scanned in parallel segments
+// projecting only the key attributes and items are deleted with BatchWriteItem.
+//
+// Items written while the truncate is running may or may not be deleted.
+func TruncateTable(client *dynamodb.DynamoDB, table string) (*TruncateTableOutput, error) {
+	return TruncateTableWithContext(context.Background(), client, table)
+}
+
+// TruncateTableWithContext is the same as TruncateTable.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func TruncateTableWithContext(ctx context.Context, client *dynamodb.DynamoDB, table string, opts ...request.Option) (*TruncateTableOutput, error) {
+	if table == "" {
+		return nil, ErrTruncateTableName
+	}
+	desc, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]*string)
+	projection := ""
+	for i, k := range desc.Table.KeySchema {
+		placeholder := "#k" + strconv.Itoa(i)
+		names[placeholder] = k.AttributeName
+		if projection != "" {
+			projection += ", "
+		}
+		projection += placeholder
+	}
+
+	var (
+		mu  sync.Mutex
+		out = &TruncateTableOutput{}
+	)
+	err = scanSegments(ctx, client, &dynamodb.ScanInput{
+		TableName:                aws.String(table),
+		ProjectionExpression:     aws.String(projection),
+		ExpressionAttributeNames: names,
+		ReturnConsumedCapacity:   aws.String(dynamodb.ReturnConsumedCapacityTotal),
+	}, truncateSegments, func(_ int, page *dynamodb.ScanOutput) error {
+		deletes := make([]*dynamodb.WriteRequest, 0, len(page.Items))
+		for _, item := range page.Items {
+			deletes = append(deletes, &dynamodb.WriteRequest{
+				DeleteRequest: &dynamodb.DeleteRequest{Key: item},
+			})
+		}
+		var caps []*dynamodb.ConsumedCapacity
+		if page.ConsumedCapacity != nil {
+			caps = append(caps, page.ConsumedCapacity)
+		}
+		deleted := int64(len(deletes))
+		unprocessed := 0
+		if len(deletes) > 0 {
+			res, err := batchWriteItem(ctx, client, &dynamodb.BatchWriteItemInput{
+				ReturnConsumedCapacity: aws.String(dynamodb.ReturnConsumedCapacityTotal),
+				RequestItems:           map[string][]*dynamodb.WriteRequest{table: deletes},
+			}, opts...)
+			if err != nil {
+				return err
+			}
+			caps = append(caps, res.ConsumedCapacity...)
+			unprocessed = len(res.UnprocessedItems[table])
+			deleted -= int64(unprocessed)
+		}
+		mu.Lock()
+		out.Deleted += deleted
+		out.ConsumedCapacity = append(out.ConsumedCapacity, caps...)
+		mu.Unlock()
+		if unprocessed > 0 {
+			return ErrTruncateUnprocessed
+		}
+		return nil
+	}, opts...)
+	out.ConsumedCapacity = sumConsumedCapacity(out.ConsumedCapacity)
+	return out, err
+}
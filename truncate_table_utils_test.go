@@ -0,0 +1,106 @@
+package dynamodbx_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func TestTruncateTable(t *testing.T) {
+	t.Parallel()
+	type TestData struct {
+		S string
+	}
+	testDataSet := func(count int) []*TestData {
+		data := make([]*TestData, count)
+		for i := 0; i < count; i++ {
+			data[i] = &TestData{
+				S: strconv.Itoa(i),
+			}
+		}
+		return data
+	}
+	for _, tc := range []struct {
+		name   string
+		table  string
+		count  int
+		err    error
+		expect int64
+	}{
+		{
+			name: "empty table name",
+			err:  dynamodbx.ErrTruncateTableName,
+		},
+		{
+			name:  "empty table",
+			table: "testTruncateEmpty",
+		},
+		{
+			name:   "truncate 300 items",
+			table:  "testTruncate",
+			count:  300,
+			expect: 300,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ddb := memdb.NewClient()
+			if tc.err != nil {
+				if _, err := dynamodbx.TruncateTable(ddb, tc.table); err != tc.err {
+					t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+				}
+				return
+			}
+			_, err := dynamodbx.CreateTableSync(ddb, &dynamodb.CreateTableInput{
+				TableName:   aws.String(tc.table),
+				BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+				AttributeDefinitions: []*dynamodb.AttributeDefinition{
+					{
+						AttributeName: aws.String("S"),
+						AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+					},
+				},
+				KeySchema: []*dynamodb.KeySchemaElement{
+					{
+						AttributeName: aws.String("S"),
+						KeyType:       aws.String(dynamodb.KeyTypeHash),
+					},
+				},
+			})
+			defer ddb.DeleteTable(&dynamodb.DeleteTableInput{
+				TableName: aws.String(tc.table),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.count > 0 {
+				req, err := dynamodbx.BatchPutRequest(tc.table, testDataSet(tc.count))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := dynamodbx.BatchWriteItem(ddb, &dynamodb.BatchWriteItemInput{RequestItems: req}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			out, err := dynamodbx.TruncateTable(ddb, tc.table)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Deleted != tc.expect {
+				t.Fatalf("expected %d items deleted, got %d", tc.expect, out.Deleted)
+			}
+			scan, err := ddb.Scan(&dynamodb.ScanInput{TableName: aws.String(tc.table)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(scan.Items) != 0 {
+				t.Fatalf("expected an empty table, got %d items", len(scan.Items))
+			}
+		})
+	}
+}
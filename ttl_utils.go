@@ -0,0 +1,162 @@
+package dynamodbx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	ErrEmptyTTLAttribute = errors.New("dynamodbx/SetTTL: attribute name cannot be empty")
+	ErrTTLType           = errors.New("dynamodbx/TTL: time to live fields must be a time.Time or a time.Duration")
+)
+
+// EnableTTLSync will enable time to live on a table and block until DescribeTimeToLive reports it as
+// ENABLED. This can take up to an hour.
+func EnableTTLSync(client *dynamodb.DynamoDB, table, attributeName string) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return EnableTTLSyncWithContext(context.Background(), client, table, attributeName)
+}
+
+// EnableTTLSyncWithContext will enable time to live on a table and block until DescribeTimeToLive
+// reports it as ENABLED.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func EnableTTLSyncWithContext(ctx context.Context, client *dynamodb.DynamoDB, table, attributeName string, opts ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return updateTTLSync(ctx, client, table, attributeName, true, opts...)
+}
+
+// DisableTTLSync will disable time to live on a table and block until DescribeTimeToLive reports it
+// as DISABLED. This can take up to an hour.
+func DisableTTLSync(client *dynamodb.DynamoDB, table, attributeName string) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return DisableTTLSyncWithContext(context.Background(), client, table, attributeName)
+}
+
+// DisableTTLSyncWithContext will disable time to live on a table and block until DescribeTimeToLive
+// reports it as DISABLED.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func DisableTTLSyncWithContext(ctx context.Context, client *dynamodb.DynamoDB, table, attributeName string, opts ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return updateTTLSync(ctx, client, table, attributeName, false, opts...)
+}
+
+func updateTTLSync(ctx context.Context, client *dynamodb.DynamoDB, table, attributeName string, enabled bool, opts ...request.Option) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	out, err := client.UpdateTimeToLiveWithContext(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(table),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(attributeName),
+			Enabled:       aws.Bool(enabled),
+		},
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	want := dynamodb.TimeToLiveStatusDisabled
+	if enabled {
+		want = dynamodb.TimeToLiveStatusEnabled
+	}
+	for {
+		out, err := client.DescribeTimeToLiveWithContext(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(table)}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if aws.StringValue(out.TimeToLiveDescription.TimeToLiveStatus) == want {
+			break
+		}
+		if err := aws.SleepWithContext(ctx, time.Second); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// TTLAttributeValue encodes t the way dynamodb expects a time to live attribute, a number holding
+// the unix epoch time in seconds.
+func TTLAttributeValue(t time.Time) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(t.Unix(), 10))}
+}
+
+// SetTTL sets attributeName to expires on every PutRequest in reqs, such as those created by
+// BatchPutRequest, so every writer encodes expiry in the same way. Existing values are overwritten.
+func SetTTL(reqs map[string][]*dynamodb.WriteRequest, attributeName string, expires time.Time) error {
+	if attributeName == "" {
+		return ErrEmptyTTLAttribute
+	}
+	for _, items := range reqs {
+		for _, req := range items {
+			if req.PutRequest == nil {
+				continue
+			}
+			if req.PutRequest.Item == nil {
+				req.PutRequest.Item = make(map[string]*dynamodb.AttributeValue)
+			}
+			// every item gets its own value so changing one item does not change the others
+			req.PutRequest.Item[attributeName] = TTLAttributeValue(expires)
+		}
+	}
+	return nil
+}
+
+// SetTTLAfter is the same as SetTTL but expires the items ttl from now.
+func SetTTLAfter(reqs map[string][]*dynamodb.WriteRequest, attributeName string, ttl time.Duration) error {
+	return SetTTL(reqs, attributeName, time.Now().Add(ttl))
+}
+
+// tagTTL marks the time to live field of an item type, as in
+//
+//	Expires time.Time     `dynamodbx:"ttl"`
+//	TTL     time.Duration `dynamodbav:"Expires" dynamodbx:"ttl"`
+const tagTTL = "ttl"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// ttl is the time to live field of an item type, which sets the expiry of each item. A time.Time is
+// the time the item expires, and a time.Duration is how long after it is written the item expires.
+// Either is stored as the unix epoch time in seconds, as TTLAttributeValue encodes it, and items
+// whose field is unset do not expire.
+type ttl struct {
+	index    []int
+	name     string
+	duration bool
+}
+
+// itemTTL returns the time to live field of the struct type t, or nil if it has none.
+func itemTTL(t reflect.Type) (*ttl, error) {
+	f, ok := taggedField(t, tagTTL)
+	if !ok {
+		return nil, nil
+	}
+	switch {
+	case f.Type == durationType:
+		return &ttl{index: f.Index, name: attributeName(f), duration: true}, nil
+	case f.Type.ConvertibleTo(timeType):
+		return &ttl{index: f.Index, name: attributeName(f)}, nil
+	}
+	return nil, ErrTTLType
+}
+
+// put sets the expiry of the item, a struct or a pointer to one, in the marshalled item, or removes
+// it if the field is unset. Durations are added to now.
+func (l *ttl) put(item reflect.Value, av map[string]*dynamodb.AttributeValue, now time.Time) {
+	if item.Kind() == reflect.Ptr && item.IsNil() {
+		return
+	}
+	f := reflect.Indirect(item).FieldByIndex(l.index)
+	var expires time.Time
+	if l.duration {
+		if d := time.Duration(f.Int()); d != 0 {
+			expires = now.Add(d)
+		}
+	} else {
+		expires = f.Convert(timeType).Interface().(time.Time)
+	}
+	if expires.IsZero() {
+		delete(av, l.name)
+		return
+	}
+	av[l.name] = TTLAttributeValue(expires)
+}
@@ -0,0 +1,303 @@
+package dynamodbx_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/dynamodbxtest"
+	"github.com/kynrai/dynamodbx/expr"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+// stubClient returns a client whose requests are answered by stub rather than sent. stub is called
+// with the name of each operation and its input, and returns the output, or an error. The returned
+// func lists the operations called so far.
+func stubClient(stub func(op string, input interface{}) (interface{}, error)) (*dynamodb.DynamoDB, func() []string) {
+	var (
+		mu  sync.Mutex
+		ops []string
+	)
+	client := memdb.NewClient()
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(func(r *request.Request) {
+		mu.Lock()
+		ops = append(ops, r.Operation.Name)
+		mu.Unlock()
+		r.HTTPResponse = &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(bytes.NewReader(nil))}
+		out, err := stub(r.Operation.Name, r.Params)
+		if err != nil {
+			r.Error = err
+			return
+		}
+		reflect.ValueOf(r.Data).Elem().Set(reflect.ValueOf(out).Elem())
+	})
+	return client, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ops...)
+	}
+}
+
+func TestTTLUtilsSetTTL(t *testing.T) {
+	t.Parallel()
+
+	type Item struct {
+		Foo string
+	}
+	expires := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name      string
+		attribute string
+		input     interface{}
+		err       error
+		expect    map[string][]*dynamodb.WriteRequest
+	}{
+		{
+			name:  "empty attribute name",
+			input: []Item{{"a"}},
+			err:   dynamodbx.ErrEmptyTTLAttribute,
+		},
+		{
+			name:      "set ttl",
+			attribute: "Expires",
+			input:     []Item{{"a"}, {"b"}},
+			expect: map[string][]*dynamodb.WriteRequest{
+				"test": []*dynamodb.WriteRequest{
+					{
+						PutRequest: &dynamodb.PutRequest{
+							Item: map[string]*dynamodb.AttributeValue{
+								"Foo":     &dynamodb.AttributeValue{S: aws.String("a")},
+								"Expires": &dynamodb.AttributeValue{N: aws.String("1551441600")},
+							},
+						},
+					},
+					{
+						PutRequest: &dynamodb.PutRequest{
+							Item: map[string]*dynamodb.AttributeValue{
+								"Foo":     &dynamodb.AttributeValue{S: aws.String("b")},
+								"Expires": &dynamodb.AttributeValue{N: aws.String("1551441600")},
+							},
+						},
+					},
+				},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			reqs, err := dynamodbx.BatchPutRequest("test", tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = dynamodbx.SetTTL(reqs, tc.attribute, expires)
+			if tc.err != err {
+				t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+			}
+			if tc.expect != nil && !reflect.DeepEqual(reqs, tc.expect) {
+				t.Fatal(pretty.Compare(reqs, tc.expect))
+			}
+		})
+	}
+}
+
+func TestTTLUtilsUpdateTTLSync(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name     string
+		enable   bool
+		statuses []string
+	}{
+		{
+			name:     "enable",
+			enable:   true,
+			statuses: []string{dynamodb.TimeToLiveStatusEnabling, dynamodb.TimeToLiveStatusEnabled},
+		},
+		{
+			name:     "disable",
+			statuses: []string{dynamodb.TimeToLiveStatusDisabling, dynamodb.TimeToLiveStatusDisabled},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			statuses := tc.statuses
+			ddb, ops := stubClient(func(op string, input interface{}) (interface{}, error) {
+				switch in := input.(type) {
+				case *dynamodb.UpdateTimeToLiveInput:
+					if aws.BoolValue(in.TimeToLiveSpecification.Enabled) != tc.enable || aws.StringValue(in.TimeToLiveSpecification.AttributeName) != "Expires" {
+						t.Errorf("unexpected time to live specification: %v", in.TimeToLiveSpecification)
+					}
+					return &dynamodb.UpdateTimeToLiveOutput{TimeToLiveSpecification: in.TimeToLiveSpecification}, nil
+				case *dynamodb.DescribeTimeToLiveInput:
+					status := statuses[0]
+					statuses = statuses[1:]
+					return &dynamodb.DescribeTimeToLiveOutput{
+						TimeToLiveDescription: &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(status)},
+					}, nil
+				}
+				t.Fatalf("unexpected operation %s", op)
+				return nil, nil
+			})
+			update := dynamodbx.DisableTTLSync
+			if tc.enable {
+				update = dynamodbx.EnableTTLSync
+			}
+			out, err := update(ddb, "test", "Expires")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if aws.BoolValue(out.TimeToLiveSpecification.Enabled) != tc.enable {
+				t.Fatalf("unexpected output: %v", out)
+			}
+			expect := []string{"UpdateTimeToLive", "DescribeTimeToLive", "DescribeTimeToLive"}
+			if got := ops(); !reflect.DeepEqual(got, expect) {
+				t.Fatal(pretty.Compare(got, expect))
+			}
+		})
+	}
+}
+
+func TestTTLUtilsSetTTLValues(t *testing.T) {
+	t.Parallel()
+	reqs, err := dynamodbx.BatchPutRequest("test", []struct{ Foo string }{{"a"}, {"b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dynamodbx.SetTTLAfter(reqs, "Expires", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// Changing the expiry of one item leaves the others alone
+	reqs["test"][0].PutRequest.Item["Expires"].SetN("0")
+	if got := aws.StringValue(reqs["test"][1].PutRequest.Item["Expires"].N); got == "0" {
+		t.Fatal("expected every item to have its own time to live value")
+	}
+}
+
+func TestTTLUtilsTTLField(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := dynamodbx.WithClock(func() time.Time { return now })
+	epoch := func(t time.Time) *dynamodb.AttributeValue {
+		return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(t.Unix(), 10))}
+	}
+	type expiring struct {
+		S       string
+		Expires time.Time `dynamodbx:"ttl"`
+	}
+	type lasting struct {
+		S   string
+		TTL time.Duration `dynamodbav:"Expires" dynamodbx:"ttl"`
+	}
+
+	for _, tc := range []struct {
+		name   string
+		input  interface{}
+		expect []*dynamodb.AttributeValue
+	}{
+		{
+			name:   "time",
+			input:  []expiring{{"a", now.Add(time.Hour)}, {"b", now.Add(48 * time.Hour)}, {"c", time.Time{}}},
+			expect: []*dynamodb.AttributeValue{epoch(now.Add(time.Hour)), epoch(now.Add(48 * time.Hour)), nil},
+		},
+		{
+			name:   "duration",
+			input:  []*lasting{{"a", time.Minute}, {"b", 24 * time.Hour}, {"c", 0}},
+			expect: []*dynamodb.AttributeValue{epoch(now.Add(time.Minute)), epoch(now.Add(24 * time.Hour)), nil},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			reqs, err := dynamodbx.BatchPutRequest("test", tc.input, clock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []*dynamodb.AttributeValue
+			for _, req := range reqs["test"] {
+				got = append(got, req.PutRequest.Item["Expires"])
+			}
+			if diff := pretty.Compare(got, tc.expect); diff != "" {
+				t.Fatalf("unexpected batch put expiries: %s", diff)
+			}
+
+			puts, err := dynamodbx.ConditionalPutRequest(tc.input, expr.ConditionBuilder{}, clock)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = nil
+			for _, put := range puts {
+				got = append(got, put.Item["Expires"])
+			}
+			if diff := pretty.Compare(got, tc.expect); diff != "" {
+				t.Fatalf("unexpected conditional put expiries: %s", diff)
+			}
+		})
+	}
+
+	// Tables write the expiry of each item
+	ddb := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec)
+	items, err := dynamodbx.NewTable(ddb, tbl.Name, batchWriteSpec.KeySchema, lasting{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items = items.WithClock(func() time.Time { return now })
+	if err := items.BatchPut([]lasting{{"a", time.Hour}, {"b", 2 * time.Hour}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := items.Put(&lasting{"c", 3 * time.Hour}, expr.ConditionBuilder{}); err != nil {
+		t.Fatal(err)
+	}
+	scan, err := ddb.Scan(&dynamodb.ScanInput{TableName: aws.String(tbl.Name)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires := make(map[string]*dynamodb.AttributeValue)
+	for _, item := range scan.Items {
+		expires[aws.StringValue(item["S"].S)] = item["Expires"]
+	}
+	expect := map[string]*dynamodb.AttributeValue{"a": epoch(now.Add(time.Hour)), "b": epoch(now.Add(2 * time.Hour)), "c": epoch(now.Add(3 * time.Hour))}
+	if diff := pretty.Compare(expires, expect); diff != "" {
+		t.Fatalf("unexpected table expiries: %s", diff)
+	}
+
+	// Diffs compare expiries as they are stored
+	input, err := dynamodbx.UpdateItemDiff("test", []string{"S"}, lasting{"a", time.Hour}, lasting{"a", 2 * time.Hour}, clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := pretty.Compare(input.ExpressionAttributeValues, map[string]*dynamodb.AttributeValue{":v0": epoch(now.Add(2 * time.Hour))}); diff != "" {
+		t.Fatalf("unexpected update values: %s", diff)
+	}
+	if input, err := dynamodbx.UpdateItemDiff("test", []string{"S"}, lasting{"a", time.Hour}, lasting{"a", time.Hour}, clock); err != nil || input != nil {
+		t.Fatalf("expected no update, got %v and %v", input, err)
+	}
+}
+
+func TestTTLUtilsTTLType(t *testing.T) {
+	t.Parallel()
+	type badTTL struct {
+		S       string
+		Expires int64 `dynamodbx:"ttl"`
+	}
+	if _, err := dynamodbx.NewTable(memdb.NewClient(), "test", batchWriteSpec.KeySchema, badTTL{}); err != dynamodbx.ErrTTLType {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrTTLType)
+	}
+	if _, err := dynamodbx.BatchPutRequest("test", []badTTL{{S: "a"}}); err != dynamodbx.ErrTTLType {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrTTLType)
+	}
+}
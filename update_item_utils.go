@@ -0,0 +1,135 @@
+package dynamodbx
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/kynrai/dynamodbx/expr"
+)
+
+var (
+	ErrUpdateDiffTableName  = errors.New("dynamodbx/UpdateItemDiff: table name cannot be empty")
+	ErrUpdateDiffKey        = errors.New("dynamodbx/UpdateItemDiff: key attributes must be given and present in the new item")
+	ErrUpdateDiffKeyChanged = errors.New("dynamodbx/UpdateItemDiff: key attributes cannot be changed")
+)
+
+// UpdateItemDiff creates an UpdateItemInput which changes the item old into the item new, setting
+// only the attributes which were modified and removing the attributes new no longer has. This is
+// mainly used to write partial updates of go structs without overwriting concurrent changes to
+// other attributes, as a PutItem of new would.
+//
+// The inputs old and new are converted using the dynamodbattribute.MarshalMap function. Maps
+// present in both are compared attribute by attribute, so only the nested attributes which differ
+// are set. Lists and sets are set whole.
+//
+// The input keys are the names of the key attributes of the table, whose values are taken from
+// new and cannot be changed. The result is nil if the items are equal.
+//
+// If new is a struct with timestamp fields, tagged `dynamodbx:"created"` and `dynamodbx:"updated"`,
+// they are left out of the comparison and the update stamps them with the time of the clock set by
+// WithClock, setting the created time only if the item does not have one. A time to live field,
+// tagged `dynamodbx:"ttl"`, is compared and set as BatchPutRequest writes it.
+//
+// If new is a struct with a version field, tagged `dynamodbx:"version"`, the update increments the
+// version of old, on the condition that the stored version is still the version of old. Its
+// ConditionalCheckFailedException then means the item was modified since old was read. An old
+// item without a version, such as nil, has version zero and must not exist yet.
+//
+// The return values and other fields of the UpdateItemInput may be set before it is used, but a
+// condition must be built along with the update, as the update uses expression attribute
+// placeholders.
+func UpdateItemDiff(table string, keys []string, old, new interface{}, opts ...ItemOption) (*dynamodb.UpdateItemInput, error) {
+	if table == "" {
+		return nil, ErrUpdateDiffTableName
+	}
+	if len(keys) == 0 {
+		return nil, ErrUpdateDiffKey
+	}
+	o, err := dynamodbattribute.MarshalMap(old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := dynamodbattribute.MarshalMap(new)
+	if err != nil {
+		return nil, err
+	}
+	key := make(map[string]*dynamodb.AttributeValue, len(keys))
+	for _, k := range keys {
+		if n[k] == nil {
+			return nil, ErrUpdateDiffKey
+		}
+		if o[k] != nil && !reflect.DeepEqual(o[k], n[k]) {
+			return nil, ErrUpdateDiffKeyChanged
+		}
+		key[k] = n[k]
+		// key attributes are never updated, so they are left out of the diff
+		delete(o, k)
+		delete(n, k)
+	}
+	var (
+		ver *version
+		ts  *timestamps
+		exp *ttl
+	)
+	now := clockTime(opts)
+	if t := structType(new); t != nil {
+		if ver, err = itemVersion(t); err != nil {
+			return nil, err
+		}
+		if ts, err = itemTimestamps(t); err != nil {
+			return nil, err
+		}
+		if exp, err = itemTTL(t); err != nil {
+			return nil, err
+		}
+	}
+	if exp != nil {
+		// the expiry of old is only converted if it has the field, otherwise it is as stored
+		exp.put(reflect.ValueOf(new), n, now)
+		if structType(old) == structType(new) {
+			exp.put(reflect.ValueOf(old), o, now)
+		}
+	}
+	var current int64
+	if ver != nil {
+		// the version is read from the marshalled item, as old need not be of the type of new
+		if current, err = storedVersion(o[ver.name]); err != nil {
+			return nil, err
+		}
+		delete(o, ver.name)
+		delete(n, ver.name)
+	}
+	if ts != nil {
+		for _, name := range ts.names() {
+			delete(o, name)
+			delete(n, name)
+		}
+	}
+	update := expr.Diff(o, n)
+	if !update.IsSet() {
+		return nil, nil
+	}
+	if ts != nil {
+		update = ts.update(update, now)
+	}
+	b := expr.NewBuilder()
+	if ver != nil {
+		update = update.Set(expr.AttributeName(ver.name), expr.Value(current+1))
+		b = b.WithCondition(ver.condition(current))
+	}
+	e, err := b.WithUpdate(update).Build()
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(table),
+		Key:                       key,
+		UpdateExpression:          e.Update(),
+		ConditionExpression:       e.Condition(),
+		ExpressionAttributeNames:  e.Names(),
+		ExpressionAttributeValues: e.Values(),
+	}, nil
+}
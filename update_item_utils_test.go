@@ -0,0 +1,107 @@
+package dynamodbx_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/dynamodbxtest"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+func TestUpdateItemDiff(t *testing.T) {
+	t.Parallel()
+	type Address struct {
+		City   string
+		Street string
+	}
+	type User struct {
+		ID      string
+		Name    string
+		Email   string `dynamodbav:",omitempty"`
+		Tags    []string
+		Address Address
+	}
+	spec := &dynamodb.CreateTableInput{
+		TableName:            aws.String("testUpdateItemDiff"),
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("ID"), AttributeType: aws.String("S")}},
+		KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("ID"), KeyType: aws.String("HASH")}},
+	}
+	old := User{ID: "1", Name: "Ann", Email: "ann@example.com", Tags: []string{"a"}, Address: Address{City: "London", Street: "High St"}}
+	for _, tc := range []struct {
+		name    string
+		noTable bool
+		keys    []string
+		new     User
+		err     error
+		noop    bool
+		expect  User
+	}{
+		{
+			name:   "modified attributes",
+			keys:   []string{"ID"},
+			new:    User{ID: "1", Name: "Anne", Tags: []string{"a", "b"}, Address: Address{City: "London", Street: "Low St"}},
+			expect: User{ID: "1", Name: "Anne", Tags: []string{"a", "b"}, Address: Address{City: "London", Street: "Low St"}},
+		},
+		{
+			name: "unchanged",
+			keys: []string{"ID"},
+			new:  old,
+			noop: true,
+		},
+		{
+			name:    "empty table name",
+			noTable: true,
+			keys:    []string{"ID"},
+			new:     old,
+			err:     dynamodbx.ErrUpdateDiffTableName,
+		},
+		{
+			name: "no keys",
+			new:  old,
+			err:  dynamodbx.ErrUpdateDiffKey,
+		},
+		{
+			name: "missing key",
+			keys: []string{"Email"},
+			new:  User{ID: "1"},
+			err:  dynamodbx.ErrUpdateDiffKey,
+		},
+		{
+			name: "changed key",
+			keys: []string{"ID"},
+			new:  User{ID: "2"},
+			err:  dynamodbx.ErrUpdateDiffKeyChanged,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ddb := memdb.NewClient()
+			tbl := dynamodbxtest.NewTestTable(t, ddb, spec, old)
+			table := tbl.Name
+			if tc.noTable {
+				table = ""
+			}
+			input, err := dynamodbx.UpdateItemDiff(table, tc.keys, old, tc.new)
+			if err != tc.err {
+				t.Fatalf("expected error mismatch: got: %v, want: %v", err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			if tc.noop {
+				if input != nil {
+					t.Fatalf("expected no update, got %v", input)
+				}
+				return
+			}
+			if _, err := ddb.UpdateItem(input); err != nil {
+				t.Fatal(err)
+			}
+			tbl.AssertItems(tc.expect)
+		})
+	}
+}
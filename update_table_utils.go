@@ -0,0 +1,71 @@
+package dynamodbx
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// UpdateTableSync will update a dynamodb table and block until the table and all of its global
+// secondary indexes are active again. This is useful when adding or removing indexes, enabling
+// streams or changing the billing mode before code relies on the change.
+func UpdateTableSync(client *dynamodb.DynamoDB, input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+	return UpdateTableSyncWithContext(context.Background(), client, input)
+}
+
+// UpdateTableSyncWithContext will update a dynamodb table and block until the table and all of its
+// global secondary indexes are active again.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func UpdateTableSyncWithContext(ctx context.Context, client *dynamodb.DynamoDB, input *dynamodb.UpdateTableInput, opts ...request.Option) (*dynamodb.UpdateTableOutput, error) {
+	out, err := client.UpdateTableWithContext(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := waitTableActive(ctx, client, aws.StringValue(input.TableName), opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WaitTableActive blocks until a table and all of its global secondary indexes are active.
+func WaitTableActive(client *dynamodb.DynamoDB, table string) error {
+	return waitTableActive(context.Background(), client, table)
+}
+
+// WaitTableActiveWithContext is the same as WaitTableActive.
+// A context and request options can be provided to and will be passed to the underlying aws-sdk-go calls
+func WaitTableActiveWithContext(ctx context.Context, client *dynamodb.DynamoDB, table string, opts ...request.Option) error {
+	return waitTableActive(ctx, client, table, opts...)
+}
+
+// waitTableActive polls DescribeTable until the table and every global secondary index on it are
+// ACTIVE. Indexes which are being deleted are reported as DELETING until they disappear.
+func waitTableActive(ctx context.Context, client *dynamodb.DynamoDB, table string, opts ...request.Option) error {
+	for {
+		out, err := client.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)}, opts...)
+		if err != nil {
+			return err
+		}
+		if tableActive(out.Table) {
+			return nil
+		}
+		if err := aws.SleepWithContext(ctx, time.Millisecond*100); err != nil {
+			return err
+		}
+	}
+}
+
+func tableActive(t *dynamodb.TableDescription) bool {
+	if aws.StringValue(t.TableStatus) != dynamodb.TableStatusActive {
+		return false
+	}
+	for _, gsi := range t.GlobalSecondaryIndexes {
+		if aws.StringValue(gsi.IndexStatus) != dynamodb.IndexStatusActive {
+			return false
+		}
+	}
+	return true
+}
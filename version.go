@@ -0,0 +1,150 @@
+package dynamodbx
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kynrai/dynamodbx/expr"
+)
+
+var (
+	ErrVersionConflict = errors.New("dynamodbx/Version: the item was modified since its version was read")
+	ErrVersionType     = errors.New("dynamodbx/Version: version fields must be integers")
+)
+
+// tagVersion marks the version field of an item type, as in
+//
+//	Version int `dynamodbx:"version"`
+const tagVersion = "version"
+
+// version is the version field of an item type. Items with a version are only written if the stored
+// version is the version they were read with, and each write increments it. Items without a stored
+// version have version zero.
+type version struct {
+	index []int
+	name  string
+}
+
+// itemVersion returns the version field of the struct type t, or nil if it has none.
+func itemVersion(t reflect.Type) (*version, error) {
+	f, ok := taggedField(t, tagVersion)
+	if !ok {
+		return nil, nil
+	}
+	switch f.Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return nil, ErrVersionType
+	}
+	return &version{index: f.Index, name: attributeName(f)}, nil
+}
+
+// get returns the version of the item, a struct or a pointer to one.
+func (v *version) get(item reflect.Value) int64 {
+	if item.Kind() == reflect.Ptr && item.IsNil() {
+		return 0
+	}
+	f := reflect.Indirect(item).FieldByIndex(v.index)
+	if f.Kind() >= reflect.Uint && f.Kind() <= reflect.Uint64 {
+		return int64(f.Uint())
+	}
+	return f.Int()
+}
+
+// increment increments the version of the item if it is a pointer to a struct, after it has been
+// written.
+func (v *version) increment(item reflect.Value) {
+	if item.Kind() != reflect.Ptr || item.IsNil() {
+		return
+	}
+	f := item.Elem().FieldByIndex(v.index)
+	if f.Kind() >= reflect.Uint && f.Kind() <= reflect.Uint64 {
+		f.SetUint(f.Uint() + 1)
+		return
+	}
+	f.SetInt(f.Int() + 1)
+}
+
+// condition returns the condition that the stored version is current, which for version zero is
+// that the item has no version.
+func (v *version) condition(current int64) expr.ConditionBuilder {
+	if current == 0 {
+		return expr.AttributeName(v.name).AttributeNotExists()
+	}
+	return expr.AttributeName(v.name).Equal(expr.Value(current))
+}
+
+// put sets the incremented version in the marshalled item and returns cond, if set, along with the
+// condition that the stored version is current.
+func (v *version) put(item reflect.Value, av map[string]*dynamodb.AttributeValue, cond expr.ConditionBuilder) expr.ConditionBuilder {
+	current := v.get(item)
+	av[v.name] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(current+1, 10))}
+	return v.and(current, cond)
+}
+
+// and returns cond, if set, along with the condition that the stored version is current.
+func (v *version) and(current int64, cond expr.ConditionBuilder) expr.ConditionBuilder {
+	if !cond.IsSet() {
+		return v.condition(current)
+	}
+	return v.condition(current).And(cond)
+}
+
+// storedVersion returns the version held by a marshalled version attribute, zero if it is missing.
+func storedVersion(av *dynamodb.AttributeValue) (int64, error) {
+	if av == nil || av.NULL != nil {
+		return 0, nil
+	}
+	if av.N == nil {
+		return 0, ErrVersionType
+	}
+	n, err := strconv.ParseInt(*av.N, 10, 64)
+	if err != nil {
+		return 0, ErrVersionType
+	}
+	return n, nil
+}
+
+// versionConflict returns ErrVersionConflict for a conditional check failure, and err otherwise.
+func versionConflict(err error) error {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// taggedField returns the field of the struct type t, or of the structs it embeds, with the
+// dynamodbx tag.
+func taggedField(t reflect.Type, tag string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("dynamodbx") == tag {
+			return f, true
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if ef, ok := taggedField(f.Type, tag); ok {
+				ef.Index = append([]int{i}, ef.Index...)
+				return ef, true
+			}
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// attributeName returns the attribute name of the field as dynamodbattribute names it.
+func attributeName(f reflect.StructField) string {
+	tag := f.Tag.Get("dynamodbav")
+	if tag == "" {
+		tag = f.Tag.Get("json")
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return f.Name
+}
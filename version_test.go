@@ -0,0 +1,174 @@
+package dynamodbx_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/kynrai/dynamodbx"
+	"github.com/kynrai/dynamodbx/dynamodbxtest"
+	"github.com/kynrai/dynamodbx/expr"
+	"github.com/kynrai/dynamodbx/memdb"
+)
+
+type versioned struct {
+	S       string
+	Value   string
+	Version int `dynamodbav:"v" dynamodbx:"version"`
+}
+
+func newVersioned(t *testing.T, fixtures ...interface{}) (*dynamodbx.Table, *dynamodbxtest.Table) {
+	t.Helper()
+	ddb := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec, fixtures...)
+	items, err := dynamodbx.NewTable(ddb, tbl.Name, batchWriteSpec.KeySchema, versioned{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return items, tbl
+}
+
+func TestVersionPut(t *testing.T) {
+	t.Parallel()
+	items, tbl := newVersioned(t)
+	a := &versioned{S: "a", Value: "1"}
+	if err := items.Put(a, expr.ConditionBuilder{}); err != nil {
+		t.Fatal(err)
+	}
+	if a.Version != 1 {
+		t.Fatalf("expected version 1, got %d", a.Version)
+	}
+
+	// A second writer which read version 1 loses to the first
+	var b versioned
+	if err := items.Get(a, &b); err != nil {
+		t.Fatal(err)
+	}
+	a.Value = "2"
+	if err := items.Put(a, expr.ConditionBuilder{}); err != nil {
+		t.Fatal(err)
+	}
+	b.Value = "3"
+	if err := items.Put(&b, expr.ConditionBuilder{}); err != dynamodbx.ErrVersionConflict {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrVersionConflict)
+	}
+	if b.Version != 1 {
+		t.Fatalf("expected version 1 after a conflict, got %d", b.Version)
+	}
+	// New items conflict with stored items
+	if err := items.Put(versioned{S: "a"}, expr.ConditionBuilder{}); err != dynamodbx.ErrVersionConflict {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrVersionConflict)
+	}
+	tbl.AssertItems(versioned{"a", "2", 2})
+}
+
+func TestVersionUpdate(t *testing.T) {
+	t.Parallel()
+	items, tbl := newVersioned(t, versioned{"a", "1", 3})
+	stale := &versioned{S: "a", Version: 2}
+	if err := items.Update(stale, expr.Set(expr.Name("Value"), expr.Value("x")), expr.ConditionBuilder{}, nil); err != dynamodbx.ErrVersionConflict {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrVersionConflict)
+	}
+	current := &versioned{S: "a", Version: 3}
+	var out versioned
+	if err := items.Update(current, expr.Set(expr.Name("Value"), expr.Value("2")), expr.ConditionBuilder{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if current.Version != 4 || out != (versioned{"a", "2", 4}) {
+		t.Fatalf("expected version 4, got %d and %+v", current.Version, out)
+	}
+	// Keys without a version update whatever the stored version is
+	if err := items.Update(map[string]*dynamodb.AttributeValue{"S": {S: aws.String("a")}}, expr.Set(expr.Name("Value"), expr.Value("3")), expr.ConditionBuilder{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	tbl.AssertItems(versioned{"a", "3", 5})
+}
+
+func TestVersionBatchPut(t *testing.T) {
+	t.Parallel()
+	items, tbl := newVersioned(t, []versioned{{"a", "1", 1}, {"b", "1", 1}})
+	batch := []*versioned{{"a", "2", 1}, {"b", "2", 0}, {"c", "2", 0}}
+	if err := items.BatchPut(batch); err != dynamodbx.ErrVersionConflict {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrVersionConflict)
+	}
+	if batch[0].Version != 2 || batch[1].Version != 0 || batch[2].Version != 1 {
+		t.Fatalf("expected versions 2, 0 and 1, got %d, %d and %d", batch[0].Version, batch[1].Version, batch[2].Version)
+	}
+	tbl.AssertItems([]versioned{{"a", "2", 2}, {"b", "1", 1}, {"c", "2", 1}})
+
+	// BatchWriteItem would write versioned items unchecked
+	if _, err := dynamodbx.BatchPutRequest(tbl.Name, batch); err != dynamodbx.ErrBatchPutVersion {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrBatchPutVersion)
+	}
+}
+
+func TestVersionPackageBatchPut(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec, []versioned{{"a", "1", 1}, {"b", "1", 1}})
+	batch := []*versioned{{"a", "2", 1}, {"b", "2", 0}, {"c", "2", 0}}
+	if err := dynamodbx.BatchPut(ddb, tbl.Name, batch); err != dynamodbx.ErrVersionConflict {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrVersionConflict)
+	}
+	if batch[0].Version != 2 || batch[1].Version != 0 || batch[2].Version != 1 {
+		t.Fatalf("expected versions 2, 0 and 1, got %d, %d and %d", batch[0].Version, batch[1].Version, batch[2].Version)
+	}
+	tbl.AssertItems([]versioned{{"a", "2", 2}, {"b", "1", 1}, {"c", "2", 1}})
+
+	// Values are written with their version checked but are not incremented
+	if err := dynamodbx.BatchPut(ddb, tbl.Name, []versioned{{"a", "3", 2}, {"c", "3", 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dynamodbx.BatchPut(ddb, tbl.Name, []versioned{{"a", "4", 2}}); err != dynamodbx.ErrVersionConflict {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrVersionConflict)
+	}
+	tbl.AssertItems([]versioned{{"a", "3", 3}, {"b", "1", 1}, {"c", "3", 2}})
+}
+
+func TestVersionUpdateItemDiff(t *testing.T) {
+	t.Parallel()
+	ddb := memdb.NewClient()
+	tbl := dynamodbxtest.NewTestTable(t, ddb, batchWriteSpec, versioned{"a", "1", 1})
+	old := versioned{"a", "1", 1}
+	input, err := dynamodbx.UpdateItemDiff(tbl.Name, []string{"S"}, old, versioned{"a", "2", 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ddb.UpdateItem(input); err != nil {
+		t.Fatal(err)
+	}
+	// The same diff is stale once it has been applied
+	if _, err := ddb.UpdateItem(input); err == nil {
+		t.Fatal("expected a conditional check failure")
+	}
+	tbl.AssertItems(versioned{"a", "2", 2})
+
+	// The old item need not be a struct of the same type
+	input, err = dynamodbx.UpdateItemDiff(tbl.Name, []string{"S"}, map[string]interface{}{"S": "a", "Value": "2", "v": 2}, versioned{"a", "3", 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ddb.UpdateItem(input); err != nil {
+		t.Fatal(err)
+	}
+	// Without an old item the new item must not exist yet
+	input, err = dynamodbx.UpdateItemDiff(tbl.Name, []string{"S"}, nil, versioned{"b", "1", 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ddb.UpdateItem(input); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ddb.UpdateItem(input); err == nil {
+		t.Fatal("expected a conditional check failure")
+	}
+	tbl.AssertItems([]versioned{{"a", "3", 3}, {"b", "1", 1}})
+
+	type badVersion struct {
+		S       string
+		Version string `dynamodbx:"version"`
+	}
+	if _, err := dynamodbx.NewTable(ddb, tbl.Name, batchWriteSpec.KeySchema, badVersion{}); err != dynamodbx.ErrVersionType {
+		t.Fatalf("expected error mismatch: got: %v, want: %v", err, dynamodbx.ErrVersionType)
+	}
+}